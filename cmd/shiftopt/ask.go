@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+// cmdAsk runs a manager command in plain words: "who is working Saturday close?",
+// "give Grace four more hours this week", "what if Bob is off Friday?". It is a dry run
+// unless -apply is set.
+func cmdAsk(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: shiftopt [-apply] ask <command>")
+	}
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	now, err := localNow(db)
+	if err != nil {
+		return err
+	}
+	plan, err := ai.Preview(context.Background(), store, strings.Join(args, " "), now)
+	if err != nil {
+		return err
+	}
+	printPlan(plan)
+
+	if plan.Change.Empty() {
+		return nil
+	}
+	if !*apply {
+		fmt.Println("\nDry run: nothing saved. Run again with -apply to save the change and the draft roster(s).")
+		return nil
+	}
+	versions, err := ai.Apply(store, plan, *author)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nSaved. Draft version(s) %v: review with diff, then publish.\n", versions)
+	return nil
+}
+
+func printPlan(plan ai.Plan) {
+	for _, d := range plan.Working {
+		fmt.Printf("%s:\n", d.Date.Format("Mon 2 Jan"))
+		if d.Shifts != nil && len(d.Shifts) == 0 {
+			fmt.Println("  Nobody.")
+		}
+		for _, s := range d.Shifts {
+			fmt.Printf("  %-20s %s\n", s.Employee.Name, shiftLabel(s))
+		}
+	}
+
+	for _, u := range plan.Change.Unavailability {
+		day := "every day"
+		if !u.Date.IsZero() {
+			day = u.Date.Format("Mon 2 Jan")
+		}
+		fmt.Printf("Would mark %s unavailable %s %02d:00-%02d:00\n", u.EmployeeName, day, u.StartHour, u.EndHour)
+	}
+	for _, l := range plan.Change.Locks {
+		fmt.Printf("Would pin %s %s %02d:00-%02d:00\n", l.EmployeeName, l.Date.Format("Mon 2 Jan"), l.StartHour, l.EndHour)
+	}
+
+	for _, t := range plan.Trials {
+		fmt.Printf("\n[Trial run] %s\n", t.Date.Format("Mon 2 Jan"))
+		if scheduler.ChangeCount(t.Diff) == 0 {
+			fmt.Println("  No shift changes.")
+		}
+		for _, s := range t.Diff.Added {
+			fmt.Printf("  + %-20s %s\n", s.Employee.Name, shiftLabel(s))
+		}
+		for _, s := range t.Diff.Removed {
+			fmt.Printf("  - %-20s %s\n", s.Employee.Name, shiftLabel(s))
+		}
+		for _, c := range t.Diff.Changed {
+			fmt.Printf("  ~ %-20s %s -> %s\n", c.Before.Employee.Name, shiftLabel(c.Before), shiftLabel(c.After))
+		}
+		beforeCost, beforeUnfilled := 0.0, 0
+		if t.Before != nil {
+			beforeCost, beforeUnfilled = t.Before.TotalCost, t.Before.Unfilled
+		}
+		fmt.Printf("  Cost: $%.2f -> $%.2f (%+.2f)\n", beforeCost, t.After.TotalCost, t.Diff.CostDelta)
+		fmt.Printf("  Unfilled: %d -> %d\n", beforeUnfilled, t.After.Unfilled)
+	}
+
+	for _, n := range plan.Notes {
+		fmt.Println("Note:", n)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+// cmdCalendar writes the staff calendars of a roster version (one .ics per employee plus a combined one)
+func cmdCalendar(db *sql.DB, args []string) error {
+	if len(args) > 2 {
+		return fmt.Errorf("usage: shiftopt calendar [version|latest] [dir]")
+	}
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	arg := "latest"
+	if len(args) > 0 {
+		arg = args[0]
+	}
+	versionID, err := versionArg(store, arg)
+	if err != nil {
+		return err
+	}
+	dir := "calendars"
+	if len(args) == 2 {
+		dir = args[1]
+	}
+
+	roster, v, err := store.LoadRoster(versionID)
+	if err != nil {
+		return err
+	}
+	name, err := locationName(store, v.LocationID)
+	if err != nil {
+		return err
+	}
+	files, err := scheduler.ExportICS(roster, scheduler.ICSOptions{Location: name, Stamp: v.CreatedAt}, dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Success: %d calendars for version %d written to %s/\n", len(files), v.ID, dir)
+	return nil
+}
+
+// versionArg resolves a version number, or "latest" for the newest version the store can see
+func versionArg(store database.Store, arg string) (int, error) {
+	if arg != "latest" {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return 0, fmt.Errorf("invalid version %q", arg)
+		}
+		return id, nil
+	}
+	versions, err := store.ListRosterVersions()
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, fmt.Errorf("no roster versions saved yet")
+	}
+	return versions[0].ID, nil
+}
+
+// locationName is the store name printed on exports
+func locationName(store database.Store, id int) (string, error) {
+	locations, err := store.Locations()
+	if err != nil {
+		return "", err
+	}
+	for _, l := range locations {
+		if l.ID == id {
+			return l.Name, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/iannsp/shiftopt/internal/dashboard"
+)
+
+func cmdDashboard(db *sql.DB, args []string) error {
+	if len(args) > 2 {
+		return fmt.Errorf("usage: shiftopt dashboard [version|latest] [out.html]")
+	}
+	versionID := 0
+	if len(args) > 0 && args[0] != "latest" {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		versionID = id
+	}
+	filename := "dashboard.html"
+	if len(args) == 2 {
+		filename = args[1]
+	}
+
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	page, err := dashboard.Load(store, versionID, *budget)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := dashboard.Render(file, page); err != nil {
+		return err
+	}
+	fmt.Printf("Success: Dashboard for version %d written to %s\n", page.Version.ID, filename)
+	return nil
+}
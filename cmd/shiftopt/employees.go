@@ -0,0 +1,169 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/importer"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+func cmdImport(db *sql.DB, args []string) error {
+	if len(args) > 0 && args[0] == "roster" {
+		return importRoster(db, args[1:])
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: shiftopt import employees|demand|unavailability|roster <file.csv|file.json>")
+	}
+	imports := map[string]func(database.Store, string) (importer.Result, error){
+		"employees":      importer.Employees,
+		"demand":         importer.Demands,
+		"unavailability": importer.Unavailability,
+	}
+	run, ok := imports[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown import %q (want employees, demand or unavailability)", args[0])
+	}
+
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	res, err := run(store, args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %s from %s: %d added, %d updated.\n", args[0], args[1], res.Added, res.Updated)
+	return nil
+}
+
+// cmdEmployees lists the crew, or changes it with add/update/deactivate/float
+func cmdEmployees(db *sql.DB, args []string) error {
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return listEmployees(store)
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) != 4 {
+			return fmt.Errorf("usage: shiftopt employees add <name> <hourly rate> <skill level>")
+		}
+		e, err := parseEmployee(args[1], args[2], args[3])
+		if err != nil {
+			return err
+		}
+		id, err := store.AddEmployee(e)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Employee %d: %s added.\n", id, e.Name)
+	case "update":
+		if len(args) != 5 {
+			return fmt.Errorf("usage: shiftopt employees update <id> <name> <hourly rate> <skill level>")
+		}
+		e, err := findEmployee(store, args[1])
+		if err != nil {
+			return err
+		}
+		changed, err := parseEmployee(args[2], args[3], args[4])
+		if err != nil {
+			return err
+		}
+		e.Name, e.HourlyRate, e.SkillLevel = changed.Name, changed.HourlyRate, changed.SkillLevel
+		if err := store.UpdateEmployee(e); err != nil {
+			return err
+		}
+		fmt.Printf("Employee %d updated.\n", e.ID)
+	case "deactivate":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: shiftopt employees deactivate <id>")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid employee %q", args[1])
+		}
+		if err := store.DeactivateEmployee(id); err != nil {
+			return err
+		}
+		fmt.Printf("Employee %d deactivated.\n", id)
+	case "float":
+		if len(args) != 3 || (args[2] != "on" && args[2] != "off") {
+			return fmt.Errorf("usage: shiftopt employees float <id> on|off")
+		}
+		e, err := findEmployee(store, args[1])
+		if err != nil {
+			return err
+		}
+		e.Floats = args[2] == "on"
+		if err := store.UpdateEmployee(e); err != nil {
+			return err
+		}
+		fmt.Printf("Employee %d floating: %s.\n", e.ID, args[2])
+	default:
+		return fmt.Errorf("unknown employees command %q (want add, update, deactivate or float)", args[0])
+	}
+	return nil
+}
+
+func listEmployees(store database.Store) error {
+	employees, err := store.AllEmployees()
+	if err != nil {
+		return err
+	}
+	if len(employees) == 0 {
+		fmt.Println("No employees. Import some with: shiftopt import employees <file>")
+		return nil
+	}
+
+	fmt.Printf("%-4s %-20s %8s  %-7s %-8s %-4s %s\n", "ID", "NAME", "RATE", "SKILL", "STATUS", "LOC", "FLOATS")
+	for _, e := range employees {
+		skill, status, floats := "Junior", "active", ""
+		if e.SkillLevel >= 2 {
+			skill = "Senior"
+		}
+		if !e.Active {
+			status = "inactive"
+		}
+		if e.Floats {
+			floats = "yes"
+		}
+		fmt.Printf("%-4d %-20s %8.2f  %-7s %-8s %-4d %s\n", e.ID, e.Name, e.HourlyRate, skill, status, e.LocationID, floats)
+	}
+	return nil
+}
+
+// findEmployee looks an employee up by ID, inactive staff included
+func findEmployee(store database.Store, arg string) (models.Employee, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return models.Employee{}, fmt.Errorf("invalid employee %q", arg)
+	}
+	employees, err := store.AllEmployees()
+	if err != nil {
+		return models.Employee{}, err
+	}
+	for _, e := range employees {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return models.Employee{}, fmt.Errorf("employee %d not found", id)
+}
+
+func parseEmployee(name, rate, skill string) (models.Employee, error) {
+	e := models.Employee{Name: name}
+	var err error
+	if e.HourlyRate, err = strconv.ParseFloat(rate, 64); err != nil {
+		return e, fmt.Errorf("invalid hourly rate %q", rate)
+	}
+	if e.SkillLevel, err = strconv.Atoi(skill); err != nil {
+		return e, fmt.Errorf("invalid skill level %q", skill)
+	}
+	return e, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/inbound"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// cmdContacts lists, adds or removes the numbers and addresses staff message from
+func cmdContacts(db *sql.DB, args []string) error {
+	store := database.NewSQLiteStore(db)
+	if len(args) == 0 {
+		contacts, err := store.Contacts()
+		if err != nil {
+			return err
+		}
+		if len(contacts) == 0 {
+			fmt.Println("No contacts yet. Add one with: shiftopt contacts add <name> <phone|email>")
+		}
+		for _, c := range contacts {
+			fmt.Printf("%4d  %-20s  %s\n", c.ID, c.EmployeeName, c.Address)
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: shiftopt contacts add <name> <phone|email>")
+		}
+		employeeID, err := store.EmployeeIDByName(args[1])
+		if err != nil {
+			return fmt.Errorf("employee %q not found", args[1])
+		}
+		id, err := store.AddContact(models.Contact{EmployeeID: employeeID, Address: args[2]})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Contact %d: messages from %s are from %s.\n", id, args[2], args[1])
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: shiftopt contacts remove <id>")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid contact id %q", args[1])
+		}
+		if err := store.RemoveContact(id); err != nil {
+			return err
+		}
+		fmt.Printf("Contact %d removed.\n", id)
+	default:
+		return fmt.Errorf("unknown contacts command %q (want add or remove)", args[0])
+	}
+	return nil
+}
+
+// cmdSMS receives a text as the SMS webhook would, for trying the gateway locally
+func cmdSMS(db *sql.DB, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: shiftopt sms <from-number> <message>")
+	}
+	return receive(db, inbound.Message{Channel: models.ChannelSMS, From: args[0], Text: strings.Join(args[1:], " ")})
+}
+
+// cmdInbox reads the new staff email in a maildir
+func cmdInbox(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: shiftopt inbox <maildir>")
+	}
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	now, err := localNow(db)
+	if err != nil {
+		return err
+	}
+	results, err := inbound.Maildir(args[0]).Receive(context.Background(), store, ai.CachedFromEnv(store), now)
+	for _, res := range results {
+		fmt.Printf("%s\n", res.File)
+		printReceived(res.Record, res.Outcome)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d new message(s).\n", len(results))
+	return nil
+}
+
+// cmdInbound lists the messages received, and what became of them
+func cmdInbound(db *sql.DB, args []string) error {
+	messages, err := database.NewSQLiteStore(db).InboundMessages()
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		fmt.Println("No messages received yet.")
+	}
+	for _, m := range messages {
+		who := m.EmployeeName
+		if who == "" {
+			who = "?"
+		}
+		fmt.Printf("%4d  %s  %-5s  %-22s  %-20s  %-8s  %q\n", m.ID, m.ReceivedAt.Local().Format("2006-01-02 15:04"), m.Channel, m.Sender, who, m.Status, m.Text)
+		if m.Error != "" {
+			fmt.Printf("      %s\n", m.Error)
+		}
+	}
+	return nil
+}
+
+func receive(db *sql.DB, m inbound.Message) error {
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	now, err := localNow(db)
+	if err != nil {
+		return err
+	}
+	record, outcome, err := inbound.Receive(context.Background(), store, ai.CachedFromEnv(store), m, now)
+	if err != nil {
+		return err
+	}
+	printReceived(record, outcome)
+	return nil
+}
+
+// printReceived shows what became of a message and the reply the sender gets
+func printReceived(record models.InboundMessage, outcome ai.Outcome) {
+	switch record.Status {
+	case models.InboundRejected:
+		fmt.Printf("  Rejected: %s\n", record.Error)
+	default:
+		printOutcome(outcome)
+	}
+	if reply := inbound.Reply(record, outcome); reply != "" {
+		fmt.Printf("  Reply to %s: %s\n", record.Sender, reply)
+	}
+}
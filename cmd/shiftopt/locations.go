@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+// storeFor applies the -location flag: commands that go through it only see that store
+func storeFor(db *sql.DB) (database.Store, error) {
+	store := database.NewSQLiteStore(db)
+	if *location == "" {
+		return store, nil
+	}
+	l, err := store.LocationByName(*location)
+	if err != nil {
+		return nil, err
+	}
+	return store.AtLocation(l.ID), nil
+}
+
+// scheduleStore is storeFor for a scheduling run: a run is for one store, so without
+// -location it schedules the default one rather than every store's crew and demand at once
+func scheduleStore(db *sql.DB) (database.Store, error) {
+	if *location == "" {
+		return database.NewSQLiteStore(db).AtLocation(models.DefaultLocation), nil
+	}
+	return storeFor(db)
+}
+
+// localNow is the current time at the -location store (the default store without the flag)
+func localNow(db *sql.DB) (time.Time, error) {
+	store := database.NewSQLiteStore(db)
+	id := 0
+	if *location != "" {
+		l, err := store.LocationByName(*location)
+		if err != nil {
+			return time.Time{}, err
+		}
+		id = l.ID
+	}
+	return database.LocalNow(store, id)
+}
+
+// cmdLocations lists the stores, or changes them with add/travel/timezone
+func cmdLocations(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return listLocations(db)
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("usage: shiftopt locations add <name> [region]")
+		}
+		region := ""
+		if len(args) == 3 {
+			region = args[2]
+		}
+		id, err := database.AddLocation(db, args[1], region)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Location %d: %s added.\n", id, args[1])
+	case "travel":
+		if len(args) != 4 {
+			return fmt.Errorf("usage: shiftopt locations travel <from> <to> <minutes>")
+		}
+		store := database.NewSQLiteStore(db)
+		from, err := store.LocationByName(args[1])
+		if err != nil {
+			return err
+		}
+		to, err := store.LocationByName(args[2])
+		if err != nil {
+			return err
+		}
+		minutes, err := strconv.Atoi(args[3])
+		if err != nil {
+			return fmt.Errorf("invalid minutes %q", args[3])
+		}
+		if err := database.SetTravelTime(db, from.ID, to.ID, minutes); err != nil {
+			return err
+		}
+		fmt.Printf("Travel %s <-> %s: %d min.\n", from.Name, to.Name, minutes)
+	case "timezone":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: shiftopt locations timezone <name> <zone>  (an IANA name, e.g. Europe/Lisbon)")
+		}
+		store := database.NewSQLiteStore(db)
+		l, err := store.LocationByName(args[1])
+		if err != nil {
+			return err
+		}
+		if err := store.SetLocationTimezone(l.ID, args[2]); err != nil {
+			return err
+		}
+		fmt.Printf("%s now runs on %s time.\n", l.Name, args[2])
+	default:
+		return fmt.Errorf("unknown locations command %q (want add, travel or timezone)", args[0])
+	}
+	return nil
+}
+
+func listLocations(db *sql.DB) error {
+	store := database.NewSQLiteStore(db)
+	locations, err := store.Locations()
+	if err != nil {
+		return err
+	}
+	times, err := store.TravelTimes()
+	if err != nil {
+		return err
+	}
+	names := make(map[int]string)
+	for _, l := range locations {
+		names[l.ID] = l.Name
+	}
+
+	fmt.Printf("%-4s %-20s %-12s %s\n", "ID", "NAME", "REGION", "TIMEZONE")
+	for _, l := range locations {
+		zone := l.Timezone
+		if zone == "" {
+			zone = "(server)"
+		}
+		fmt.Printf("%-4d %-20s %-12s %s\n", l.ID, l.Name, l.Region, zone)
+	}
+	if len(times) > 0 {
+		fmt.Println("\nTravel times:")
+		for _, t := range times {
+			fmt.Printf("  %s <-> %s: %d min\n", names[t.FromID], names[t.ToID], t.Minutes)
+		}
+	}
+	return nil
+}
+
+// cmdRegion schedules every store of a region together and saves one draft per store
+func cmdRegion(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: shiftopt region <name>")
+	}
+	today, err := runDay(db)
+	if err != nil {
+		return err
+	}
+	rosters, err := scheduler.Region(context.Background(), database.NewSQLiteStore(db).OnDay(today), args[0])
+	if err != nil {
+		return err
+	}
+	locations, err := database.ListLocations(db)
+	if err != nil {
+		return err
+	}
+	names := make(map[int]string)
+	for _, l := range locations {
+		names[l.ID] = l.Name
+	}
+
+	fmt.Printf("\n[Region %s]\n", args[0])
+	for _, r := range rosters {
+		r.Date = today
+		id, err := database.SaveRoster(db, r, models.RosterDraft, *author)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  %-20s | Cost: $%7.2f | Unfilled: %d | draft v%d\n", names[r.LocationID], r.TotalCost, r.Unfilled, id)
+	}
+	return nil
+}
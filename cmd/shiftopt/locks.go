@@ -0,0 +1,96 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+func cmdPin(db *sql.DB, args []string) error {
+	return addLock(db, args, models.LockPinned)
+}
+
+func cmdForbid(db *sql.DB, args []string) error {
+	return addLock(db, args, models.LockForbidden)
+}
+
+func addLock(db *sql.DB, args []string, kind string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: shiftopt %s <employee name> <start hour> <end hour>", map[string]string{
+			models.LockPinned: "pin", models.LockForbidden: "forbid",
+		}[kind])
+	}
+	empID, err := database.GetEmployeeIDByName(db, args[0])
+	if err != nil {
+		return fmt.Errorf("employee %q not found", args[0])
+	}
+	start, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid start hour %q", args[1])
+	}
+	end, err := strconv.Atoi(args[2])
+	if err != nil {
+		return fmt.Errorf("invalid end hour %q", args[2])
+	}
+
+	id, err := database.AddLock(db, empID, start, end, kind)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Lock %d: %s %s %02d:00-%02d:00\n", id, args[0], kind, start, end)
+	return printConflicts(db)
+}
+
+func cmdUnlock(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: shiftopt unlock <lock id>")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid lock %q", args[0])
+	}
+	if err := database.RemoveLock(db, id); err != nil {
+		return err
+	}
+	fmt.Printf("Lock %d removed.\n", id)
+	return nil
+}
+
+func cmdLocks(db *sql.DB, args []string) error {
+	locks, err := database.ListLocks(db)
+	if err != nil {
+		return err
+	}
+	if len(locks) == 0 {
+		fmt.Println("No locks.")
+		return nil
+	}
+	for _, l := range locks {
+		name := l.EmployeeName
+		if name == "" {
+			name = fmt.Sprintf("<unknown #%d>", l.EmployeeID)
+		}
+		day := "every day"
+		if !l.Date.IsZero() {
+			day = l.Date.Format(database.DateLayout)
+		}
+		fmt.Printf("  %-4d | %-20s | %-9s | %02d:00-%02d:00 | %s\n", l.ID, name, l.Kind, l.StartHour, l.EndHour, day)
+	}
+	return printConflicts(db)
+}
+
+// printConflicts reports the problems the schedulers would refuse to run with
+func printConflicts(db *sql.DB) error {
+	conflicts, err := scheduler.CheckLocks(db)
+	if err != nil {
+		return err
+	}
+	for _, c := range conflicts {
+		fmt.Printf("  CONFLICT: %s\n", c.Reason)
+	}
+	return nil
+}
@@ -1,48 +1,249 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
 	"log"
-    "fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
 	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/inbound"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/payroll"
 	"github.com/iannsp/shiftopt/internal/scheduler"
-	"github.com/iannsp/shiftopt/internal/ai"
+)
+
+// commands are the optional subcommands (shiftopt <command> [args]).
+// Without a subcommand, shiftopt schedules the day and exports the roster.
+var commands = map[string]func(db *sql.DB, args []string) error{
+	"versions":    cmdVersions,
+	"diff":        cmdDiff,
+	"publish":     cmdPublish,
+	"repair":      cmdRepair,
+	"pin":         cmdPin,
+	"forbid":      cmdForbid,
+	"unlock":      cmdUnlock,
+	"locks":       cmdLocks,
+	"migrate":     cmdMigrate,
+	"employees":   cmdEmployees,
+	"import":      cmdImport,
+	"locations":   cmdLocations,
+	"region":      cmdRegion,
+	"dashboard":   cmdDashboard,
+	"calendar":    cmdCalendar,
+	"unavailable": cmdUnavailable,
+	"message":     cmdUnavailable,
+	"requests":    cmdRequests,
+	"approve":     cmdApprove,
+	"deny":        cmdDeny,
+	"ask":         cmdAsk,
+	"contacts":    cmdContacts,
+	"sms":         cmdSMS,
+	"inbox":       cmdInbox,
+	"inbound":     cmdInbound,
+	"pending":     cmdPending,
+	"answer":      cmdAnswer,
+	"export":      cmdExport,
+	"week":        cmdWeek,
+	"payroll":     cmdPayroll,
+}
+
+var (
+	dbPath   = flag.String("db", "shiftopt.db", "SQLite database file")
+	author   = flag.String("author", os.Getenv("USER"), "Name recorded on saved roster versions")
+	seed     = flag.Bool("seed", false, "Replace the crew and demand with random demo data (and a simulated SMS)")
+	location = flag.String("location", "", "Only work on this store (default: the whole database; runs schedule the default store)")
+	date     = flag.String("date", "", "Day the default run schedules, yyyy-mm-dd (default: today at the store)")
+	budget   = flag.Float64("budget", 0, "Daily labour budget shown on the dashboard (0 = none)")
+	format   = flag.String("format", "csv", "Roster export format: "+strings.Join(scheduler.ExportFormats(), ", "))
+	layout   = flag.String("layout", scheduler.LayoutHourly, "Export rows: hourly (one per hour worked) or shift (one per shift)")
+	columns  = flag.String("columns", strings.Join(scheduler.DefaultColumns, ","), "Export columns after time and name: role, rate, cost, safety")
+	apply    = flag.Bool("apply", false, "Save what ask previews (default: a dry run)")
+	from     = flag.String("from", "", "Employee who sent the message read by unavailable/message (\"I\" and \"me\")")
+
+	payrollLayout = flag.String("payroll-layout", payroll.LayoutGeneric, "Payroll CSV layout: "+strings.Join(payroll.Layouts(), ", "))
+	companyCode   = flag.String("company-code", "", "Company code written on payroll rows (required by the adp layout)")
 )
 
 func main() {
-	db, err := database.InitDB("shiftopt.db")
+	flag.Usage = usage
+	flag.Parse()
+
+	// migrate manages the schema itself, everything else gets an up-to-date database
+	open := database.InitDB
+	if flag.Arg(0) == "migrate" {
+		open = database.OpenDB
+	}
+	db, err := open(*dbPath)
 	if err != nil { log.Fatal(err) }
 	defer db.Close()
-	
-	// We only seed if we want fresh random data. 
-	// For now, let's assume we always simulate a new day.
-	database.SeedData(db)
-
-
-// --- STEP 3: SIMULATE USER INPUT (The "Product" Feature) ---
-	// "Alice" is our Senior Vet. Let's block her for the morning.
-	incomingText := "Alice has a dentist appointment in the morning"
-	
-	fmt.Printf("\n[Input] SMS Received: %q\n", incomingText)
-	
-	// 1. Parse
-	constraint := ai.ParseConstraint(incomingText)
-	fmt.Printf("[AI] Parsed: Who=%s, When=%d:00-%d:00, Why=%s\n", 
-		constraint.EmployeeName, constraint.StartHour, constraint.EndHour, constraint.Reason)
-
-	// 2. Resolve ID
-	empID, err := database.GetEmployeeIDByName(db, constraint.EmployeeName)
-	if err == nil {
-		// 3. Save to DB
-		database.AddUnavailability(db, empID, constraint.StartHour, constraint.EndHour, constraint.Reason)
-		fmt.Println("[DB] Constraint Saved successfully.")
-	} else {
-		fmt.Println("[Error] Employee not found.")
+
+	if flag.NArg() > 0 {
+		cmd, ok := commands[flag.Arg(0)]
+		if !ok {
+			usage()
+			os.Exit(2)
+		}
+		if err := cmd(db, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	roster, err := scheduler.RunSmartTetris(db)
+	exporter, err := exporterFromFlags()
+	if err != nil { log.Fatal(err) }
+
+	// We only seed if we want fresh random data.
+	if *seed {
+		database.SeedData(db)
+		simulateSMS(db)
+	}
+	store, err := scheduleStore(db)
+	if err != nil { log.Fatal(err) }
+	if crew, err := store.Employees(); err != nil || len(crew) == 0 {
+		fmt.Println("No employees yet. Run with -seed for demo data, or: shiftopt import employees <file>")
+		return
+	}
+
+	// The day's roster: blocks dated another day do not apply
+	day, err := runDay(db)
+	if err != nil { log.Fatal(err) }
+	roster, err := scheduler.SmartTetris(context.Background(), store.OnDay(day))
 	if err != nil { log.Fatal(err) }
+	roster.Date = day
 
-	// The Goal: Deliver the CSV
-	err = scheduler.ExportToCSV(roster, "roster.csv")
+	// Keep every run as a draft version, so it can be compared and published later
+	versionID, err := store.SaveRoster(roster, models.RosterDraft, *author)
 	if err != nil { log.Fatal(err) }
+	fmt.Printf("[DB] Roster saved as draft version %d.\n", versionID)
+
+	// The Goal: Deliver the roster file
+	filename := "roster." + *format
+	err = scheduler.ExportFile(roster, exporter, filename)
+	if err != nil { log.Fatal(err) }
+	fmt.Printf("Success: Roster exported to %s\n", filename)
+}
+
+// runDay is the -date flag, or today at the store
+func runDay(db *sql.DB) (time.Time, error) {
+	if *date != "" {
+		day, err := time.Parse(database.DateLayout, *date)
+		if err != nil {
+			return day, fmt.Errorf("invalid -date %q (want yyyy-mm-dd)", *date)
+		}
+		return day, nil
+	}
+	now, err := localNow(db)
+	if err != nil {
+		return now, err
+	}
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+}
+
+// --- SIMULATE USER INPUT (The "Product" Feature) ---
+func simulateSMS(db *sql.DB) {
+	// "Alice" is our Senior Vet. She texts from her phone to block her morning.
+	sms := inbound.Message{Channel: models.ChannelSMS, From: "+15550100001", Text: "I have a dentist appointment in the morning"}
+
+	fmt.Printf("\n[Input] SMS Received from %s: %q\n", sms.From, sms.Text)
+
+	// 1. Her number names her, 2. Parse against the current crew, 3. Save to DB with the message
+	store := database.NewSQLiteStore(db)
+	alice, err := store.EmployeeIDByName("Alice (Vet)")
+	if err != nil {
+		fmt.Printf("[Error] %v\n", err)
+		return
+	}
+	if _, err := store.AddContact(models.Contact{EmployeeID: alice, Address: sms.From}); err != nil {
+		fmt.Printf("[Error] %v\n", err)
+		return
+	}
+	now, err := database.LocalNow(store, models.DefaultLocation)
+	if err != nil {
+		fmt.Printf("[Error] %v\n", err)
+		return
+	}
+	record, outcome, err := inbound.Receive(context.Background(), store, ai.CachedFromEnv(store), sms, now)
+	if err != nil {
+		fmt.Printf("[Error] %v\n", err)
+		return
+	}
+	fmt.Println("[AI] Parsed:")
+	printReceived(record, outcome)
+	if record.Status == models.InboundSaved {
+		fmt.Println("[DB] Constraint Saved successfully.")
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: shiftopt [flags] [command]")
+	fmt.Fprintln(os.Stderr, "\nWithout a command, schedules the day, saves a draft roster and exports roster.<format>.")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	fmt.Fprintln(os.Stderr, "  versions            List saved roster versions")
+	fmt.Fprintln(os.Stderr, "  diff <from> <to>    Compare two roster versions")
+	fmt.Fprintln(os.Stderr, "  publish <id>        Mark a draft version as published")
+	fmt.Fprintln(os.Stderr, "  repair <id|latest> <name> <s> <e> [reason]")
+	fmt.Fprintln(os.Stderr, "                      Call-out for hours [s, e): patch the roster, keeping other shifts")
+	fmt.Fprintln(os.Stderr, "  pin <name> <s> <e>  Force an employee to work hours [s, e)")
+	fmt.Fprintln(os.Stderr, "  forbid <name> <s> <e>")
+	fmt.Fprintln(os.Stderr, "                      Keep an employee off hours [s, e)")
+	fmt.Fprintln(os.Stderr, "  unlock <id>         Remove a pin or forbid")
+	fmt.Fprintln(os.Stderr, "  locks               List pins/forbids and their conflicts")
+	fmt.Fprintln(os.Stderr, "  migrate [status|up|down|to <n>]")
+	fmt.Fprintln(os.Stderr, "                      Manage the database schema version")
+	fmt.Fprintln(os.Stderr, "  employees           List the crew")
+	fmt.Fprintln(os.Stderr, "  employees add <name> <rate> <skill>")
+	fmt.Fprintln(os.Stderr, "  employees update <id> <name> <rate> <skill>")
+	fmt.Fprintln(os.Stderr, "  employees deactivate <id>")
+	fmt.Fprintln(os.Stderr, "                      Hire, edit or retire an employee (skill: 1 Junior, 2 Senior)")
+	fmt.Fprintln(os.Stderr, "  import employees|demand|unavailability <file>")
+	fmt.Fprintln(os.Stderr, "                      Load a .csv or .json file (rejected whole if any row is invalid)")
+	fmt.Fprintln(os.Stderr, "  import roster <file> [yyyy-mm-dd]")
+	fmt.Fprintln(os.Stderr, "                      Save a hand-edited export as a draft and list the rules it breaks")
+	fmt.Fprintln(os.Stderr, "  employees float <id> on|off")
+	fmt.Fprintln(os.Stderr, "                      Let an employee cover nearby stores in the same region")
+	fmt.Fprintln(os.Stderr, "  locations           List stores and travel times")
+	fmt.Fprintln(os.Stderr, "  locations add <name> [region]")
+	fmt.Fprintln(os.Stderr, "  locations travel <from> <to> <minutes>")
+	fmt.Fprintln(os.Stderr, "                      Open a store, or set the trip between two stores")
+	fmt.Fprintln(os.Stderr, "  locations timezone <name> <zone>")
+	fmt.Fprintln(os.Stderr, "                      Set a store's IANA time zone: \"today\" and \"tomorrow\" are read there")
+	fmt.Fprintln(os.Stderr, "  region <name>       Schedule every store of a region together for -date (floaters fill gaps)")
+	fmt.Fprintln(os.Stderr, "  dashboard [version] [file]")
+	fmt.Fprintln(os.Stderr, "                      Write an HTML dashboard of a roster (default: latest, dashboard.html)")
+	fmt.Fprintln(os.Stderr, "  export [version] [file]")
+	fmt.Fprintln(os.Stderr, "                      Export a saved roster with -format/-layout/-columns (default: latest, roster.<format>)")
+	fmt.Fprintln(os.Stderr, "  week [yyyy-mm-dd] [file]")
+	fmt.Fprintln(os.Stderr, "                      Print the week's grid to .html or .pdf (default: this week, week.html)")
+	fmt.Fprintln(os.Stderr, "  payroll <from> <to> [file]")
+	fmt.Fprintln(os.Stderr, "                      Regular/overtime/premium hours and gross pay per employee (default: payroll.csv)")
+	fmt.Fprintln(os.Stderr, "  calendar [version] [dir]")
+	fmt.Fprintln(os.Stderr, "                      Write .ics calendars per employee plus roster.ics (default: latest, calendars/)")
+	fmt.Fprintln(os.Stderr, "  unavailable <message>")
+	fmt.Fprintln(os.Stderr, "  message <message>   Save everything a free-text staff message asks for, all or none:")
+	fmt.Fprintln(os.Stderr, "                      unavailability, offers of extra hours, time off and swaps (-from: who sent it)")
+	fmt.Fprintln(os.Stderr, "                      (held with a question when a name, day or time is unclear)")
+	fmt.Fprintln(os.Stderr, "  pending             List held messages and their questions")
+	fmt.Fprintln(os.Stderr, "  answer <id> <reply> Answer a held message's question (\"Mary Jones\", \"2pm to 5pm\", \"yes\")")
+	fmt.Fprintln(os.Stderr, "  requests [pending|approved|denied]")
+	fmt.Fprintln(os.Stderr, "                      List time-off and swap requests (default: pending)")
+	fmt.Fprintln(os.Stderr, "  approve|deny time-off|swap <id>")
+	fmt.Fprintln(os.Stderr, "                      Decide a request (approved time off becomes unavailability)")
+	fmt.Fprintln(os.Stderr, "  ask <command>       \"who is working Saturday close?\", \"give Grace four more hours this week\",")
+	fmt.Fprintln(os.Stderr, "                      \"what if Bob is off Friday?\": previews the trial roster (-apply saves it as a draft)")
+	fmt.Fprintln(os.Stderr, "  contacts [add <name> <phone|email> | remove <id>]")
+	fmt.Fprintln(os.Stderr, "                      The numbers and addresses staff text and email from")
+	fmt.Fprintln(os.Stderr, "  sms <from> <message>")
+	fmt.Fprintln(os.Stderr, "                      Receive a text as the SMS webhook would (POST /inbound/sms)")
+	fmt.Fprintln(os.Stderr, "  inbox <maildir>     Read the new staff email in a maildir (fill it from IMAP with fetchmail or mbsync)")
+	fmt.Fprintln(os.Stderr, "  inbound             List received texts and emails, and what became of them")
+	fmt.Fprintln(os.Stderr, "\n-location scopes the default run, versions, employees and import to one store.")
+	fmt.Fprintln(os.Stderr, "\nFlags:")
+	flag.PrintDefaults()
 }
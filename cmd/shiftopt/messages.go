@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/database"
+)
+
+// cmdUnavailable reads a free-text message ("Alice can't do Monday morning or Friday",
+// "I can pick up extra hours Saturday") and saves everything it asks for, all or none
+func cmdUnavailable(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: shiftopt [-from <name>] message <message>")
+	}
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	now, err := localNow(db) // "Tomorrow" at the store
+	if err != nil {
+		return err
+	}
+	senderID := 0
+	if *from != "" {
+		if senderID, err = store.EmployeeIDByName(*from); err != nil {
+			return fmt.Errorf("employee %q not found", *from)
+		}
+	}
+	outcome, err := ai.RecordFrom(context.Background(), store, ai.CachedFromEnv(store), senderID, strings.Join(args, " "), now)
+	if err != nil {
+		return err
+	}
+	printOutcome(outcome)
+	return nil
+}
+
+// cmdPending lists the messages waiting for an answer
+func cmdPending(db *sql.DB, args []string) error {
+	pending, err := database.NewSQLiteStore(db).PendingMessages()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Println("No messages waiting for an answer.")
+		return nil
+	}
+	for _, p := range pending {
+		fmt.Printf("%4d  %q\n      %s\n", p.ID, p.Text, p.Question)
+	}
+	return nil
+}
+
+// cmdAnswer replies to the question of a pending message
+func cmdAnswer(db *sql.DB, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: shiftopt answer <pending-id> <reply>")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid pending message id %q", args[0])
+	}
+	now, err := localNow(db)
+	if err != nil {
+		return err
+	}
+	outcome, err := ai.Answer(database.NewSQLiteStore(db), id, strings.Join(args[1:], " "), now)
+	if err != nil {
+		return err
+	}
+	printOutcome(outcome)
+	return nil
+}
+
+// printOutcome shows what was saved, or what was held and the question to ask
+func printOutcome(outcome ai.Outcome) {
+	printConstraints(outcome.Constraints)
+	if outcome.Held() {
+		fmt.Printf("Held as pending message %d, nothing saved yet.\n%s\n", outcome.PendingID, outcome.Question)
+		fmt.Printf("Reply with: shiftopt answer %d <reply>\n", outcome.PendingID)
+		return
+	}
+	fmt.Printf("Saved %d constraint(s).\n", len(outcome.Constraints))
+}
+
+func printConstraints(found []ai.Constraint) {
+	for _, c := range found {
+		day := "every day"
+		if !c.Date.IsZero() {
+			day = c.Date.Format("Mon ") + c.Date.Format(database.DateLayout)
+		}
+		unclear := ""
+		for _, a := range c.Unclear {
+			unclear += " | unclear: " + a.Field
+		}
+		what := c.Reason
+		switch c.Intent {
+		case ai.IntentAvailable:
+			what = "Offers hours"
+		case ai.IntentTimeOff:
+			what = "Asks for time off"
+		case ai.IntentSwap:
+			what = "Swap with " + c.SwapWith.Name
+		}
+		fmt.Printf("  %-20s | %-14s | %02d:00-%02d:00 | %-18s | confidence %.0f%%%s\n",
+			c.EmployeeName, day, c.StartHour, c.EndHour, what, 100*c.Confidence, unclear)
+	}
+}
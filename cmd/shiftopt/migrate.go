@@ -0,0 +1,64 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/iannsp/shiftopt/internal/database"
+)
+
+// cmdMigrate runs on a database opened without auto-migration:
+//
+//	shiftopt migrate            apply all pending migrations
+//	shiftopt migrate status     show applied and pending migrations
+//	shiftopt migrate down       revert the newest migration
+//	shiftopt migrate to <n>     move up or down to version n
+func cmdMigrate(db *sql.DB, args []string) error {
+	current, err := database.SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	target := current
+	switch action {
+	case "status":
+		for _, m := range database.Migrations() {
+			state := "pending"
+			if m.Version <= current {
+				state = "applied"
+			}
+			fmt.Printf("  %03d  %-20s %s\n", m.Version, m.Name, state)
+		}
+		fmt.Printf("Schema version: %d (latest %d)\n", current, database.LatestVersion())
+		return nil
+	case "up":
+		target = database.LatestVersion()
+	case "down":
+		if current == 0 {
+			return fmt.Errorf("nothing to revert")
+		}
+		target = current - 1
+	case "to":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: shiftopt migrate to <version>")
+		}
+		target, err = strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+	default:
+		return fmt.Errorf("usage: shiftopt migrate [status|up|down|to <version>]")
+	}
+
+	if err := database.Migrate(db, target); err != nil {
+		return err
+	}
+	fmt.Printf("Schema version: %d -> %d\n", current, target)
+	return nil
+}
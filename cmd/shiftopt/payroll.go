@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/payroll"
+)
+
+// cmdPayroll totals hours and gross pay per employee for the days from..to (inclusive)
+func cmdPayroll(db *sql.DB, args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("usage: shiftopt payroll <from yyyy-mm-dd> <to yyyy-mm-dd> [out.csv]")
+	}
+	from, err := time.Parse(database.DateLayout, args[0])
+	if err != nil {
+		return fmt.Errorf("invalid date %q (want yyyy-mm-dd)", args[0])
+	}
+	to, err := time.Parse(database.DateLayout, args[1])
+	if err != nil {
+		return fmt.Errorf("invalid date %q (want yyyy-mm-dd)", args[1])
+	}
+	if to.Before(from) {
+		return fmt.Errorf("period ends before it starts")
+	}
+	filename := "payroll.csv"
+	if len(args) == 3 {
+		filename = args[2]
+	}
+
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	report, err := payroll.Load(store, from, to.AddDate(0, 0, 1), payroll.DefaultRules)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n[Payroll] %s to %s\n", args[0], args[1])
+	fmt.Printf("  %-20s | %7s | %8s | %7s | %10s\n", "Employee", "Regular", "Overtime", "Premium", "Gross")
+	for _, l := range report.Lines {
+		fmt.Printf("  %-20s | %7d | %8d | %7d | $%9.2f\n", l.Name, l.RegularHours, l.OvertimeHours, l.PremiumHours, l.Gross)
+	}
+	fmt.Printf("  %-20s | %27d h | $%9.2f\n", "Total", report.Hours, report.Gross)
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := payroll.WriteCSV(file, report, payroll.CSVOptions{Layout: *payrollLayout, CompanyCode: *companyCode}); err != nil {
+		return err
+	}
+	fmt.Printf("Success: Payroll (%s layout) written to %s\n", *payrollLayout, filename)
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// cmdRequests lists the time-off and swap requests read from staff messages
+func cmdRequests(db *sql.DB, args []string) error {
+	status := models.RequestPending
+	if len(args) > 0 {
+		status = args[0]
+	}
+	store := database.NewSQLiteStore(db)
+	timeOff, err := store.TimeOffRequests(status)
+	if err != nil {
+		return err
+	}
+	swaps, err := store.SwapRequests(status)
+	if err != nil {
+		return err
+	}
+	if len(timeOff)+len(swaps) == 0 {
+		fmt.Printf("No %s requests.\n", status)
+		return nil
+	}
+	for _, t := range timeOff {
+		fmt.Printf("time-off %4d | %-20s | %-14s | %02d:00-%02d:00 | %-8s | %q\n",
+			t.ID, t.EmployeeName, requestDay(t.Date), t.StartHour, t.EndHour, t.Status, t.Message)
+	}
+	for _, w := range swaps {
+		fmt.Printf("swap     %4d | %-20s | %-14s | %02d:00-%02d:00 | %-8s | with %s: %q\n",
+			w.ID, w.EmployeeName, requestDay(w.Date), w.StartHour, w.EndHour, w.Status, w.WithName, w.Message)
+	}
+	return nil
+}
+
+func requestDay(day time.Time) string {
+	if day.IsZero() {
+		return "every day"
+	}
+	return day.Format(database.DateLayout)
+}
+
+func cmdApprove(db *sql.DB, args []string) error {
+	return decideRequest(db, args, models.RequestApproved)
+}
+
+func cmdDeny(db *sql.DB, args []string) error {
+	return decideRequest(db, args, models.RequestDenied)
+}
+
+func decideRequest(db *sql.DB, args []string, status string) error {
+	verb := map[string]string{models.RequestApproved: "approve", models.RequestDenied: "deny"}[status]
+	if len(args) != 2 {
+		return fmt.Errorf("usage: shiftopt %s time-off|swap <request id>", verb)
+	}
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid request id %q", args[1])
+	}
+	store := database.NewSQLiteStore(db)
+	switch args[0] {
+	case "time-off":
+		err = store.DecideTimeOff(id, status)
+	case "swap":
+		err = store.DecideSwap(id, status)
+	default:
+		return fmt.Errorf("usage: shiftopt %s time-off|swap <request id>", verb)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Request %d %s.\n", id, status)
+	if args[0] == "swap" && status == models.RequestApproved {
+		fmt.Println("Move the shifts on the roster (pin/forbid, or import an edited export).")
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/importer"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+func cmdVersions(db *sql.DB, args []string) error {
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	versions, err := store.ListRosterVersions()
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		fmt.Println("No roster versions saved yet.")
+		return nil
+	}
+
+	fmt.Printf("  %-4s | %-10s | %-9s | %-12s | %-20s | %9s | %s\n", "ID", "Date", "Status", "Author", "Created", "Cost", "Unfilled")
+	for _, v := range versions {
+		fmt.Printf("  %-4d | %-10s | %-9s | %-12s | %-20s | $%8.2f | %d\n",
+			v.ID, dateLabel(v), v.Status, v.Author, v.CreatedAt.Format("2006-01-02 15:04:05"), v.TotalCost, v.Unfilled)
+	}
+	return nil
+}
+
+func cmdDiff(db *sql.DB, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: shiftopt diff <from-version> <to-version>")
+	}
+	fromID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid version %q", args[0])
+	}
+	toID, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid version %q", args[1])
+	}
+
+	from, fromV, err := database.LoadRoster(db, fromID)
+	if err != nil {
+		return err
+	}
+	to, toV, err := database.LoadRoster(db, toID)
+	if err != nil {
+		return err
+	}
+
+	diff := scheduler.DiffRosters(from, to)
+
+	fmt.Printf("\n[Roster Diff] v%d (%s, %s, %s) -> v%d (%s, %s, %s)\n",
+		fromV.ID, dateLabel(fromV), fromV.Status, fromV.Author,
+		toV.ID, dateLabel(toV), toV.Status, toV.Author)
+
+	printDiff(from, to, diff)
+	return nil
+}
+
+// cmdRepair patches a roster version after a call-out: the call-out is saved for the roster's
+// day, and the repaired roster as a new draft, changing as few shifts as possible
+func cmdRepair(db *sql.DB, args []string) error {
+	if len(args) < 4 {
+		return fmt.Errorf("usage: shiftopt repair <version|latest> <employee name> <start hour> <end hour> [reason]")
+	}
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	id, err := versionArg(store, args[0])
+	if err != nil {
+		return err
+	}
+	start, err := strconv.Atoi(args[2])
+	if err != nil {
+		return fmt.Errorf("invalid start hour %q", args[2])
+	}
+	end, err := strconv.Atoi(args[3])
+	if err != nil {
+		return fmt.Errorf("invalid end hour %q", args[3])
+	}
+	reason := "Call-out"
+	if len(args) > 4 {
+		reason = strings.Join(args[4:], " ")
+	}
+
+	published, v, err := store.LoadRoster(id)
+	if err != nil {
+		return err
+	}
+	callOut := models.Unavailability{EmployeeName: args[1], StartHour: start, EndHour: end, Reason: reason, Date: v.Date}
+	repaired, diff, err := scheduler.Repair(store, published, callOut)
+	if err != nil {
+		return err
+	}
+
+	// Keep the call-out with the draft, so later runs for the day leave the hours out too
+	callOut.EmployeeID, err = store.EmployeeIDByName(args[1])
+	if err != nil {
+		return err
+	}
+	versions, err := store.SaveChange([]models.Unavailability{callOut}, nil, []*models.Roster{repaired}, *author)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n[Roster Repair] v%d (%s, %s) -> draft v%d\n", v.ID, dateLabel(v), v.Status, versions[0])
+	printDiff(published, repaired, diff)
+	return nil
+}
+
+// printDiff lists the shift changes between two rosters and what they cost
+func printDiff(from, to *models.Roster, diff models.RosterDiff) {
+	if scheduler.ChangeCount(diff) == 0 {
+		fmt.Println("  No shift changes.")
+	}
+	for _, s := range diff.Added {
+		fmt.Printf("  + %-20s %s\n", s.Employee.Name, shiftLabel(s))
+	}
+	for _, s := range diff.Removed {
+		fmt.Printf("  - %-20s %s\n", s.Employee.Name, shiftLabel(s))
+	}
+	for _, c := range diff.Changed {
+		fmt.Printf("  ~ %-20s %s -> %s\n", c.Before.Employee.Name, shiftLabel(c.Before), shiftLabel(c.After))
+	}
+
+	fmt.Printf("\n  Cost: $%.2f -> $%.2f (%+.2f)\n", from.TotalCost, to.TotalCost, diff.CostDelta)
+	fmt.Printf("  Unfilled: %d -> %d\n", from.Unfilled, to.Unfilled)
+}
+
+func cmdPublish(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: shiftopt publish <version>")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid version %q", args[0])
+	}
+	if err := database.PublishRoster(db, id); err != nil {
+		return err
+	}
+	fmt.Printf("Roster version %d published.\n", id)
+	return nil
+}
+
+func shiftLabel(s models.Shift) string {
+	return fmt.Sprintf("%02d:00-%02d:00", s.StartHour, s.EndHour)
+}
+
+func dateLabel(v models.RosterVersion) string {
+	if v.Date.IsZero() {
+		return "-"
+	}
+	return v.Date.Format(database.DateLayout)
+}
+
+// cmdExport writes a saved roster version in the -format chosen
+func cmdExport(db *sql.DB, args []string) error {
+	if len(args) > 2 {
+		return fmt.Errorf("usage: shiftopt export [version|latest] [file]")
+	}
+	exporter, err := exporterFromFlags()
+	if err != nil {
+		return err
+	}
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	arg := "latest"
+	if len(args) > 0 {
+		arg = args[0]
+	}
+	id, err := versionArg(store, arg)
+	if err != nil {
+		return err
+	}
+	filename := "roster." + *format
+	if len(args) == 2 {
+		filename = args[1]
+	}
+
+	roster, _, err := store.LoadRoster(id)
+	if err != nil {
+		return err
+	}
+	if err := scheduler.ExportFile(roster, exporter, filename); err != nil {
+		return err
+	}
+	fmt.Printf("Success: Roster version %d exported to %s\n", id, filename)
+	return nil
+}
+
+// exporterFromFlags builds the exporter described by -format, -layout and -columns
+func exporterFromFlags() (scheduler.Exporter, error) {
+	cols := []string{}
+	for _, c := range strings.Split(*columns, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cols = append(cols, c)
+		}
+	}
+	return scheduler.NewExporter(*format, scheduler.ExportOptions{Layout: *layout, Columns: cols})
+}
+
+// importRoster saves a hand-edited export as a new draft and reports the rules it breaks
+func importRoster(db *sql.DB, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: shiftopt import roster <file.csv|file.json> [yyyy-mm-dd]")
+	}
+	day, err := runDay(db)
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		parsed, err := time.Parse(database.DateLayout, args[1])
+		if err != nil {
+			return fmt.Errorf("invalid date %q (want yyyy-mm-dd)", args[1])
+		}
+		day = parsed
+	}
+
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	roster, err := importer.Roster(store, args[0])
+	if err != nil {
+		return err
+	}
+	edit, err := importer.SaveEdit(store, roster, day, *author)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("[DB] Roster imported from %s as draft version %d.\n", args[0], edit.VersionID)
+	if edit.Base.ID != 0 {
+		fmt.Printf("  Edited from v%d: %d shift change(s), cost $%.2f -> $%.2f (%+.2f)\n",
+			edit.Base.ID, scheduler.ChangeCount(edit.Diff), edit.Base.TotalCost, roster.TotalCost, edit.Diff.CostDelta)
+	} else {
+		fmt.Printf("  Cost: $%.2f\n", roster.TotalCost)
+	}
+	fmt.Printf("  Unfilled: %d\n", roster.Unfilled)
+
+	if len(edit.Violations) == 0 {
+		fmt.Println("  No rule violations.")
+		return nil
+	}
+	fmt.Printf("  %d rule violation(s), %d introduced by the edits:\n", len(edit.Violations), len(edit.Introduced))
+	introduced := make(map[string]bool)
+	for _, v := range edit.Introduced {
+		introduced[v.Reason] = true
+	}
+	for _, v := range edit.Violations {
+		marker := " "
+		if introduced[v.Reason] {
+			marker = "+"
+		}
+		fmt.Printf("  %s %s\n", marker, v.Reason)
+	}
+	return nil
+}
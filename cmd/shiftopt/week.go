@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/weekly"
+)
+
+// cmdWeek prints the weekly grid to an .html or .pdf file
+func cmdWeek(db *sql.DB, args []string) error {
+	if len(args) > 2 {
+		return fmt.Errorf("usage: shiftopt week [yyyy-mm-dd] [out.html|out.pdf]")
+	}
+	day := time.Now()
+	if len(args) > 0 {
+		d, err := time.Parse(database.DateLayout, args[0])
+		if err != nil {
+			return fmt.Errorf("invalid date %q (want yyyy-mm-dd)", args[0])
+		}
+		day = d
+	}
+	filename := "week.html"
+	if len(args) == 2 {
+		filename = args[1]
+	}
+	render := weekly.RenderHTML
+	switch filepath.Ext(filename) {
+	case ".html":
+	case ".pdf":
+		render = weekly.RenderPDF
+	default:
+		return fmt.Errorf("unknown output %q (want .html or .pdf)", filename)
+	}
+
+	store, err := storeFor(db)
+	if err != nil {
+		return err
+	}
+	week, err := weekly.Load(store, day)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := render(file, week); err != nil {
+		return err
+	}
+	fmt.Printf("Success: Week of %s (%d staff, %d rosters) written to %s\n",
+		week.Start.Format(database.DateLayout), len(week.Rows), len(week.Versions), filename)
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/api"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/jobs"
+)
+
+var (
+	addr        = flag.String("addr", ":8080", "Listen address")
+	dbPath      = flag.String("db", "shiftopt.db", "SQLite database file")
+	postgres    = flag.String("postgres", "", "PostgreSQL DSN (overrides -db)")
+	workers     = flag.Int("workers", 2, "Scheduling jobs run in parallel")
+	unsignedSMS = flag.Bool("unsigned-sms", false, "Accept unsigned POST /inbound/sms when TWILIO_AUTH_TOKEN is not set (local testing only)")
+)
+
+func main() {
+	flag.Parse()
+
+	store, err := openStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.DB().Close()
+
+	// Workers stop with the server: jobs they were running go back to the queue
+	ctx, stopWorkers := context.WithCancel(context.Background())
+	pool := jobs.NewPool(store, *workers)
+	if err := pool.Start(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	// Free-text messages are read by the model configured in the environment (see internal/ai),
+	// its answers cached in the database
+	server := api.NewServer(store, pool).WithParser(ai.CachedFromEnv(store))
+	// The SMS webhook only takes messages signed with the gateway's auth token: without one it
+	// refuses them all, unless -unsigned-sms
+	if token := os.Getenv("TWILIO_AUTH_TOKEN"); token != "" {
+		server.WithTwilio(token, os.Getenv("SHIFTOPT_SMS_URL"))
+	} else if *unsignedSMS {
+		server.WithUnsignedSMS()
+		log.Println("TWILIO_AUTH_TOKEN not set and -unsigned-sms: POST /inbound/sms accepts unsigned requests")
+	} else {
+		log.Println("TWILIO_AUTH_TOKEN not set: POST /inbound/sms is refused (-unsigned-sms to test locally)")
+	}
+	httpServer := &http.Server{Addr: *addr, Handler: logRequests(server)}
+
+	// Stop on Ctrl-C / SIGTERM: finish in-flight requests first
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		httpServer.Shutdown(ctx)
+	}()
+
+	log.Printf("shiftoptd listening on %s with %d worker(s) (spec at /openapi.yaml)", *addr, *workers)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	stopWorkers()
+	pool.Wait()
+}
+
+func openStore() (*database.SQLStore, error) {
+	if *postgres != "" {
+		return database.OpenPostgres(*postgres)
+	}
+	db, err := database.InitDB(*dbPath)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite takes one writer at a time: queue requests instead of failing with "database is locked"
+	db.SetMaxOpenConns(1)
+	return database.NewSQLiteStore(db), nil
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		log.Printf("%s %s (%s)", r.Method, r.URL, time.Since(start).Round(time.Millisecond))
+	})
+}
@@ -1,45 +1,62 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/iannsp/shiftopt/internal/database"
 	"github.com/iannsp/shiftopt/internal/models"
 	"github.com/iannsp/shiftopt/internal/scheduler"
 )
 
+var (
+	dbPath = flag.String("db", "shiftopt.db", "SQLite database file")
+	seed     = flag.Bool("seed", false, "Replace the crew and demand with random demo data first")
+	location = flag.String("location", "", "Store to schedule, by name (default: the main store)")
+	date     = flag.String("date", "", "Day to schedule, yyyy-mm-dd (default: today at the store)")
+)
+
 func main() {
-	db, err := database.InitDB("shiftopt.db")
+	flag.Parse()
+
+	db, err := database.InitDB(*dbPath)
 	if err != nil { log.Fatal(err) }
 	defer db.Close()
-	database.SeedData(db)
+	if *seed {
+		database.SeedData(db)
+	}
+	store, err := dayStore(db)
+	if err != nil { log.Fatal(err) }
+	ctx := context.Background()
 
 	fmt.Println("========================================")
 	fmt.Println("   SHIFTOPT DIAGNOSTIC SUMMARY")
 	fmt.Println("========================================")
 
 	// 1. Context: The Workforce
-	printCrewStats(db)
+	printCrewStats(store)
 
 	// 2. Execution: Run All 3 Strategies
 	// A. Baseline
-	rosterHourly, err := scheduler.RunSafeSchedule(db)
+	rosterHourly, err := scheduler.SafeSchedule(ctx, store)
 	if err != nil { log.Fatal(err) }
 
 	// B. Block Logic (Dumb)
-	rosterTetris, err := scheduler.RunTetrisSchedule(db)
+	rosterTetris, err := scheduler.TetrisSchedule(ctx, store)
 	if err != nil { log.Fatal(err) }
 
 	// C. Scored Logic (Smart)
-	rosterSmart, err := scheduler.RunSmartTetris(db)
+	rosterSmart, err := scheduler.SmartTetris(ctx, store)
 	if err != nil { log.Fatal(err) }
 
 	// 3. Visualization: Inspect the "Smart" Roster deeply
-	printVisualDistribution(db, rosterSmart)
+	printVisualDistribution(store, rosterSmart)
 
 	// 4. Comparison: The Numbers
 	fmt.Println("\n[Strategy Showdown: Cost vs. Coverage]")
@@ -61,23 +78,50 @@ func main() {
 	}
 }
 
+// dayStore is the store the strategies run against: one location (the main store unless
+// -location says) on one day, so dated time off and on-call windows apply as in shiftopt
+func dayStore(db *sql.DB) (database.Store, error) {
+	store := database.NewSQLiteStore(db)
+	id := models.DefaultLocation
+	if *location != "" {
+		l, err := store.LocationByName(*location)
+		if err != nil {
+			return nil, err
+		}
+		id = l.ID
+	}
+	var day time.Time
+	if *date != "" {
+		d, err := time.Parse(database.DateLayout, *date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -date %q (want yyyy-mm-dd)", *date)
+		}
+		day = d
+	} else {
+		now, err := database.LocalNow(store, id)
+		if err != nil {
+			return nil, err
+		}
+		y, m, d := now.Date()
+		day = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	return store.AtLocation(id).OnDay(day), nil
+}
+
 // --- VISUALIZATION HELPERS ---
 
-func printVisualDistribution(db *sql.DB, roster *models.Roster) {
+func printVisualDistribution(store database.Store, roster *models.Roster) {
 	fmt.Println("\n[Smart Schedule Composition]")
 	fmt.Println("Legend: [V]eteran, [J]unior, [G]rinder, [_]Missed")
 
 	// Get Demands
 	demands := make(map[int]int)
-	rows, _ := db.Query("SELECT hour_of_day, needed FROM demands")
+	curve, _ := store.Demands()
 	var hours []int
-	for rows.Next() {
-		var h, n int
-		rows.Scan(&h, &n)
-		demands[h] = n
-		hours = append(hours, h)
+	for _, d := range curve {
+		demands[d.HourOfDay] = d.Needed
+		hours = append(hours, d.HourOfDay)
 	}
-	rows.Close()
 	sort.Ints(hours)
 
 	// Map Roster
@@ -135,13 +179,17 @@ func printSummaryRow(label string, r *models.Roster) {
 		label, r.TotalCost, assigned, totalNeeded, status)
 }
 
-func printCrewStats(db *sql.DB) {
+func printCrewStats(store database.Store) {
 	fmt.Println("\n[Workforce Supply]")
-	var total, seniors, juniors int
-	var avgCost float64
-	db.QueryRow("SELECT COUNT(*) FROM employees").Scan(&total)
-	db.QueryRow("SELECT COUNT(*) FROM employees WHERE skill_level >= 2").Scan(&seniors)
-	db.QueryRow("SELECT COUNT(*) FROM employees WHERE skill_level = 1").Scan(&juniors)
-	db.QueryRow("SELECT AVG(hourly_rate) FROM employees").Scan(&avgCost)
+	var seniors, juniors int
+	crew, _ := store.Employees()
+	for _, e := range crew {
+		if e.SkillLevel >= 2 {
+			seniors++
+		} else if e.SkillLevel == 1 {
+			juniors++
+		}
+	}
+	total := len(crew)
 	fmt.Printf("  Headcount: %d (%d Seniors, %d Juniors)\n", total, seniors, juniors)
 }
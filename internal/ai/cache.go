@@ -0,0 +1,88 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync/atomic"
+)
+
+// ParseCache keeps parse results by key (database.Store implements it)
+type ParseCache interface {
+	CachedParse(key string) (result string, ok bool, err error)
+	CacheParse(key, result string) error
+}
+
+// Cached answers a message from cache when parser already read the same prompt: the
+// same text, crew, sender and day. name tells parsers apart, e.g. the provider and model.
+// Only answers are cached, never errors; a failing cache is logged and skipped.
+func Cached(parser ConstraintParser, name string, cache ParseCache) ConstraintParser {
+	return cachedParser{parser, name, cache}
+}
+
+type cachedParser struct {
+	parser ConstraintParser
+	name   string
+	cache  ParseCache
+}
+
+func (p cachedParser) Parse(ctx context.Context, msg Message) ([]Constraint, error) {
+	sum := sha256.Sum256([]byte(p.name + "\n" + prompt(msg)))
+	key := hex.EncodeToString(sum[:])
+
+	// 1. Read before asking
+	raw, ok, err := p.cache.CachedParse(key)
+	if err != nil {
+		log.Printf("[AI] Reading the parse cache: %v\n", err)
+	}
+	var found []Constraint
+	if ok && json.Unmarshal([]byte(raw), &found) == nil {
+		counters.cacheHits.Add(1)
+		return found, nil
+	}
+
+	// 2. Remember the answer
+	if found, err = p.parser.Parse(ctx, msg); err != nil {
+		return nil, err
+	}
+	if out, err := json.Marshal(found); err == nil {
+		if err := p.cache.CacheParse(key, string(out)); err != nil {
+			log.Printf("[AI] Writing the parse cache: %v\n", err)
+		}
+	}
+	return found, nil
+}
+
+// Stats counts what the model parsers did since the process started
+type Stats struct {
+	Parses         int64 // Messages read by a model backed by the rules
+	Fallbacks      int64 // Of those, answered by the rules because the model failed
+	CacheHits      int64 // Messages answered from the parse cache
+	ModelCalls     int64 // Requests sent to a model, retries included
+	InvalidAnswers int64 // Model answers that broke the schema
+}
+
+// FallbackRate is the share of messages the rules answered for a failed model
+func (s Stats) FallbackRate() float64 {
+	if s.Parses == 0 {
+		return 0
+	}
+	return float64(s.Fallbacks) / float64(s.Parses)
+}
+
+var counters struct {
+	parses, fallbacks, cacheHits, modelCalls, invalidAnswers atomic.Int64
+}
+
+// ReadStats returns the counters so far
+func ReadStats() Stats {
+	return Stats{
+		Parses:         counters.parses.Load(),
+		Fallbacks:      counters.fallbacks.Load(),
+		CacheHits:      counters.cacheHits.Load(),
+		ModelCalls:     counters.modelCalls.Load(),
+		InvalidAnswers: counters.invalidAnswers.Load(),
+	}
+}
@@ -0,0 +1,284 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// Fields a parser can leave unclear
+const (
+	FieldEmployee = "employee" // A name several people share
+	FieldDate     = "date"     // A vague day: "soon", "sometime"
+	FieldTime     = "time"     // No hours, or vague ones: "later", "late"
+	FieldConfirm  = "confirm"  // Everything is there, but the parser is unsure of it
+)
+
+// ConfirmBelow is the confidence under which Record asks the sender to confirm
+const ConfirmBelow = 0.5
+
+// Ambiguity is one field of a constraint that needs the sender's answer
+type Ambiguity struct {
+	Field   string
+	Text    string   // The unclear words as written, e.g. "later" (may be empty)
+	Options []Member // FieldEmployee: everyone it could be
+}
+
+// ambiguity checks what a model flagged: a known field, and for employees, at least
+// two candidates on the crew
+func ambiguity(field, text string, options []int, crew Crew) (Ambiguity, bool) {
+	a := Ambiguity{Field: strings.ToLower(strings.TrimSpace(field)), Text: text}
+	switch a.Field {
+	case FieldDate, FieldTime, FieldConfirm:
+		return a, true
+	case FieldEmployee:
+		for _, id := range options {
+			if m, ok := crew.ByID(id); ok {
+				a.Options = append(a.Options, m)
+			}
+		}
+		return a, len(a.Options) > 1
+	}
+	return a, false
+}
+
+// Answer applies the sender's reply to the question of a pending message. The reply
+// settles that field on every constraint that shares it; the message is then saved when
+// nothing else is unclear, or held with the next question. A reply that answers nothing
+// is asked again.
+func Answer(store database.Store, id int, reply string, today time.Time) (Outcome, error) {
+	// 1. The message and its draft
+	p, err := store.Pending(id)
+	if err != nil {
+		return Outcome{}, err
+	}
+	var found []Constraint
+	if err := json.Unmarshal([]byte(p.Draft), &found); err != nil {
+		return Outcome{}, fmt.Errorf("pending message %d: %v", id, err)
+	}
+	c, ok := firstUnclear(found)
+	if !ok {
+		return save(store, id, p.Text, found)
+	}
+	asked := c.Unclear[0]
+
+	// 2. Settle it wherever it was asked
+	var settled []Constraint
+	for _, c := range found {
+		if !has(c.Unclear, asked) {
+			settled = append(settled, c)
+			continue
+		}
+		next, ok := settle(c, asked, reply, today)
+		if !ok {
+			p.Question = "Sorry, I did not get that. " + question(c, asked)
+			return Outcome{Constraints: found, PendingID: id, Question: p.Question}, store.UpdatePending(p)
+		}
+		settled = append(settled, next...)
+	}
+
+	// 3. Ask the next question, or save
+	if c, ok := firstUnclear(settled); ok {
+		return hold(store, p, settled, question(c, c.Unclear[0]))
+	}
+	return save(store, id, p.Text, settled)
+}
+
+// save checks every settled constraint against the crew, as Record does those it does
+// not ask about, then saves what the pending message asked for
+func save(store database.Store, id int, text string, found []Constraint) (Outcome, error) {
+	crew, err := LoadCrew(store)
+	if err != nil {
+		return Outcome{}, err
+	}
+	for i := range found {
+		if err := resolve(&found[i], crew); err != nil {
+			return Outcome{}, err
+		}
+	}
+	return Outcome{Constraints: found}, store.ResolvePending(id, requests(found, text))
+}
+
+// settle answers one ambiguity of c: none, one or (for a range of days) several constraints
+func settle(c Constraint, a Ambiguity, reply string, today time.Time) ([]Constraint, bool) {
+	rest := make([]Ambiguity, 0, len(c.Unclear))
+	for _, other := range c.Unclear {
+		if !same(other, a) {
+			rest = append(rest, other)
+		}
+	}
+	c.Unclear = rest
+	c.Confidence = max(c.Confidence, 0.9) // The sender said so
+
+	switch a.Field {
+	case FieldEmployee:
+		m, ok := pick(reply, a.Options)
+		if !ok {
+			return nil, false
+		}
+		if c.EmployeeID != 0 && c.SwapWith.ID == 0 && strings.EqualFold(c.SwapWith.Name, a.Text) {
+			c.SwapWith = m // The partner was asked about, not who is asking
+		} else {
+			c.EmployeeID, c.EmployeeName = m.ID, m.Name
+		}
+		return []Constraint{c}, true
+	case FieldTime:
+		start, end, ok := HoursIn(reply)
+		if !ok {
+			return nil, false
+		}
+		c.StartHour, c.EndHour = start, end
+		return []Constraint{c}, true
+	case FieldDate:
+		text := words(reply)
+		for i := range text {
+			if dates, n := dateAt(text, i, reference(today)); n > 0 {
+				out := make([]Constraint, len(dates))
+				for j, day := range dates {
+					out[j] = c
+					out[j].Date = day
+				}
+				return out, true
+			}
+		}
+		return nil, false
+	case FieldConfirm:
+		switch yesNo(reply) {
+		case "yes":
+			c.Confidence = 1
+			return []Constraint{c}, true
+		case "no":
+			return nil, true // Dropped
+		}
+	}
+	return nil, false
+}
+
+// question is what to ask the sender about a
+func question(c Constraint, a Ambiguity) string {
+	who := doing(c)
+	switch a.Field {
+	case FieldEmployee:
+		names := make([]string, len(a.Options))
+		for i, m := range a.Options {
+			names[i] = m.Name
+		}
+		return fmt.Sprintf("Which %s: %s?", a.Text, orList(names))
+	case FieldDate:
+		if a.Text != "" {
+			return fmt.Sprintf("Which day is %q?", a.Text)
+		}
+		return fmt.Sprintf("Which day is %s?", who)
+	case FieldTime:
+		if a.Text != "" {
+			return fmt.Sprintf("What time is %q? (e.g. 2pm to 5pm, after 3pm, morning, all day)", a.Text)
+		}
+		return fmt.Sprintf("What time is %s? (e.g. 2pm to 5pm, after 3pm, morning, all day)", who)
+	}
+	return fmt.Sprintf("Is this right: %s %s? (yes/no)", who, when(c.Unavailability))
+}
+
+// hold saves the draft and its question, as a new pending message when p has no ID yet
+func hold(store database.Store, p models.PendingMessage, found []Constraint, q string) (Outcome, error) {
+	draft, err := json.Marshal(found)
+	if err != nil {
+		return Outcome{}, err
+	}
+	p.Question, p.Draft = q, string(draft)
+	if p.ID == 0 {
+		p.ID, err = store.AddPending(p)
+	} else {
+		err = store.UpdatePending(p)
+	}
+	return Outcome{Constraints: found, PendingID: p.ID, Question: q}, err
+}
+
+func firstUnclear(found []Constraint) (Constraint, bool) {
+	for _, c := range found {
+		if len(c.Unclear) > 0 {
+			return c, true
+		}
+	}
+	return Constraint{}, false
+}
+
+func unclear(c Constraint, field string) bool {
+	for _, a := range c.Unclear {
+		if a.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func has(list []Ambiguity, a Ambiguity) bool {
+	for _, other := range list {
+		if same(other, a) {
+			return true
+		}
+	}
+	return false
+}
+
+func same(a, b Ambiguity) bool {
+	return a.Field == b.Field && strings.EqualFold(a.Text, b.Text)
+}
+
+// pick finds the option a reply names: by name or alias, or by any other word of a
+// single option's name ("Jones", "barista")
+func pick(reply string, options []Member) (Member, bool) {
+	text := words(reply)
+	for i := range text {
+		if m, n := mentionAt(text, i, options); n > 0 {
+			return m, true
+		}
+	}
+	said := make(map[string]bool)
+	for _, w := range text {
+		said[w] = true
+	}
+	var found []Member
+	for _, m := range options {
+		for i, w := range words(m.Name) {
+			if i > 0 && said[w] { // The first name is the one they share
+				found = append(found, m)
+				break
+			}
+		}
+	}
+	if len(found) != 1 {
+		return Member{}, false
+	}
+	return found[0], true
+}
+
+func yesNo(reply string) string {
+	switch firstWord(strings.ToLower(strings.Trim(strings.TrimSpace(reply), ".!"))) {
+	case "yes", "y", "yeah", "yep", "ok", "okay", "correct", "right", "sure":
+		return "yes"
+	case "no", "n", "nope", "wrong", "cancel":
+		return "no"
+	}
+	return ""
+}
+
+// when is a constraint's day and hours, e.g. "Fri 6 Mar 08:00-20:00" or "every day 09:00-10:00"
+func when(u models.Unavailability) string {
+	day := "every day"
+	if !u.Date.IsZero() {
+		day = u.Date.Format("Mon 2 Jan")
+	}
+	return fmt.Sprintf("%s %02d:00-%02d:00", day, u.StartHour, u.EndHour)
+}
+
+// orList joins names as "A, B or C"
+func orList(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// The opening hours open-ended times are read within: "after 3pm" is 15-20
+const (
+	dayStart = 8
+	dayEnd   = 20
+)
+
+// Words that start a clock phrase, and what a single time means after them
+var clockLeads = map[string]string{
+	"from": "after", "after": "after", "since": "after",
+	"before": "before", "until": "before", "till": "before", "til": "before", "by": "before",
+	"at": "at", "around": "at",
+	"between": "range",
+}
+
+// clockToken is one way of writing a time: "3", "3pm", "14:30", "9:15am"
+var clockToken = regexp.MustCompile(`^(\d{1,2})(?::(\d\d))?(am|pm|a|p)?$`)
+
+// clockTime is a time as written, before am/pm is settled
+type clockTime struct {
+	hour, minute int
+	suffix       string // "am", "pm" or "" (unsaid)
+	explicit     bool   // Has a suffix, minutes or is a word like "noon": clearly a time
+	padded       bool   // "07:00": 24-hour time, nothing to guess
+}
+
+// clockAt recognises a clock phrase at text[i] and returns its hours, [start, end):
+//
+//	2pm to 5pm, 2-5pm, from 9 to 1, between 2 and 4  a range
+//	after 3pm, from 14:00, since noon                 until closing (20:00)
+//	before 11am, until noon, by 10                    from opening (08:00)
+//	at 3pm, 3pm, around 10:30                         that hour
+//
+// A bare number is only a time after a lead word, in a dashed range ("9-5") or next to a
+// clear time, so "2 shifts" is not 2 o'clock. Bare hours 1-7 are afternoons: the store is shut at 3am.
+// A time before opening ("before 8am") is an empty window, start == end: no hours at all.
+func clockAt(text []string, i int) (start, end, n int) {
+	lead, j := clockLeads[text[i]], i
+	if lead != "" {
+		j++
+	}
+	from, k := timeAt(text, j)
+	if k == 0 {
+		return 0, 0, 0
+	}
+	j += k
+
+	// 1. A range: both ends settle am/pm together
+	if j < len(text) && rangeWords[text[j]] && (text[j] != "and" || lead == "range") {
+		if to, k := timeAt(text, j+1); k > 0 && (lead != "" || from.explicit || to.explicit || text[j] == "-") {
+			start, end = hours(from, to)
+			if start < end {
+				return start, end, j + 1 + k - i
+			}
+			return 0, 0, 0
+		}
+	}
+
+	// 2. A single time
+	if lead == "" && !from.explicit || lead == "range" {
+		return 0, 0, 0
+	}
+	h := from.clock(from.suffix)
+	switch lead {
+	case "after":
+		start, end = h, max(dayEnd, h+1)
+	case "before":
+		if h+ceilHour(from.minute) <= dayStart {
+			return dayStart, dayStart, j - i // Before opening: no hours at all
+		}
+		start, end = dayStart, h+ceilHour(from.minute)
+	default:
+		start, end = h, h+1
+	}
+	if start < 0 || start >= end || end > 24 {
+		return 0, 0, 0
+	}
+	return start, end, j - i
+}
+
+// timeAt reads one time at text[i] and how many words it takes
+func timeAt(text []string, i int) (clockTime, int) {
+	if i >= len(text) {
+		return clockTime{}, 0
+	}
+	switch text[i] {
+	case "noon", "midday":
+		return clockTime{hour: 12, suffix: "pm", explicit: true}, 1
+	case "midnight":
+		return clockTime{hour: 24, explicit: true}, 1
+	}
+	m := clockToken.FindStringSubmatch(text[i])
+	if m == nil {
+		return clockTime{}, 0
+	}
+	t := clockTime{suffix: m[3]}
+	t.hour, _ = strconv.Atoi(m[1])
+	t.padded = len(m[1]) == 2 && m[1][0] == '0'
+	t.minute, _ = strconv.Atoi(m[2])
+	n := 1
+	if t.suffix == "" && i+1 < len(text) {
+		switch text[i+1] {
+		case "am", "pm":
+			t.suffix, n = text[i+1], 2
+		case "o'clock", "oclock":
+			n = 2
+			t.explicit = true
+		}
+	}
+	if t.suffix == "a" || t.suffix == "p" {
+		t.suffix += "m"
+	}
+	t.explicit = t.explicit || t.suffix != "" || m[2] != ""
+	if t.hour > 24 || t.minute > 59 || t.suffix != "" && (t.hour < 1 || t.hour > 12) {
+		return clockTime{}, 0
+	}
+	return t, n
+}
+
+// clock is the hour in 24-hour time with the given am/pm, guessing when there is none
+func (t clockTime) clock(suffix string) int {
+	switch {
+	case suffix == "am" && t.hour == 12:
+		return 0
+	case suffix == "pm" && t.hour < 12:
+		return t.hour + 12
+	case suffix == "" && !t.padded && t.hour >= 1 && t.hour <= 7:
+		return t.hour + 12 // "after 3" is 15:00
+	}
+	return t.hour
+}
+
+// hours turns a range into [start, end): "2 to 5pm" is 14-17, "9 to 1" is 9-13 and the
+// minutes round outwards ("9:30 to 11:15" is 9-12)
+func hours(from, to clockTime) (start, end int) {
+	end = to.clock(to.suffix)
+	fromSuffix := from.suffix
+	if fromSuffix == "" && to.suffix != "" && from.clock(to.suffix) <= end {
+		fromSuffix = to.suffix // "2 to 5pm": both afternoon
+	}
+	start = from.clock(fromSuffix)
+	if end <= start && to.suffix == "" && end+12 <= 24 {
+		end += 12 // "9 to 1" is 9-13
+	}
+	return start, end + ceilHour(to.minute)
+}
+
+func ceilHour(minute int) int {
+	if minute > 0 {
+		return 1
+	}
+	return 0
+}
+
+// HoursIn reads the hours of a reply: a day part ("all day", "morning") or a clock phrase
+func HoursIn(reply string) (start, end int, ok bool) {
+	text := words(reply)
+	for i := range text {
+		if start, end, n := periodAt(text, i); n > 0 {
+			return start, end, true
+		}
+		if start, end, n := clockAt(text, i); n > 0 {
+			return start, end, start < end
+		}
+	}
+	return 0, 0, false
+}
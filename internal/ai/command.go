@@ -0,0 +1,472 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+// What a manager can ask for in plain words
+const (
+	CommandWho    = "who"     // "Who is working Saturday close?": reads the roster, changes nothing
+	CommandGive   = "give"    // "Give Grace four more hours this week": pins longer shifts
+	CommandWhatIf = "what_if" // "What if Bob is off Friday?": unavailability
+)
+
+// ErrUnknownCommand is returned for text that is none of the commands, or misses what one needs
+var ErrUnknownCommand = errors.New("unknown command")
+
+// maxDaily is the schedulers' limit on one person's hours in a day
+const maxDaily = 8
+
+// Command is a manager's request, read offline: commands change the roster, so they are
+// never left to a model's guess
+type Command struct {
+	Kind      string
+	Member    Member      // Who it is about (zero when nobody is named)
+	Days      []time.Time // The days it covers (none = today)
+	StartHour int         // The window asked about, [start, end); 0-0 is the whole day
+	EndHour   int
+	Part      string // "open" or "close": the first or last hours of the day, read from the demand curve
+	Hours     int    // CommandGive: how many more hours
+	Text      string // CommandWhatIf: the words after "what if", read like a staff message
+}
+
+// Roster words a manager uses for the ends of the day
+var rosterParts = map[string]string{"open": "open", "opening": "open", "close": "close", "closing": "close"}
+
+// partHours is how long "open" and "close" are
+const partHours = 4
+
+var countWords = map[string]int{"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12}
+
+// ParseCommand reads a manager command against the crew
+func ParseCommand(input string, crew Crew, today time.Time) (Command, error) {
+	text := words(input)
+	today = reference(today)
+
+	// 1. What is asked for
+	var c Command
+kind:
+	for i, w := range text {
+		switch {
+		case w == "what" && i+1 < len(text) && text[i+1] == "if":
+			c.Kind, c.Text = CommandWhatIf, strings.Join(text[i+2:], " ")
+		case w == "suppose":
+			c.Kind, c.Text = CommandWhatIf, strings.Join(text[i+1:], " ")
+		case w == "who" || w == "who's" || w == "whos":
+			c.Kind = CommandWho
+		case w == "give" || w == "add":
+			c.Kind = CommandGive
+		default:
+			continue
+		}
+		break kind
+	}
+	if c.Kind == "" {
+		return Command{}, fmt.Errorf("%w (try: who is working Saturday close? / give Grace four more hours this week / what if Bob is off Friday?)", ErrUnknownCommand)
+	}
+
+	// 2. Who, when and how much
+	for i := 0; i < len(text); {
+		if m, n := mentionAt(text, i, crew); n > 0 {
+			if c.Member.ID == 0 {
+				c.Member = m
+			}
+			i += n
+			continue
+		}
+		if same := crew.sharing(text[i]); len(same) > 1 && c.Kind != CommandWhatIf {
+			names := make([]string, len(same))
+			for k, m := range same {
+				names[k] = m.Name
+			}
+			return Command{}, fmt.Errorf("%w: which %s: %s?", ErrUnknownEmployee, text[i], orList(names))
+		}
+		if days, n := dateAt(text, i, today); n > 0 {
+			c.Days = append(c.Days, days...)
+			i += n
+			continue
+		}
+		if hours, n := hoursAt(text, i); n > 0 {
+			c.Hours += hours
+			i += n
+			continue
+		}
+		if part, ok := rosterParts[text[i]]; ok {
+			c.Part = part
+			i++
+			continue
+		}
+		if start, end, n := periodAt(text, i); n > 0 {
+			c.StartHour, c.EndHour = start, end
+			i += n
+			continue
+		}
+		if start, end, n := clockAt(text, i); n > 0 {
+			if start < end {
+				c.StartHour, c.EndHour = start, end
+			}
+			i += n
+			continue
+		}
+		i++
+	}
+
+	// 3. What each command cannot do without
+	switch {
+	case c.Kind == CommandGive && c.Member.ID == 0:
+		return Command{}, fmt.Errorf("%w: who should get the hours?", ErrUnknownEmployee)
+	case c.Kind == CommandGive && c.Hours == 0:
+		return Command{}, fmt.Errorf("%w: how many hours? (e.g. give %s four more hours)", ErrUnknownCommand, c.Member.Name)
+	case c.Kind == CommandWhatIf && c.Text == "":
+		return Command{}, fmt.Errorf("%w: what if what? (e.g. what if Bob is off Friday?)", ErrUnknownCommand)
+	}
+	return c, nil
+}
+
+// hoursAt reads an amount of hours at text[i]: "4 hours", "four more hours", "an extra hour"
+func hoursAt(text []string, i int) (hours, n int) {
+	hours, ok := countWords[text[i]]
+	if !ok {
+		var err error
+		if hours, err = strconv.Atoi(text[i]); err != nil || hours <= 0 {
+			return 0, 0
+		}
+	}
+	j := i + 1
+	if j < len(text) && (text[j] == "more" || text[j] == "extra") {
+		j++
+	}
+	if j < len(text) && (text[j] == "hours" || text[j] == "hour" || text[j] == "hrs" || text[j] == "h") {
+		return hours, j + 1 - i
+	}
+	return 0, 0
+}
+
+// Plan is the dry run of a command: what it found or would change, and the roster each
+// changed day would get. Nothing is saved until Apply.
+type Plan struct {
+	Command Command
+	Working []DayShifts      // CommandWho: the shifts in the window, per day
+	Change  scheduler.Change // What Apply saves
+	Trials  []Trial          // The SmartTetris re-run of each day the change touches
+	Notes   []string         // What could not be done as asked
+}
+
+// DayShifts are the shifts of one day's roster
+type DayShifts struct {
+	Date   time.Time
+	Shifts []models.Shift // Nil when the day has no roster yet
+}
+
+// Trial is one day re-scheduled with the change
+type Trial struct {
+	Date   time.Time
+	Before *models.Roster // The roster in force (nil = none yet)
+	After  *models.Roster
+	Diff   models.RosterDiff
+}
+
+// Preview reads a manager command and works out what it would do, without saving anything.
+// Scope the store to one location for a chain: each day is re-run for the Store's crew.
+func Preview(ctx context.Context, store database.Store, text string, today time.Time) (Plan, error) {
+	crew, err := LoadCrew(store)
+	if err != nil {
+		return Plan{}, err
+	}
+	today = reference(today)
+	c, err := ParseCommand(text, crew, today)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan := Plan{Command: c}
+	switch c.Kind {
+	case CommandWho:
+		err = plan.who(store, today)
+	case CommandGive:
+		err = plan.give(ctx, store, today)
+	case CommandWhatIf:
+		err = plan.whatIf(ctx, store, crew, today)
+	}
+	return plan, err
+}
+
+// Apply saves what a plan changes, and each trial roster as a draft version to review and
+// publish, all in one transaction. It returns the new version IDs.
+func Apply(store database.Store, plan Plan, author string) ([]int, error) {
+	if plan.Change.Empty() {
+		return nil, fmt.Errorf("nothing to apply: %q changes nothing", plan.Command.Kind)
+	}
+	var drafts []*models.Roster
+	for _, t := range plan.Trials {
+		drafts = append(drafts, t.After)
+	}
+	return store.SaveChange(plan.Change.Unavailability, plan.Change.Locks, drafts, author)
+}
+
+// who lists the shifts that overlap the window, of the member when one is named
+func (p *Plan) who(store database.Store, today time.Time) error {
+	c := p.Command
+	start, end := c.StartHour, c.EndHour
+	if start == end {
+		start, end = 0, 24
+	}
+	if c.Part != "" {
+		opens, closes, err := openingHours(store)
+		if err != nil {
+			return err
+		}
+		start, end = opens, opens+partHours
+		if c.Part == "close" {
+			start, end = closes-partHours, closes
+		}
+	}
+	for _, day := range daysOr(c.Days, today) {
+		rosters, _, err := database.EffectiveRosters(store, day, day.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+		if len(rosters) == 0 {
+			p.Working = append(p.Working, DayShifts{Date: day})
+			p.Notes = append(p.Notes, fmt.Sprintf("No roster for %s yet.", day.Format("Mon 2 Jan")))
+			continue
+		}
+		found := DayShifts{Date: day, Shifts: []models.Shift{}}
+		for _, r := range rosters {
+			for _, s := range scheduler.Shifts(r) {
+				if s.StartHour < end && s.EndHour > start && (c.Member.ID == 0 || s.Employee.ID == c.Member.ID) {
+					found.Shifts = append(found.Shifts, s)
+				}
+			}
+		}
+		p.Working = append(p.Working, found)
+	}
+	return nil
+}
+
+// give lengthens the member's shift day by day, from the first rostered day asked, until the hours
+// are placed: later first, then earlier, within opening hours, their availability and
+// the daily limit. Each longer shift is a pin for that day, kept when the trial run
+// really gives them more hours.
+func (p *Plan) give(ctx context.Context, store database.Store, today time.Time) error {
+	c := p.Command
+	opens, closes, err := openingHours(store)
+	if err != nil {
+		return err
+	}
+
+	need := c.Hours
+	for _, day := range daysOr(c.Days, today) {
+		if need == 0 {
+			break
+		}
+		if day.Before(today) {
+			continue
+		}
+
+		// 1. Their shifts on the day's roster, or the first hour they could start
+		blocked, err := blockedHours(store.OnDay(day), c.Member.ID)
+		if err != nil {
+			return err
+		}
+		before, err := rosterOn(store, day)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			continue // "More" than nothing: schedule the day first
+		}
+		worked := hoursOf(before, c.Member.ID)
+		if worked >= maxDaily {
+			continue
+		}
+		var shifts []models.Shift
+		for _, s := range scheduler.Shifts(before) {
+			if s.Employee.ID == c.Member.ID {
+				shifts = append(shifts, s)
+				markHours(blocked, s.StartHour, s.EndHour) // Growing stops at their other shifts
+			}
+		}
+		for h := opens; len(shifts) == 0 && h < closes; h++ {
+			if !blocked[h] {
+				shifts = []models.Shift{{StartHour: h, EndHour: h}}
+			}
+		}
+
+		// 2. Grow their shifts, later first; every shift is pinned so the re-run keeps it
+		var pins []models.Lock
+		grown := 0
+		for _, sh := range shifts {
+			from, to := sh.StartHour, sh.EndHour
+		grow:
+			for grown < need && worked+grown < maxDaily {
+				switch {
+				case to < closes && !blocked[to]:
+					to++
+				case from > opens && !blocked[from-1]:
+					from--
+				default:
+					break grow
+				}
+				grown++
+			}
+			if from < to {
+				pins = append(pins, models.Lock{EmployeeID: c.Member.ID, EmployeeName: c.Member.Name,
+					StartHour: from, EndHour: to, Kind: models.LockPinned, Date: day})
+			}
+		}
+		if grown == 0 {
+			continue
+		}
+
+		// 3. Re-run the day; pins that gain nothing are dropped
+		p.Change.Locks = append(p.Change.Locks, pins...)
+		if err := p.try(ctx, store, day, before); err != nil {
+			return err
+		}
+		gained := hoursOf(p.Trials[len(p.Trials)-1].After, c.Member.ID) - worked
+		if gained <= 0 {
+			p.Change.Locks = p.Change.Locks[:len(p.Change.Locks)-len(pins)]
+			p.Trials = p.Trials[:len(p.Trials)-1]
+			continue
+		}
+		need = max(0, need-gained)
+	}
+	if need > 0 {
+		p.Notes = append(p.Notes, fmt.Sprintf("Only %d of %d hours fit %s's rostered days, availability, opening hours and the %d-hour day.",
+			c.Hours-need, c.Hours, c.Member.Name, maxDaily))
+	}
+	return nil
+}
+
+// whatIf reads the rest of the command as a staff message and re-runs each day it touches
+func (p *Plan) whatIf(ctx context.Context, store database.Store, crew Crew, today time.Time) error {
+	found, err := RuleParser{}.Parse(ctx, Message{Text: p.Command.Text, Crew: crew, Today: today})
+	if err != nil {
+		return err
+	}
+	opens, closes, err := openingHours(store)
+	if err != nil {
+		return err
+	}
+	var days []time.Time
+	for _, c := range found {
+		if unclear(c, FieldEmployee) {
+			return fmt.Errorf("%w: %s", ErrUnknownEmployee, question(c, c.Unclear[0]))
+		}
+		if intentOf(c) != IntentUnavailable {
+			return fmt.Errorf("%w: only \"what if someone is off\" can be tried", ErrUnknownCommand)
+		}
+		u := c.Unavailability
+		if u.StartHour == dayStart && u.EndHour == dayEnd {
+			u.StartHour, u.EndHour = min(opens, dayStart), max(closes, dayEnd) // "Off Friday" is the whole opening day
+		}
+		p.Change.Unavailability = append(p.Change.Unavailability, u)
+		day := c.Date
+		if day.IsZero() {
+			day = today // Every day: try today's roster
+		}
+		if !hasDay(days, day) {
+			days = append(days, day)
+		}
+	}
+	for _, day := range days {
+		before, err := rosterOn(store, day)
+		if err != nil {
+			return err
+		}
+		if err := p.try(ctx, store, day, before); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// try re-runs one day with the change so far
+func (p *Plan) try(ctx context.Context, store database.Store, day time.Time, before *models.Roster) error {
+	after, diff, err := scheduler.Trial(ctx, store, day, p.Change, before)
+	if err != nil {
+		return err
+	}
+	p.Trials = append(p.Trials, Trial{Date: day, Before: before, After: after, Diff: diff})
+	return nil
+}
+
+// rosterOn is the roster in force that day, nil when there is none
+func rosterOn(store database.Store, day time.Time) (*models.Roster, error) {
+	rosters, _, err := database.EffectiveRosters(store, day, day.AddDate(0, 0, 1))
+	if err != nil || len(rosters) == 0 {
+		return nil, err
+	}
+	return rosters[0], nil
+}
+
+// openingHours are the first and after-last hours of the demand curve (the parser's day
+// when there is none)
+func openingHours(store database.Store) (opens, closes int, err error) {
+	demands, err := store.Demands()
+	if err != nil || len(demands) == 0 {
+		return dayStart, dayEnd, err
+	}
+	return demands[0].HourOfDay, demands[len(demands)-1].HourOfDay + 1, nil
+}
+
+// hoursOf counts one employee's hours on a roster (nil = none)
+func hoursOf(roster *models.Roster, employeeID int) int {
+	if roster == nil {
+		return 0
+	}
+	n := 0
+	for _, a := range roster.Assignments {
+		if a.Employee.ID == employeeID {
+			n++
+		}
+	}
+	return n
+}
+
+// blockedHours are the hours one employee is unavailable on the store's day
+func blockedHours(store database.Store, employeeID int) (map[int]bool, error) {
+	blocks, err := store.Unavailability()
+	if err != nil {
+		return nil, err
+	}
+	blocked := make(map[int]bool)
+	for _, u := range blocks {
+		if u.EmployeeID == employeeID {
+			markHours(blocked, u.StartHour, u.EndHour)
+		}
+	}
+	return blocked, nil
+}
+
+func markHours(hours map[int]bool, start, end int) {
+	for h := start; h < end; h++ {
+		hours[h] = true
+	}
+}
+
+func daysOr(days []time.Time, today time.Time) []time.Time {
+	if len(days) == 0 {
+		return []time.Time{today}
+	}
+	return days
+}
+
+func hasDay(days []time.Time, day time.Time) bool {
+	for _, d := range days {
+		if d.Equal(day) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// Member is one person the parser may name
+type Member struct {
+	ID      int
+	Name    string
+	Aliases []string // Other ways people write the name, e.g. "Alice" for "Alice (Vet)"
+}
+
+// Crew is the list of people a message can be about
+type Crew []Member
+
+// ErrUnknownEmployee is returned when a parsed constraint names nobody in the crew
+var ErrUnknownEmployee = errors.New("unknown employee")
+
+// CrewFrom builds the crew from employees. Each gets as aliases its name without the
+// role in brackets and its first name, unless another employee shares that alias.
+func CrewFrom(employees []models.Employee) Crew {
+	// 1. Candidate aliases and how many people use each
+	candidates := make([][]string, len(employees))
+	owners := make(map[string]int)
+	for i, e := range employees {
+		seen := map[string]bool{strings.ToLower(e.Name): true}
+		bare := strings.TrimSpace(e.Name)
+		if open := strings.Index(bare, "("); open > 0 {
+			bare = strings.TrimSpace(bare[:open])
+		}
+		for _, alias := range []string{bare, firstWord(bare)} {
+			if alias == "" || seen[strings.ToLower(alias)] {
+				continue
+			}
+			seen[strings.ToLower(alias)] = true
+			candidates[i] = append(candidates[i], alias)
+		}
+		for key := range seen {
+			owners[key]++
+		}
+	}
+
+	// 2. Keep only the aliases that point at one person
+	crew := make(Crew, len(employees))
+	for i, e := range employees {
+		crew[i] = Member{ID: e.ID, Name: e.Name}
+		for _, alias := range candidates[i] {
+			if owners[strings.ToLower(alias)] == 1 {
+				crew[i].Aliases = append(crew[i].Aliases, alias)
+			}
+		}
+	}
+	return crew
+}
+
+// LoadCrew is the Store's active staff as a Crew
+func LoadCrew(store database.Store) (Crew, error) {
+	employees, err := store.Employees()
+	if err != nil {
+		return nil, err
+	}
+	return CrewFrom(employees), nil
+}
+
+// DemoCrew is the staff SeedData hires, with the IDs a fresh database gives them.
+// Only for MockParse and tests: everything else reads the crew from the Store (LoadCrew).
+var DemoCrew = CrewFrom([]models.Employee{
+	{ID: 1, Name: "Alice (Vet)"}, {ID: 2, Name: "Bob (Vet)"}, {ID: 3, Name: "Carol (Vet)"},
+	{ID: 4, Name: "Dave (Jun)"}, {ID: 5, Name: "Eve (Jun)"}, {ID: 6, Name: "Frank (Jun)"},
+	{ID: 7, Name: "Grace (Grinder)"}, {ID: 8, Name: "Hank (Grinder)"},
+})
+
+// ByID finds a member
+func (c Crew) ByID(id int) (Member, bool) {
+	for _, m := range c {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return Member{}, false
+}
+
+// ByName finds a member by name or alias, ignoring case
+func (c Crew) ByName(name string) (Member, bool) {
+	name = strings.TrimSpace(name)
+	for _, m := range c {
+		if strings.EqualFold(m.Name, name) {
+			return m, true
+		}
+		for _, alias := range m.Aliases {
+			if strings.EqualFold(alias, name) {
+				return m, true
+			}
+		}
+	}
+	return Member{}, false
+}
+
+// Resolve checks that a parsed constraint is about a member of the crew and fills in
+// both the ID and the canonical name. The ID wins when the model returned one.
+func (c Crew) Resolve(u models.Unavailability) (models.Unavailability, error) {
+	var m Member
+	var ok bool
+	if u.EmployeeID != 0 {
+		if m, ok = c.ByID(u.EmployeeID); !ok {
+			return u, fmt.Errorf("%w: no active employee has ID %d", ErrUnknownEmployee, u.EmployeeID)
+		}
+	} else if m, ok = c.ByName(u.EmployeeName); !ok {
+		return u, fmt.Errorf("%w: %q", ErrUnknownEmployee, u.EmployeeName)
+	}
+	u.EmployeeID, u.EmployeeName = m.ID, m.Name
+	return u, nil
+}
+
+// sharing is every member whose first name is word (lower case)
+func (c Crew) sharing(word string) []Member {
+	var same []Member
+	for _, m := range c {
+		if name := words(m.Name); len(name) > 0 && name[0] == word {
+			same = append(same, m)
+		}
+	}
+	return same
+}
+
+// list writes the crew for the prompt, one member per line
+func (c Crew) list() string {
+	var b strings.Builder
+	for _, m := range c {
+		fmt.Fprintf(&b, "\t- ID %d: %s", m.ID, m.Name)
+		if len(m.Aliases) > 0 {
+			fmt.Fprintf(&b, " (also: %s)", strings.Join(m.Aliases, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func firstWord(s string) string {
+	if f := strings.Fields(s); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
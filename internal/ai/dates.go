@@ -0,0 +1,264 @@
+package ai
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRangeDays caps "from ... to ..." so a typo cannot block a year
+const maxRangeDays = 62
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// Words that join the two ends of a range: "Dec 20 to Jan 2", "Monday through Wednesday"
+var rangeWords = map[string]bool{"to": true, "through": true, "thru": true, "till": true, "until": true, "and": true, "-": true}
+
+// dateAt reads a date expression at text[i] and returns the days it covers, in order,
+// and how many words it took. Days are UTC midnights; today is the reference day.
+//
+//	today, tomorrow, the day after tomorrow
+//	friday, this friday      the next friday on or after today
+//	next friday              the friday of next week
+//	this/next week/weekend   every day of it
+//	the 24th                 the next 24th on or after today
+//	dec 20, 20th of december the next one on or after today, or in the year written after it
+//	2026-12-20
+//	from <date> to <date>    every day in between, both ends included ("between ... and ...",
+//	                         "through", "until", "-"); the end counts from the start, so
+//	                         "dec 20 to jan 2" crosses the year and "dec 20-24" stays in December
+func dateAt(text []string, i int, today time.Time) ([]time.Time, int) {
+	start := i
+	between := false
+	if i < len(text) && (text[i] == "from" || text[i] == "between") {
+		between = text[i] == "between"
+		i++
+	}
+	first, n := singleDate(text, i, today)
+	if n == 0 {
+		return nil, 0
+	}
+	i += n
+
+	// A range: the end is resolved from the first day
+	if i+1 < len(text) && rangeWords[text[i]] && (text[i] != "and" || between) {
+		last, m := singleDate(text, i+1, first[0])
+		if d, err := strconv.Atoi(text[i+1]); m == 0 && err == nil && len(first) == 1 {
+			last, m = dayOfMonth(first[0], d), 1 // "dec 20-24"
+		}
+		if m > 0 && last != nil {
+			from, to := first[0], last[len(last)-1]
+			if !to.Before(from) && to.Sub(from) <= maxRangeDays*24*time.Hour {
+				return days(from, to), i + 1 + m - start
+			}
+		}
+	}
+	return first, i - start
+}
+
+// singleDate reads one date, or one named stretch of days ("next week")
+func singleDate(text []string, i int, today time.Time) ([]time.Time, int) {
+	if i >= len(text) {
+		return nil, 0
+	}
+	w, next := text[i], ""
+	if i+1 < len(text) {
+		next = text[i+1]
+	}
+
+	switch {
+	case w == "today" || w == "tonight":
+		return []time.Time{today}, 1
+	case w == "this" && (next == "morning" || next == "afternoon" || next == "evening"):
+		return []time.Time{today}, 1 // The day part is read next
+	case w == "tomorrow":
+		return []time.Time{today.AddDate(0, 0, 1)}, 1
+	case hasPrefix(text[i:], []string{"the", "day", "after", "tomorrow"}):
+		return []time.Time{today.AddDate(0, 0, 2)}, 4
+	case hasPrefix(text[i:], []string{"day", "after", "tomorrow"}):
+		return []time.Time{today.AddDate(0, 0, 2)}, 3
+	case (w == "this" || w == "next") && next == "week":
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		if w == "next" {
+			monday = monday.AddDate(0, 0, 7)
+		}
+		return days(monday, monday.AddDate(0, 0, 6)), 2
+	case (w == "this" || w == "next") && next == "weekend":
+		saturday := onOrAfter(today, time.Saturday)
+		if today.Weekday() == time.Sunday {
+			saturday = today.AddDate(0, 0, -1)
+		}
+		if w == "next" {
+			saturday = saturday.AddDate(0, 0, 7)
+		}
+		return days(saturday, saturday.AddDate(0, 0, 1)), 2
+	case w == "next":
+		if wd, ok := weekdays[next]; ok {
+			return []time.Time{nextWeek(today, wd)}, 2
+		}
+		return nil, 0
+	case w == "this" || w == "on" || w == "the":
+		// Filler before a date: "this friday", "on the 24th"
+		if d, n := singleDate(text, i+1, today); n > 0 {
+			return d, n + 1
+		}
+		return nil, 0
+	}
+
+	if wd, ok := weekdayAt(text, i); ok {
+		return []time.Time{onOrAfter(today, wd)}, 1
+	}
+	if day, err := time.Parse("2006-01-02", w); err == nil {
+		return []time.Time{day}, 1
+	}
+
+	// Month and day, either order: "dec 20", "december 20th", "20 dec", "20th of december"
+	if m, ok := months[w]; ok {
+		if d, ok := ordinal(next); ok {
+			day, year := monthDay(text, i+2, today, m, d)
+			if day.IsZero() {
+				return nil, 0
+			}
+			return []time.Time{day}, 2 + year
+		}
+		return nil, 0
+	}
+	d, ok := ordinal(w)
+	if !ok {
+		return nil, 0
+	}
+	j := i + 1
+	if j < len(text) && text[j] == "of" {
+		j++
+	}
+	if j < len(text) {
+		if m, ok := months[text[j]]; ok {
+			day, year := monthDay(text, j+1, today, m, d)
+			if day.IsZero() {
+				return nil, 0
+			}
+			return []time.Time{day}, j + 1 - i + year
+		}
+	}
+	// A bare day of the month needs its suffix ("24th"), so hours and counts are not dates
+	if strings.TrimLeft(w, "0123456789") != "" {
+		if day := dayOfMonth(today, d); day != nil {
+			return day, 1
+		}
+	}
+	return nil, 0
+}
+
+// monthDay is the next m/d on or after today, or in the year at text[j].
+// It also returns how many words the year took (0 or 1); a zero day means no such date.
+func monthDay(text []string, j int, today time.Time, m time.Month, d int) (time.Time, int) {
+	if j < len(text) && len(text[j]) == 4 {
+		if year, err := strconv.Atoi(text[j]); err == nil && year >= 2000 && year < 2100 {
+			if day := time.Date(year, m, d, 0, 0, 0, 0, time.UTC); day.Month() == m {
+				return day, 1
+			}
+			return time.Time{}, 0
+		}
+	}
+	for year := today.Year(); year <= today.Year()+4; year++ {
+		if day := time.Date(year, m, d, 0, 0, 0, 0, time.UTC); day.Month() == m && !day.Before(today) {
+			return day, 0
+		}
+	}
+	return time.Time{}, 0
+}
+
+// dayOfMonth is the next d-th on or after today, skipping months too short for it
+func dayOfMonth(today time.Time, d int) []time.Time {
+	if d < 1 || d > 31 {
+		return nil
+	}
+	for k := 0; k < 12; k++ {
+		first := time.Date(today.Year(), today.Month()+time.Month(k), 1, 0, 0, 0, 0, time.UTC)
+		day := first.AddDate(0, 0, d-1)
+		if day.Month() == first.Month() && !day.Before(today) {
+			return []time.Time{day}
+		}
+	}
+	return nil
+}
+
+// Words around "sat" and "sun" that make them a verb or a noun, not a day:
+// "I sat down", "he sat with", "in the sun"
+var (
+	notDayBefore = map[string]bool{"i": true, "he": true, "she": true, "we": true, "they": true, "you": true, "who": true, "the": true}
+	notDayAfter  = map[string]bool{"down": true, "with": true, "through": true, "around": true, "there": true, "up": true, "out": true}
+)
+
+// weekdayAt reads the day named at text[i], guarding the short names that are also words
+func weekdayAt(text []string, i int) (time.Weekday, bool) {
+	wd, ok := weekdays[text[i]]
+	if !ok || text[i] != "sat" && text[i] != "sun" {
+		return wd, ok
+	}
+	if i > 0 && notDayBefore[text[i-1]] || i+1 < len(text) && notDayAfter[text[i+1]] {
+		return 0, false
+	}
+	return wd, true
+}
+
+// dateLike reports whether a word left unread still looks like part of a date: a month
+// with no valid day ("feb 30"), a day past the end of any month ("the 32nd") or a year.
+// "may" is left out: it is mostly the verb.
+func dateLike(w string) bool {
+	if _, ok := months[w]; ok {
+		return w != "may"
+	}
+	if w == "month" || w == "weekend" {
+		return true
+	}
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(w, suffix)); err == nil && strings.HasSuffix(w, suffix) && n > 0 {
+			return true
+		}
+	}
+	year, err := strconv.Atoi(w)
+	return err == nil && len(w) == 4 && year >= 2000 && year < 2100
+}
+
+// ordinal reads 20, 20th, 1st, 2nd, 3rd as a day of the month
+func ordinal(w string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		w = strings.TrimSuffix(w, suffix)
+	}
+	d, err := strconv.Atoi(w)
+	return d, err == nil && d >= 1 && d <= 31
+}
+
+// onOrAfter is the first wd on or after day
+func onOrAfter(day time.Time, wd time.Weekday) time.Time {
+	return day.AddDate(0, 0, (int(wd)-int(day.Weekday())+7)%7)
+}
+
+// nextWeek is wd in the Monday-Sunday week after the one holding day
+func nextWeek(day time.Time, wd time.Weekday) time.Time {
+	monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7+7)
+	return monday.AddDate(0, 0, (int(wd)+6)%7)
+}
+
+// days lists every day from one to the other, both included
+func days(from, to time.Time) []time.Time {
+	var out []time.Time
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		out = append(out, d)
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// GeminiParser asks Google's Gemini API
+type GeminiParser struct {
+	APIKey string
+	Model  string // Default gemini-2.5-flash
+}
+
+func (p *GeminiParser) Parse(ctx context.Context, msg Message) ([]Constraint, error) {
+	return ask(ctx, msg, p.complete)
+}
+
+// complete sends one prompt and returns the JSON text of the answer
+func (p *GeminiParser) complete(ctx context.Context, text string) (string, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(p.APIKey))
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	name := p.Model
+	if name == "" {
+		name = "gemini-2.5-flash"
+	}
+	model := client.GenerativeModel(name)
+
+	// 1. Configure for JSON Mode (Structured Output)
+	model.ResponseMIMEType = "application/json"
+
+	// 2. Generate
+	resp, err := model.GenerateContent(ctx, genai.Text(text))
+	if err != nil {
+		return "", fmt.Errorf("gemini: %w", err)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("gemini: empty response")
+	}
+
+	// 3. Gemini returns the JSON string in the first part
+	rawJSON, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
+	if !ok {
+		return "", fmt.Errorf("gemini: unexpected response format")
+	}
+	return string(rawJSON), nil
+}
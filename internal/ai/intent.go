@@ -0,0 +1,123 @@
+package ai
+
+import (
+	"fmt"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// What a staff message asks for
+const (
+	IntentUnavailable = "unavailable" // Cannot work: unavailability (also the empty Intent)
+	IntentAvailable   = "available"   // Offers extra hours: an on-call window
+	IntentSwap        = "swap"        // Wants to trade shifts with SwapWith: a swap request
+	IntentTimeOff     = "time_off"    // Asks for time off in advance: a time-off request for the manager
+)
+
+// Intents lists every intent a parser may return
+func Intents() []string {
+	return []string{IntentUnavailable, IntentAvailable, IntentSwap, IntentTimeOff}
+}
+
+// Words that tell the intents apart, checked in this order
+var (
+	swapWords    = map[string]bool{"swap": true, "swapping": true, "switch": true, "switching": true, "trade": true, "trading": true, "exchange": true}
+	timeOffWords = map[string]bool{"vacation": true, "holiday": true, "holidays": true, "pto": true}
+	askWords     = map[string]bool{"take": true, "have": true, "get": true, "book": true, "request": true, "requesting": true,
+		"like": true, "want": true, "wants": true, "need": true, "needs": true}
+	offerWords = map[string]bool{"extra": true, "pick": true, "cover": true, "overtime": true, "available": true, "free": true, "spare": true}
+	ableWords  = map[string]bool{"work": true, "come": true, "do": true}
+)
+
+// classify reads a subject's intent from its other words: "swap" asks for a swap, "vacation"
+// or "off" with a request ("can I take Friday off") asks for time off, "extra hours" or
+// "free" offers hours, and anything else (or "can only work") is unavailability
+func classify(s subject) string {
+	switch {
+	case s.only:
+		return IntentUnavailable
+	case anyOf(s.said, swapWords):
+		return IntentSwap
+	case anyOf(s.said, timeOffWords), s.said["off"] && anyOf(s.said, askWords):
+		return IntentTimeOff
+	case anyOf(s.said, negativeWords):
+		return IntentUnavailable
+	case anyOf(s.said, offerWords), s.said["can"] && anyOf(s.said, ableWords):
+		return IntentAvailable
+	}
+	return IntentUnavailable
+}
+
+// swapping merges the subjects of a message asking for a swap into one: "I want to swap
+// Tuesday with Eve" names the days before the partner. The requester is the sender when
+// they are one of the two, else the first named, and comes first.
+func swapping(subjects []subject, sender Member) ([]subject, error) {
+	swap := false
+	for _, s := range subjects {
+		swap = swap || s.intent == IntentSwap
+	}
+	if !swap {
+		return subjects, nil
+	}
+	merged := subject{intent: IntentSwap, namesakes: map[string][]Member{}}
+	for _, s := range subjects {
+		for _, m := range s.members {
+			if !hasMember(merged.members, m) {
+				merged.members = append(merged.members, m)
+			}
+		}
+		for name, same := range s.namesakes {
+			merged.namesakes[name] = same
+		}
+		merged.windows = append(merged.windows, s.windows...)
+	}
+	if len(merged.members) != 2 {
+		return nil, fmt.Errorf("%w: a swap is between two people, not %d", ErrUnknownEmployee, len(merged.members))
+	}
+	if sender.ID != 0 && merged.members[1].ID == sender.ID {
+		merged.members[0], merged.members[1] = merged.members[1], merged.members[0]
+	}
+	return []subject{merged}, nil
+}
+
+// intentOf is the intent of a constraint, unavailability when the parser left it empty
+func intentOf(c Constraint) string {
+	if c.Intent == "" {
+		return IntentUnavailable
+	}
+	return c.Intent
+}
+
+// doing describes what the constraint's employee does, for questions: "Alice (Vet) available"
+func doing(c Constraint) string {
+	switch intentOf(c) {
+	case IntentAvailable:
+		return c.EmployeeName + " available"
+	case IntentSwap:
+		return fmt.Sprintf("%s swapping with %s", c.EmployeeName, c.SwapWith.Name)
+	case IntentTimeOff:
+		return c.EmployeeName + " asking for time off"
+	}
+	return c.EmployeeName + " unavailable"
+}
+
+// requests routes constraints to the records they become
+func requests(found []Constraint, text string) models.Requests {
+	var r models.Requests
+	for _, c := range found {
+		u := c.Unavailability
+		switch intentOf(c) {
+		case IntentAvailable:
+			r.OnCall = append(r.OnCall, models.OnCall{EmployeeID: u.EmployeeID, StartHour: u.StartHour, EndHour: u.EndHour, Date: u.Date})
+		case IntentTimeOff:
+			r.TimeOff = append(r.TimeOff, models.TimeOffRequest{EmployeeID: u.EmployeeID, EmployeeName: u.EmployeeName,
+				Date: u.Date, StartHour: u.StartHour, EndHour: u.EndHour, Reason: u.Reason, Message: text})
+		case IntentSwap:
+			r.Swaps = append(r.Swaps, models.SwapRequest{EmployeeID: u.EmployeeID, EmployeeName: u.EmployeeName,
+				WithID: c.SwapWith.ID, WithName: c.SwapWith.Name, Date: u.Date, StartHour: u.StartHour, EndHour: u.EndHour, Message: text})
+		default:
+			r.Unavailability = append(r.Unavailability, u)
+		}
+	}
+	return r
+}
@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAIParser speaks the OpenAI chat completions API, which OpenAI itself and
+// local servers (llama.cpp's llama-server, Ollama, vLLM...) all implement
+type OpenAIParser struct {
+	BaseURL string // Default https://api.openai.com/v1
+	APIKey  string // Optional for local servers
+	Model   string // Default gpt-4o-mini
+	Client  *http.Client
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string        `json:"model"`
+	Messages       []chatMessage `json:"messages"`
+	Temperature    float64       `json:"temperature"`
+	ResponseFormat struct {
+		Type string `json:"type"`
+	} `json:"response_format"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (p *OpenAIParser) Parse(ctx context.Context, msg Message) ([]Constraint, error) {
+	return ask(ctx, msg, p.complete)
+}
+
+// complete sends one user message and returns the reply
+func (p *OpenAIParser) complete(ctx context.Context, message string) (string, error) {
+	base := strings.TrimSuffix(p.BaseURL, "/")
+	if base == "" {
+		base = "https://api.openai.com/v1"
+	}
+	model := p.Model
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// 1. Request: JSON mode, deterministic
+	body := chatRequest{Model: model, Messages: []chatMessage{{Role: "user", Content: message}}}
+	body.ResponseFormat.Type = "json_object"
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/chat/completions", bytes.NewReader(raw))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	// 2. Response: the first choice
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("openai: %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	var out chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("openai: empty response")
+	}
+	return out.Choices[0].Message.Content, nil
+}
@@ -1,118 +1,514 @@
+// Package ai turns free text ("Alice has a dentist appointment in the morning") into
+// structured availability constraints. The model behind it is pluggable: Gemini, any
+// OpenAI-compatible server (OpenAI, a local llama.cpp or Ollama) or offline rules.
 package ai
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
-	"github.com/google/generative-ai-go/genai"
+	"github.com/iannsp/shiftopt/internal/database"
 	"github.com/iannsp/shiftopt/internal/models"
-	"google.golang.org/api/option"
 )
 
-// ParseConstraint attempts to use Gemini AI, falling back to Mock if no key is found.
-func ParseConstraint(input string) models.Unavailability {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		log.Println("[AI] No API Key found. Using Mock Parser.")
-		return MockParse(input)
+// Message is what a parser reads: the text, who it may be about, who sent it and when
+type Message struct {
+	Text   string
+	Crew   Crew
+	Sender Member    // Who "I" and "me" are (zero ID = unknown)
+	Today  time.Time // Relative dates count from this calendar day, read in its time zone (zero = now)
+}
+
+// Constraint is one window found in a message and what is asked for in it: by default
+// unavailability, or an offer of hours, a swap or a time-off request (see Intent)
+type Constraint struct {
+	models.Unavailability
+	Intent     string      // IntentUnavailable (or empty), IntentAvailable, IntentSwap or IntentTimeOff
+	SwapWith   Member      // IntentSwap: who they want to trade with
+	Confidence float64     // 0 (a guess) to 1 (stated plainly)
+	Unclear    []Ambiguity // What to ask the sender before saving, most important first
+}
+
+// ConstraintParser extracts every window from a message: several people, several windows
+// and several days, each with its intent. Each is resolved against the crew, so EmployeeID
+// and EmployeeName always name a member, unless Unclear asks which of several it is.
+// A message naming nobody on the crew (nor from a known sender) is an error.
+type ConstraintParser interface {
+	Parse(ctx context.Context, msg Message) ([]Constraint, error)
+}
+
+// ErrNothingFound is returned when a message holds no constraint at all
+var ErrNothingFound = errors.New("no constraint found")
+
+// ErrInvalidAnswer is returned when a model's answer breaks the schema it was given or
+// names someone who is not on the crew
+var ErrInvalidAnswer = errors.New("invalid model answer")
+
+// maxAttempts is how many times a model is asked before its invalid answers are given up on
+const maxAttempts = 3
+
+// Providers understood by NewParser
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai" // Any server speaking the OpenAI chat completions API
+	ProviderRules  = "rules"  // Offline keyword rules, no model
+)
+
+// Config selects and configures a provider. An empty Provider picks one from the
+// credentials present: Gemini, then OpenAI-compatible, then rules.
+type Config struct {
+	Provider string
+	APIKey   string
+	BaseURL  string // OpenAI-compatible servers, e.g. http://localhost:11434/v1 for Ollama
+	Model    string
+}
+
+// ConfigFromEnv reads SHIFTOPT_AI_PROVIDER, SHIFTOPT_AI_MODEL, GEMINI_API_KEY,
+// OPENAI_API_KEY and OPENAI_BASE_URL
+func ConfigFromEnv() Config {
+	c := Config{Provider: os.Getenv("SHIFTOPT_AI_PROVIDER"), Model: os.Getenv("SHIFTOPT_AI_MODEL")}
+	gemini, openai, baseURL := os.Getenv("GEMINI_API_KEY"), os.Getenv("OPENAI_API_KEY"), os.Getenv("OPENAI_BASE_URL")
+	if c.Provider == "" {
+		switch {
+		case gemini != "":
+			c.Provider = ProviderGemini
+		case openai != "" || baseURL != "":
+			c.Provider = ProviderOpenAI
+		default:
+			c.Provider = ProviderRules
+		}
+	}
+	switch c.Provider {
+	case ProviderGemini:
+		c.APIKey = gemini
+	case ProviderOpenAI:
+		c.APIKey, c.BaseURL = openai, baseURL
 	}
+	return c
+}
 
-	result, err := callGemini(context.Background(), apiKey, input)
-	if err != nil {
-		log.Printf("[AI] Gemini Error: %v. Falling back to Mock.\n", err)
-		return MockParse(input)
+// NewParser builds the parser a Config describes
+func NewParser(c Config) (ConstraintParser, error) {
+	switch c.Provider {
+	case ProviderGemini:
+		if c.APIKey == "" {
+			return nil, fmt.Errorf("the gemini provider needs GEMINI_API_KEY")
+		}
+		return &GeminiParser{APIKey: c.APIKey, Model: c.Model}, nil
+	case ProviderOpenAI:
+		return &OpenAIParser{BaseURL: c.BaseURL, APIKey: c.APIKey, Model: c.Model}, nil
+	case ProviderRules, "":
+		return RuleParser{}, nil
 	}
+	return nil, fmt.Errorf("unknown AI provider %q (want %s, %s or %s)", c.Provider, ProviderGemini, ProviderOpenAI, ProviderRules)
+}
 
-	return result
+// WithFallback answers from primary, and from fallback when primary fails
+func WithFallback(primary, fallback ConstraintParser) ConstraintParser {
+	return fallbackParser{primary, fallback}
+}
+
+type fallbackParser struct {
+	primary, fallback ConstraintParser
+}
+
+func (p fallbackParser) Parse(ctx context.Context, msg Message) ([]Constraint, error) {
+	counters.parses.Add(1)
+	found, err := p.primary.Parse(ctx, msg)
+	if err == nil {
+		return found, nil
+	}
+	counters.fallbacks.Add(1)
+	stats := ReadStats()
+	log.Printf("[AI] %v. Falling back to rules (%d of %d messages so far).\n", err, stats.Fallbacks, stats.Parses)
+	return p.fallback.Parse(ctx, msg)
 }
 
-func callGemini(ctx context.Context, apiKey, input string) (models.Unavailability, error) {
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+// FromEnv is the parser configured in the environment, backed by the rules
+// when it is missing or fails
+func FromEnv() ConstraintParser {
+	return CachedFromEnv(nil)
+}
+
+// CachedFromEnv is FromEnv remembering the model's answers in cache (nil = no cache)
+func CachedFromEnv(cache ParseCache) ConstraintParser {
+	config := ConfigFromEnv()
+	primary, err := NewParser(config)
+	if err != nil {
+		log.Printf("[AI] %v. Using rules.\n", err)
+		return RuleParser{}
+	}
+	if config.Provider == ProviderRules {
+		log.Println("[AI] No model configured. Using rules.")
+		return primary
+	}
+	if cache != nil {
+		primary = Cached(primary, config.Provider+" "+config.Model+" "+config.BaseURL, cache)
+	}
+	return WithFallback(primary, RuleParser{})
+}
+
+// ParseConstraint parses a message about the Store's active staff with the parser from
+// FromEnv and returns its first constraint, without saving it
+func ParseConstraint(store database.Store, input string) (models.Unavailability, error) {
+	crew, err := LoadCrew(store)
+	if err != nil {
+		return models.Unavailability{}, err
+	}
+	found, err := FromEnv().Parse(context.Background(), Message{Text: input, Crew: crew})
 	if err != nil {
 		return models.Unavailability{}, err
 	}
-	defer client.Close()
+	if len(found) == 0 {
+		return models.Unavailability{}, ErrNothingFound
+	}
+	return found[0].Unavailability, nil
+}
+
+// Outcome is what Record or Answer did with a message
+type Outcome struct {
+	Constraints []Constraint
+	PendingID   int    // Set when the message is held: nothing is saved until Question is answered
+	Question    string // For the sender, e.g. "Which Mary: Mary Ann Lee or Mary Jones?"
+}
+
+// Held reports whether the message waits for an answer
+func (o Outcome) Held() bool {
+	return o.PendingID != 0
+}
+
+// Record parses a message about the Store's active staff and saves everything it asks for,
+// all or none: unavailability, on-call windows for offered hours, and time-off and swap
+// requests for the manager. Nothing is saved unless each employee the parser returned is on the crew.
+//
+// When anything is unclear, or the parser is less sure than ConfirmBelow, the whole message
+// is held as pending instead, with the first question to ask. Answer completes it.
+func Record(ctx context.Context, store database.Store, parser ConstraintParser, text string, today time.Time) (Outcome, error) {
+	return RecordFrom(ctx, store, parser, 0, text, today)
+}
 
-	model := client.GenerativeModel("gemini-2.5-flash")
-	
-	// 1. Configure for JSON Mode (Structured Output)
-	model.ResponseMIMEType = "application/json"
-	
-	// 2. The System Prompt (The "Brain" Instructions)
-	prompt := fmt.Sprintf(`
-	You are a scheduling assistant. Extract availability constraints from the user's text.
+// RecordFrom is Record for a message sent by an employee (0 = unknown): "I" and "me" are them
+func RecordFrom(ctx context.Context, store database.Store, parser ConstraintParser, senderID int, text string, today time.Time) (Outcome, error) {
+	crew, err := LoadCrew(store)
+	if err != nil {
+		return Outcome{}, err
+	}
+	var sender Member
+	if senderID != 0 {
+		var ok bool
+		if sender, ok = crew.ByID(senderID); !ok {
+			return Outcome{}, fmt.Errorf("%w: no active employee has ID %d", ErrUnknownEmployee, senderID)
+		}
+	}
+	found, err := parser.Parse(ctx, Message{Text: text, Crew: crew, Sender: sender, Today: today})
+	if err != nil {
+		return Outcome{}, err
+	}
+	if len(found) == 0 {
+		return Outcome{}, ErrNothingFound
+	}
+
+	// 1. Parsers resolve against the crew, but a custom one may not. Who is still to be
+	// asked is checked by Answer, once the sender has said.
+	for i := range found {
+		if unclear(found[i], FieldEmployee) {
+			continue
+		}
+		if err := resolve(&found[i], crew); err != nil {
+			return Outcome{}, err
+		}
+		if len(found[i].Unclear) == 0 && found[i].Confidence < ConfirmBelow {
+			found[i].Unclear = []Ambiguity{{Field: FieldConfirm}}
+		}
+	}
+
+	// 2. Save it all, or hold it all
+	if c, ok := firstUnclear(found); ok {
+		return hold(store, models.PendingMessage{Text: text, SenderID: senderID}, found, question(c, c.Unclear[0]))
+	}
+	return Outcome{Constraints: found}, store.SaveRequests(requests(found, text))
+}
+
+// resolve checks c is about a member of the crew, with a known intent and, for a swap,
+// another member to swap with
+func resolve(c *Constraint, crew Crew) error {
+	var err error
+	if c.Unavailability, err = crew.Resolve(c.Unavailability); err != nil {
+		return err
+	}
+	return checkIntent(c, crew)
+}
+
+// checkIntent checks the intent is known and a swap partner is another member of the crew
+func checkIntent(c *Constraint, crew Crew) error {
+	switch intentOf(*c) {
+	case IntentUnavailable, IntentAvailable, IntentTimeOff:
+		return nil
+	case IntentSwap:
+		m, ok := crew.ByID(c.SwapWith.ID)
+		if !ok {
+			m, ok = crew.ByName(c.SwapWith.Name)
+		}
+		if !ok || m.ID == c.EmployeeID {
+			return fmt.Errorf("%w: %s wants to swap, but with whom?", ErrUnknownEmployee, c.EmployeeName)
+		}
+		c.SwapWith = m
+		return nil
+	}
+	return fmt.Errorf("unknown intent %q (want one of %v)", c.Intent, Intents())
+}
+
+// prompt is the instruction every model gets
+func prompt(msg Message) string {
+	today := reference(msg.Today)
+	return fmt.Sprintf(`
+	You are a scheduling assistant. Extract every availability constraint from the user's text:
+	one message can be about several people, several time windows and several days.
+	The staff are:
+%s
+	%s
+	Today is %s at the store.
 	Return a SINGLE JSON object with this exact schema:
 	{
-		"EmployeeName": "string (Matches one of: Alice (Vet), Bob (Vet), Carol (Vet), Dave (Jun), Eve (Jun), Frank (Jun), Grace (Grinder), Hank (Grinder))",
-		"StartHour": int (0-23),
-		"EndHour": int (0-23, exclusive),
-		"Reason": "string (short summary)"
+		"constraints": [
+			{
+				"EmployeeID": int (the ID of the person from the staff list above),
+				"EmployeeName": "string (their name exactly as in the staff list)",
+				"Intent": "unavailable, available, swap or time_off",
+				"SwapWithID": int (for swap: the ID of the person they trade with, else 0),
+				"Date": "YYYY-MM-DD, or empty when no day is mentioned",
+				"EndDate": "YYYY-MM-DD, the last day of a range of days (inclusive), or empty",
+				"StartHour": int (0-23),
+				"EndHour": int (1-24, exclusive),
+				"Reason": "string (short summary)",
+				"Confidence": number (0-1, how sure you are of this constraint),
+				"Unclear": [
+					{
+						"Field": "employee, date or time",
+						"Text": "the unclear words as written, e.g. \"later\"",
+						"Options": [int] (for employee: the IDs of everyone it could be)
+					}
+				]
+			}
+		]
 	}
-	
+
+	Intents:
+	- unavailable: they cannot work ("I can't do Friday", "Alice is sick today")
+	- available: they offer hours ("I can pick up extra hours Saturday", "Bob is free tomorrow")
+	- swap: two people want to trade shifts ("Eve and I want to swap Tuesday"); the entry is
+	  for the person asking, SwapWithID is the other one
+	- time_off: a request for time off in advance ("Can I take next week off?", "vacation in May")
+
 	Rules:
 	- "Morning" = 08:00 to 12:00
 	- "Afternoon" = 13:00 to 17:00
-	- "All day" = 08:00 to 20:00
-	- Use fuzzy matching to map names like "Alice" to "Alice (Vet)".
-	
-	User Input: "%s"
-	`, input)
-
-	// 3. Generate
-	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
-	if err != nil {
-		return models.Unavailability{}, err
+	- "Evening" = 17:00 to 20:00
+	- "After 3pm" = 15:00 to 20:00; "before 11am" = 08:00 to 11:00; "at 3pm" = 15:00 to 16:00
+	- "Can only work mornings" is unavailable for the rest of the day (12:00 to 20:00)
+	- "All day", or a day with no time = 08:00 to 20:00
+	- A weekday name ("Friday", "this Friday") means the next such day on or after today.
+	- "Next Friday" means the Friday of next week (weeks start on Monday).
+	- "The 24th" means the next 24th on or after today; "Dec 20" the next Dec 20 on or after today.
+	- A range ("from Dec 20 to Jan 2", "Monday to Wednesday") is ONE entry with Date and EndDate.
+	- One entry per person per window: "Alice and Bob are off Monday and Friday" is four entries.
+	- Use fuzzy matching to map names and nicknames to a person on the staff list.
+	- Never invent an ID: if nobody on the list matches, use 0.
+	- Never guess: a name several people share, a vague time ("later", "late") or a vague day
+	  ("soon") goes in Unclear. Leave Unclear empty when everything was stated plainly.
+
+	User Input: %q
+	`, msg.Crew.list(), from(msg.Sender), today.Format("Monday 2006-01-02"), msg.Text)
+}
+
+// from tells the model who "I" is
+func from(sender Member) string {
+	if sender.ID == 0 {
+		return "The sender is unknown: \"I\" and \"me\" name nobody."
 	}
+	return fmt.Sprintf("The message is from ID %d: %s. \"I\", \"me\" and \"my\" mean them.", sender.ID, sender.Name)
+}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return models.Unavailability{}, fmt.Errorf("empty response from AI")
+// ask sends msg's prompt through complete until the answer decodes, at most maxAttempts
+// times. Each retry tells the model what was wrong; failed requests are not retried.
+func ask(ctx context.Context, msg Message, complete func(context.Context, string) (string, error)) ([]Constraint, error) {
+	text := prompt(msg)
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		counters.modelCalls.Add(1)
+		var raw string
+		if raw, err = complete(ctx, text); err != nil {
+			return nil, err
+		}
+		var found []Constraint
+		if found, err = decode(raw, msg.Crew); !errors.Is(err, ErrInvalidAnswer) {
+			return found, err
+		}
+		counters.invalidAnswers.Add(1)
+		log.Printf("[AI] Attempt %d of %d: %v\n", attempt, maxAttempts, err)
+		text = prompt(msg) + fmt.Sprintf("\n\tYour previous answer was rejected (%v). Answer again, following the schema exactly.\n", err)
 	}
+	return nil, err
+}
+
+// answer is the JSON the models are asked for
+type answer struct {
+	Constraints []answerItem
+}
 
-	// 4. Parse JSON
-	// Gemini returns the JSON string in the first part
-	rawJSON, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
-	if !ok {
-		return models.Unavailability{}, fmt.Errorf("unexpected response format")
+type answerItem struct {
+	EmployeeID   int
+	EmployeeName string
+	Intent       string
+	SwapWithID   int
+	Date         string
+	EndDate      string
+	StartHour    int
+	EndHour      int
+	Reason       string
+	Confidence   *float64
+	Unclear      []struct {
+		Field   string
+		Text    string
+		Options []int
 	}
+}
+
+// decode reads the model's JSON answer, tolerating a Markdown code fence around it,
+// a bare list or a single constraint, and resolves the employees against the crew
+func decode(raw string, crew Crew) ([]Constraint, error) {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimPrefix(raw, "```json")
+	raw = strings.TrimPrefix(raw, "```")
+	raw = strings.TrimSuffix(raw, "```")
+	raw = strings.TrimSpace(raw)
 
-	var parsed models.Unavailability
-	if err := json.Unmarshal([]byte(rawJSON), &parsed); err != nil {
-		return models.Unavailability{}, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	// 1. Whichever shape came back
+	var parsed answer
+	var err error
+	if strings.HasPrefix(raw, "[") {
+		err = json.Unmarshal([]byte(raw), &parsed.Constraints)
+	} else if err = json.Unmarshal([]byte(raw), &parsed); err == nil && parsed.Constraints == nil {
+		var one answerItem
+		if err = json.Unmarshal([]byte(raw), &one); err == nil && (one.EmployeeID != 0 || one.EmployeeName != "") {
+			parsed.Constraints = []answerItem{one}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal JSON: %v", ErrInvalidAnswer, err)
+	}
+	if len(parsed.Constraints) == 0 {
+		return nil, ErrNothingFound
 	}
 
-	return parsed, nil
-}
+	// 2. Check each one, and spread ranges over their days
+	var found []Constraint
+	for i, item := range parsed.Constraints {
+		if err := item.check(); err != nil {
+			return nil, fmt.Errorf("%w: constraint %d: %v", ErrInvalidAnswer, i+1, err)
+		}
+		u := models.Unavailability{EmployeeID: item.EmployeeID, EmployeeName: item.EmployeeName,
+			StartHour: item.StartHour, EndHour: item.EndHour, Reason: item.Reason}
+		c := Constraint{Unavailability: u, Intent: item.Intent, SwapWith: Member{ID: item.SwapWithID}, Confidence: 0.5} // Unknown when the model does not say
+		if item.Confidence != nil {
+			c.Confidence = *item.Confidence
+		}
+		for _, a := range item.Unclear {
+			if a, ok := ambiguity(a.Field, a.Text, a.Options, crew); ok {
+				c.Unclear = append(c.Unclear, a)
+			}
+		}
+		if !unclear(c, FieldEmployee) {
+			if c.Unavailability, err = crew.Resolve(u); err != nil {
+				return nil, fmt.Errorf("%w: constraint %d: %w", ErrInvalidAnswer, i+1, err)
+			}
+		}
+		if item.SwapWithID != 0 {
+			m, ok := crew.ByID(item.SwapWithID)
+			if !ok || m.ID == c.EmployeeID {
+				return nil, fmt.Errorf("%w: constraint %d: no other active employee has SwapWithID %d", ErrInvalidAnswer, i+1, item.SwapWithID)
+			}
+			c.SwapWith = m
+		}
 
-// MockParse (Preserved for Fallback/Testing)
-func MockParse(input string) models.Unavailability {
-	input = strings.ToLower(input)
-	result := models.Unavailability{}
+		from, to, err := dateRange(item.Date, item.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: constraint %d: %v", ErrInvalidAnswer, i+1, err)
+		}
+		if from.IsZero() {
+			found = append(found, c)
+			continue
+		}
+		for _, day := range days(from, to) {
+			c.Date = day
+			found = append(found, c)
+		}
+	}
+	return found, nil
+}
 
-	// Simple Heuristics (Same as before)
-	if strings.Contains(input, "alice") {
-		result.EmployeeName = "Alice (Vet)"
-	} else if strings.Contains(input, "bob") {
-		result.EmployeeName = "Bob (Vet)"
-	} else {
-		result.EmployeeName = "Dave (Jun)" // Default fallback
+// check holds one constraint to the schema in the prompt. The hours are not checked when
+// the model flags the time as unclear: the answer to the question sets them.
+func (item answerItem) check() error {
+	timeUnclear := false
+	for _, a := range item.Unclear {
+		timeUnclear = timeUnclear || strings.EqualFold(strings.TrimSpace(a.Field), FieldTime)
+	}
+	switch {
+	case timeUnclear:
+	case item.StartHour < 0 || item.StartHour > 23:
+		return fmt.Errorf("StartHour %d is not 0-23", item.StartHour)
+	case item.EndHour < 1 || item.EndHour > 24:
+		return fmt.Errorf("EndHour %d is not 1-24", item.EndHour)
+	case item.EndHour <= item.StartHour:
+		return fmt.Errorf("EndHour %d is not after StartHour %d", item.EndHour, item.StartHour)
+	}
+	switch {
+	case item.Confidence != nil && (*item.Confidence < 0 || *item.Confidence > 1):
+		return fmt.Errorf("Confidence %v is not 0-1", *item.Confidence)
+	case item.Intent != "" && !slices.Contains(Intents(), item.Intent):
+		return fmt.Errorf("unknown intent %q (want one of %v)", item.Intent, Intents())
+	case item.Intent == IntentSwap && item.SwapWithID == 0:
+		return fmt.Errorf("a swap without SwapWithID")
 	}
+	return nil
+}
 
-	if strings.Contains(input, "morning") {
-		result.StartHour = 8; result.EndHour = 12
-	} else if strings.Contains(input, "afternoon") {
-		result.StartHour = 13; result.EndHour = 17
-	} else {
-		result.StartHour = 9; result.EndHour = 10
+// dateRange reads a model's Date and EndDate (both optional, inclusive)
+func dateRange(date, end string) (from, to time.Time, err error) {
+	if date == "" {
+		if end != "" {
+			return from, to, fmt.Errorf("EndDate %q without a Date", end)
+		}
+		return from, to, nil
+	}
+	if from, err = time.Parse(database.DateLayout, date); err != nil {
+		return from, to, fmt.Errorf("date %q is not YYYY-MM-DD", date)
+	}
+	to = from
+	if end != "" {
+		if to, err = time.Parse(database.DateLayout, end); err != nil {
+			return from, to, fmt.Errorf("end date %q is not YYYY-MM-DD", end)
+		}
 	}
+	if to.Before(from) || to.Sub(from) > maxRangeDays*24*time.Hour {
+		return from, to, fmt.Errorf("%s to %s is not a range of at most %d days", date, end, maxRangeDays)
+	}
+	return from, to, nil
+}
 
-	if strings.Contains(input, "dentist") {
-		result.Reason = "Medical (Dentist)"
-	} else {
-		result.Reason = "Personal"
+// reference is the day relative dates are counted from
+func reference(today time.Time) time.Time {
+	if today.IsZero() {
+		today = time.Now()
 	}
-	return result
+	y, m, d := today.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
 }
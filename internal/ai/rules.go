@@ -0,0 +1,571 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// RuleParser is the offline parser: a small grammar over the words of the message, no model.
+// It recognises names and aliases, dates and ranges of dates (see dateAt), day parts
+// ("morning", "all day") and clock times ("after 3pm", "2-5pm", see clockAt).
+// It fails when the message names nobody on the crew (ErrUnknownEmployee) or leaves no
+// hours to block (ErrNothingFound: "Bob can only work", "Alice is off before 8am").
+// What it cannot settle is flagged in Unclear: a first name several people share, no
+// hours at all, or vague words like "later" or "soon".
+//
+// Each name starts a new subject ("Alice and Bob" share one) and the days and times
+// after it belong to that subject: "Alice can't do Monday morning or Friday, and Bob
+// is off Wednesday" is Alice Monday 08-12, Alice Friday 08-20 and Bob Wednesday 08-20.
+//
+// "Only" with a word of availability turns a subject around: "Bob can only work mornings"
+// is Bob off 12-20 every day, and "Eve is only available Friday" is Eve off the other
+// days of that week.
+//
+// Other words tell what is asked for (see classify): "I can pick up extra hours Saturday"
+// offers hours, "can I take Friday off" asks for time off, and "Eve and I want to swap
+// Tuesday" is a swap between the sender and Eve.
+type RuleParser struct{}
+
+func (RuleParser) Parse(ctx context.Context, msg Message) ([]Constraint, error) {
+	subjects := extract(msg)
+	if len(subjects[0].members) == 0 {
+		return nil, fmt.Errorf("%w: nobody on the staff list is named in %q", ErrUnknownEmployee, msg.Text)
+	}
+	subjects, err := swapping(subjects, msg.Sender)
+	if err != nil {
+		return nil, err
+	}
+	found := constraints(subjects, msg.Text)
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w in %q", ErrNothingFound, msg.Text)
+	}
+	return found, nil
+}
+
+// MockParse is RuleParser on the DemoCrew returning the first constraint, blaming Dave
+// when nobody is named and ignoring what is unclear. It is the zero Unavailability when
+// the message holds no constraint.
+//
+// Deprecated: use RuleParser, which reports who and what it could not read, and Record,
+// which asks instead of guessing.
+func MockParse(input string) models.Unavailability {
+	subjects := extract(Message{Text: input, Crew: DemoCrew})
+	if len(subjects[0].members) == 0 {
+		dave, _ := DemoCrew.ByID(4) // Default fallback
+		subjects[0].members = []Member{dave}
+	}
+	found := constraints(subjects, input)
+	if len(found) == 0 {
+		return models.Unavailability{}
+	}
+	return found[0].Unavailability
+}
+
+// Day parts, [start, end)
+var periods = []struct {
+	words      []string
+	start, end int
+}{
+	{[]string{"all", "day"}, 8, 20},
+	{[]string{"whole", "day"}, 8, 20},
+	{[]string{"morning"}, 8, 12},
+	{[]string{"mornings"}, 8, 12},
+	{[]string{"afternoon"}, 13, 17},
+	{[]string{"afternoons"}, 13, 17},
+	{[]string{"evening"}, 17, 20},
+	{[]string{"evenings"}, 17, 20},
+}
+
+var weekdays = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+}
+
+// Words that leave the hours or the day open ("Alice will be late", "Bob is off soon")
+var (
+	vagueTimes = map[string]bool{"later": true, "late": true, "early": true, "night": true, "lunch": true, "lunchtime": true}
+	vagueDays  = map[string]bool{"soon": true, "sometime": true, "someday": true, "eventually": true}
+)
+
+// Words that make "only" a limit on working ("can only work mornings") rather than on being
+// away ("is off only on Friday")
+var (
+	availableWords = map[string]bool{"work": true, "available": true, "free": true, "come": true, "in": true}
+	negativeWords  = map[string]bool{"can't": true, "cannot": true, "not": true, "never": true, "unavailable": true, "off": true, "busy": true,
+		"isn't": true, "aren't": true, "doesn't": true, "don't": true, "won't": true, "wasn't": true, "unable": true,
+		"cant": true, "isnt": true, "arent": true, "doesnt": true, "dont": true, "wont": true}
+)
+
+// Words that may sit between a negation and what it negates: "won't be available"
+var negationGap = map[string]bool{"be": true, "to": true, "able": true, "really": true}
+
+// negated reports whether a negation comes just before text[i]
+func negated(text []string, i int) bool {
+	for k := i - 1; k >= 0 && k >= i-3; k-- {
+		if negativeWords[text[k]] {
+			return true
+		}
+		if !negationGap[text[k]] {
+			return false
+		}
+	}
+	return false
+}
+
+// connectors may sit between two names of one subject ("Alice and Bob")
+var connectors = map[string]bool{"and": true, "or": true, "plus": true}
+
+// window is the same hours on one or more days; no days means every day
+type window struct {
+	days       []time.Time
+	start, end int
+	hasTime    bool
+}
+
+// subject is who a run of the message is about and the windows it names.
+// A first name several people share is a member with no ID, its candidates in namesakes.
+// When only is set, the windows are when they can work; said holds the other words.
+type subject struct {
+	members   []Member
+	windows   []window
+	namesakes map[string][]Member
+	only      bool
+	week      bool   // A week was named for its days: "Monday next week"
+	undated   string // A word that looks like a date but was not read as one: "the 32nd"
+	said      map[string]bool
+	intent    string
+}
+
+// requalify moves the trailing windows of a single day into the week given, keeping the
+// weekday: "Monday" then "next week" is next week's Monday. It reports whether it did.
+func (s *subject) requalify(week []time.Time) bool {
+	last := len(s.windows) - 1
+	if len(week) < 2 || len(week) > 7 || len(s.windows[last].days) != 1 {
+		return false
+	}
+	day := s.windows[last].days[0]
+	for _, d := range week {
+		if d.Weekday() != day.Weekday() {
+			continue
+		}
+		for k := last; k >= 0 && len(s.windows[k].days) == 1 && s.windows[k].days[0].Equal(day); k-- {
+			s.windows[k].days = []time.Time{d}
+		}
+		return true
+	}
+	return false
+}
+
+// setHours times the last window if it has no hours yet, else opens one on the same days
+func (s *subject) setHours(start, end int) {
+	last := len(s.windows) - 1
+	switch {
+	case last >= 0 && !s.windows[last].hasTime:
+		s.windows[last].start, s.windows[last].end, s.windows[last].hasTime = start, end, true
+	case last >= 0:
+		s.windows = append(s.windows, window{days: s.windows[last].days, start: start, end: end, hasTime: true})
+	default:
+		s.windows = append(s.windows, window{start: start, end: end, hasTime: true})
+	}
+}
+
+// extract splits the message into subjects. The first subject has no members when
+// the message names nobody; windows named before the first name belong to it.
+func extract(msg Message) []subject {
+	text := words(msg.Text)
+	today := reference(msg.Today)
+	subjects := []subject{{}}
+	lastName := -1 // Where the previous name ended
+	lastDate := -1 // Where the previous date ended
+
+	for i := 0; i < len(text); {
+		cur := &subjects[len(subjects)-1]
+
+		// 1. A name, or "I" for the sender: joins the current subject when only connectors
+		// separate it from the last one
+		m, n := mentionAt(text, i, msg.Crew)
+		if n == 0 {
+			m, n = selfAt(text, i, msg.Sender)
+		}
+		if n > 0 {
+			joined := len(cur.members) == 0 || len(cur.windows) == 0 && onlyConnectors(text[lastName:i])
+			if !joined {
+				subjects = append(subjects, subject{})
+				cur = &subjects[len(subjects)-1]
+			}
+			if !hasMember(cur.members, m) {
+				cur.members = append(cur.members, m)
+			}
+			i, lastName = i+n, i+n
+			continue
+		}
+		if same := msg.Crew.sharing(text[i]); len(same) > 1 {
+			joined := len(cur.members) == 0 || len(cur.windows) == 0 && onlyConnectors(text[lastName:i])
+			if !joined {
+				subjects = append(subjects, subject{})
+				cur = &subjects[len(subjects)-1]
+			}
+			m := Member{Name: firstWord(same[0].Name)}
+			if !hasMember(cur.members, m) {
+				cur.members = append(cur.members, m)
+			}
+			if cur.namesakes == nil {
+				cur.namesakes = make(map[string][]Member)
+			}
+			cur.namesakes[m.Name] = same
+			i, lastName = i+1, i+1
+			continue
+		}
+
+		// 2. A date or a range of dates: dates the last window if it has none yet, picks the
+		// week of its weekday ("Monday morning next week"), else opens a new one
+		if days, n := dateAt(text, i, today); n > 0 {
+			last := len(cur.windows) - 1
+			switch {
+			case last >= 0 && cur.windows[last].days == nil:
+				cur.windows[last].days = days
+			case last >= 0 && lastDate > lastName && !anyConnector(text[lastDate:i]) && cur.requalify(days):
+				cur.week = true
+			default:
+				cur.windows = append(cur.windows, window{days: days})
+			}
+			i += n
+			lastDate = i
+			continue
+		}
+
+		// 3. A day part or a clock time
+		if start, end, n := periodAt(text, i); n > 0 {
+			cur.setHours(start, end)
+			i += n
+			continue
+		}
+		if start, end, n := clockAt(text, i); n > 0 {
+			cur.setHours(start, end)
+			i += n
+			continue
+		}
+
+		// 4. Any other word may turn the subject around. An offer after a negation is none:
+		// "isn't available" is kept as "not available"
+		if cur.said == nil {
+			cur.said = make(map[string]bool)
+		}
+		if cur.undated == "" && dateLike(text[i]) {
+			cur.undated = text[i]
+		}
+		if offerWords[text[i]] && negated(text, i) {
+			cur.said["not "+text[i]] = true
+		} else {
+			cur.said[text[i]] = true
+		}
+		i++
+	}
+
+	// Windows named before anyone belong to the first person named
+	if len(subjects) > 1 && len(subjects[0].members) == 0 {
+		subjects[1].windows = append(subjects[0].windows, subjects[1].windows...)
+		if subjects[1].undated == "" {
+			subjects[1].undated = subjects[0].undated
+		}
+		subjects = subjects[1:]
+	}
+
+	// "Can only work mornings": off the rest of the day
+	for k := range subjects {
+		s := &subjects[k]
+		s.only = s.said["only"] && !anyOf(s.said, negativeWords) && anyOf(s.said, availableWords)
+		if s.only {
+			s.windows = unavailable(s.windows, today, s.week)
+		}
+		s.intent = classify(*s)
+	}
+	return subjects
+}
+
+// unavailable turns the windows someone can work into the windows they cannot, within
+// opening hours. Undated windows are every day. Named days with no hours, or in a named
+// week, leave the other days of their weeks off, from today on.
+func unavailable(free []window, today time.Time, week bool) []window {
+	// 1. The hours they can work, per day (the zero day is every day)
+	open := make(map[time.Time]*[24]bool)
+	var order []time.Time
+	mark := func(day time.Time, start, end int) {
+		if open[day] == nil {
+			open[day] = new([24]bool)
+			order = append(order, day)
+		}
+		for h := start; h < end; h++ {
+			open[day][h] = true
+		}
+	}
+	dated := len(free) > 0 // Named days: "only" is about the days when none has hours
+	allDay := true
+	for _, w := range free {
+		allDay = allDay && !w.hasTime
+		start, end := w.start, w.end
+		if !w.hasTime {
+			start, end = dayStart, dayEnd
+		}
+		if w.days == nil {
+			dated = false
+			mark(time.Time{}, start, end)
+		}
+		for _, day := range w.days {
+			mark(day, start, end)
+		}
+	}
+
+	// 2. The other days of the weeks named
+	if dated && (allDay || week) {
+		for _, day := range append([]time.Time(nil), order...) {
+			monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+			for k := 0; k < 7; k++ {
+				if other := monday.AddDate(0, 0, k); !other.Before(today) {
+					mark(other, 0, 0)
+				}
+			}
+		}
+		sort.Slice(order, func(a, b int) bool { return order[a].Before(order[b]) })
+	}
+
+	// 3. The rest of the opening hours
+	var off []window
+	for _, day := range order {
+		var days []time.Time
+		if !day.IsZero() {
+			days = []time.Time{day}
+		}
+		for h := dayStart; h < dayEnd; h++ {
+			if open[day][h] {
+				continue
+			}
+			end := h
+			for end < dayEnd && !open[day][end] {
+				end++
+			}
+			off = append(off, window{days: days, start: h, end: end, hasTime: true})
+			h = end
+		}
+	}
+	return off
+}
+
+// constraints expands subjects into one constraint per member per window, flagging
+// shared names, missing hours and the vague words of text
+func constraints(subjects []subject, text string) []Constraint {
+	vagueTime, vagueDay := firstOf(words(text), vagueTimes), firstOf(words(text), vagueDays)
+	var found []Constraint
+	seen := make(map[string]bool)
+	for _, s := range subjects {
+		// No window is a guess to ask about, unless they are free all day
+		windows := s.windows
+		if len(windows) == 0 && !s.only {
+			windows = []window{{}}
+		}
+		why := reason(text)
+		if s.only {
+			why = "Limited availability"
+		}
+		members := s.members
+		var with Member
+		if s.intent == IntentSwap {
+			members, with = s.members[:1], s.members[1]
+		}
+		for _, m := range members {
+			for _, w := range windows {
+				if w.hasTime && w.start >= w.end {
+					continue // Outside opening hours: nothing to block
+				}
+				dates := w.days
+				if dates == nil {
+					dates = []time.Time{{}}
+				}
+				// A date was meant but not read ("off the 32nd"): never every day unasked
+				unread := w.days == nil && s.undated != ""
+				for _, day := range dates {
+					c := Constraint{Unavailability: models.Unavailability{EmployeeID: m.ID, EmployeeName: m.Name,
+						Date: day, StartHour: w.start, EndHour: w.end, Reason: why}, Intent: s.intent, SwapWith: with}
+					switch {
+					case w.hasTime && w.days != nil:
+						c.Confidence = 0.9
+					case w.hasTime:
+						c.Confidence = 0.8 // No day: every day
+					case w.days != nil:
+						c.StartHour, c.EndHour, c.Confidence = 8, 20, 0.7 // A day with no time: the whole day
+					default:
+						c.StartHour, c.EndHour, c.Confidence = 9, 10, 0.3 // Neither: a one-hour guess
+					}
+					if unread {
+						c.Confidence = min(c.Confidence, 0.4)
+					}
+
+					// What to ask before saving, the person first
+					if m.ID == 0 {
+						c.Unclear = append(c.Unclear, Ambiguity{Field: FieldEmployee, Text: m.Name, Options: s.namesakes[m.Name]})
+					}
+					if with.Name != "" && with.ID == 0 {
+						c.Unclear = append(c.Unclear, Ambiguity{Field: FieldEmployee, Text: with.Name, Options: s.namesakes[with.Name]})
+					}
+					if w.days == nil && (vagueDay != "" || unread || s.intent != IntentUnavailable) { // Only unavailability is every day
+						day := vagueDay
+						if day == "" {
+							day = s.undated
+						}
+						c.Unclear = append(c.Unclear, Ambiguity{Field: FieldDate, Text: day})
+					}
+					if !w.hasTime && (vagueTime != "" || w.days == nil) {
+						c.Unclear = append(c.Unclear, Ambiguity{Field: FieldTime, Text: vagueTime})
+					}
+					key := fmt.Sprint(c.EmployeeID, c.EmployeeName, c.Date, c.StartHour, c.EndHour)
+					if !seen[key] {
+						seen[key] = true
+						found = append(found, c)
+					}
+				}
+			}
+		}
+	}
+	return found
+}
+
+// reason is a dentist appointment or personal
+func reason(input string) string {
+	if strings.Contains(strings.ToLower(input), "dentist") {
+		return "Medical (Dentist)"
+	}
+	return "Personal"
+}
+
+// mentionAt finds the crew member named at text[i], the longest name or alias winning
+// ("Mary Ann" over "Mary"), and how many words the name takes
+func mentionAt(text []string, i int, crew Crew) (Member, int) {
+	best, length := Member{}, 0
+	for _, m := range crew {
+		for _, name := range append([]string{m.Name}, m.Aliases...) {
+			phrase := words(name)
+			if len(phrase) > length && hasPrefix(text[i:], phrase) {
+				best, length = m, len(phrase)
+			}
+		}
+	}
+	return best, length
+}
+
+// selfWords are how a sender names themselves
+var selfWords = map[string]bool{"i": true, "me": true, "my": true, "myself": true, "i'm": true, "im": true, "i'll": true, "i've": true, "i'd": true}
+
+// selfAt recognises the sender naming themselves at text[i]: "I", "me", "I'm"
+func selfAt(text []string, i int, sender Member) (Member, int) {
+	if sender.ID == 0 || !selfWords[text[i]] {
+		return Member{}, 0
+	}
+	return sender, 1
+}
+
+// periodAt recognises a day part at text[i]
+func periodAt(text []string, i int) (start, end, n int) {
+	for _, p := range periods {
+		if hasPrefix(text[i:], p.words) {
+			return p.start, p.end, len(p.words)
+		}
+	}
+	return 0, 0, 0
+}
+
+func hasPrefix(text, phrase []string) bool {
+	if len(phrase) == 0 || len(phrase) > len(text) {
+		return false
+	}
+	for j, w := range phrase {
+		if text[j] != w {
+			return false
+		}
+	}
+	return true
+}
+
+func anyConnector(between []string) bool {
+	for _, w := range between {
+		if connectors[w] {
+			return true
+		}
+	}
+	return false
+}
+
+func onlyConnectors(between []string) bool {
+	for _, w := range between {
+		if !connectors[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasMember compares IDs, and names for the unresolved members that have none
+func hasMember(members []Member, m Member) bool {
+	for _, have := range members {
+		if have.ID == m.ID && (m.ID != 0 || have.Name == m.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyOf(said, set map[string]bool) bool {
+	for w := range set {
+		if said[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// firstOf is the first word of text in set, or ""
+func firstOf(text []string, set map[string]bool) string {
+	for _, w := range text {
+		if set[w] {
+			return w
+		}
+	}
+	return ""
+}
+
+// words splits lower-cased text into letters-and-digits words, dropping a trailing "'s".
+// Curly apostrophes, as phones type them, are straight ones ("can’t" is can't). ISO dates and clock times ("14:30", "9:15am") stay whole; any other dash is a word of
+// its own ("mon-wed" is mon, -, wed).
+func words(s string) []string {
+	s = strings.ReplaceAll(strings.ToLower(s), "\u2019", "'")
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '\'' || r == '-' || r == ':' || r > 0x7f)
+	})
+	var out []string
+	for _, f := range fields {
+		if _, err := time.Parse("2006-01-02", f); err == nil || clockToken.MatchString(f) {
+			out = append(out, f)
+			continue
+		}
+		for _, piece := range strings.Split(f, ":") {
+			for i, part := range strings.Split(piece, "-") {
+				if i > 0 {
+					out = append(out, "-")
+				}
+				if part = strings.TrimSuffix(strings.Trim(part, "'"), "'s"); part != "" {
+					out = append(out, part)
+				}
+			}
+		}
+	}
+	return out
+}
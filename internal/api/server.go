@@ -0,0 +1,1212 @@
+// Package api is the HTTP/JSON interface to ShiftOpt (served by cmd/shiftoptd).
+// The endpoints are documented in openapi.yaml, which is also served at /openapi.yaml.
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/dashboard"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/importer"
+	"github.com/iannsp/shiftopt/internal/inbound"
+	"github.com/iannsp/shiftopt/internal/jobs"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/payroll"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+	"github.com/iannsp/shiftopt/internal/weekly"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// Server routes the API requests to a Store, and scheduling runs to a job Pool
+type Server struct {
+	store  database.Store
+	pool   *jobs.Pool
+	parser ai.ConstraintParser // Reads free-text messages (default: the offline rules)
+	mux    *http.ServeMux
+
+	twilioToken string // Checks the signature of POST /inbound/sms; without it the webhook is refused
+	smsURL      string // The webhook URL the gateway signs (default: read from the request)
+	unsignedSMS bool   // Accept POST /inbound/sms unsigned when there is no token (local testing)
+}
+
+// NewServer builds the API on top of an unscoped Store. The pool must be started
+// by the caller for queued runs to make progress.
+// Every endpoint accepts ?location=<name> to work on one store only.
+func NewServer(store database.Store, pool *jobs.Pool) *Server {
+	s := &Server{store: store, pool: pool, parser: ai.RuleParser{}, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /openapi.yaml", s.handleSpec)
+	s.mux.HandleFunc("GET /strategies", s.handleStrategies)
+
+	s.mux.HandleFunc("GET /employees", s.handleListEmployees)
+	s.mux.HandleFunc("POST /employees", s.handleAddEmployee)
+	s.mux.HandleFunc("PUT /employees/{id}", s.handleUpdateEmployee)
+	s.mux.HandleFunc("DELETE /employees/{id}", s.handleDeactivateEmployee)
+
+	s.mux.HandleFunc("GET /demand", s.handleGetDemand)
+	s.mux.HandleFunc("PUT /demand", s.handleReplaceDemand)
+
+	s.mux.HandleFunc("GET /unavailability", s.handleListUnavailability)
+	s.mux.HandleFunc("POST /unavailability", s.handleAddUnavailability)
+	s.mux.HandleFunc("POST /unavailability/messages", s.handleUnavailabilityMessage)
+	s.mux.HandleFunc("GET /unavailability/pending", s.handleListPending)
+	s.mux.HandleFunc("POST /unavailability/pending/{id}/answer", s.handleAnswerPending)
+	s.mux.HandleFunc("GET /ai/stats", s.handleAIStats)
+
+	s.mux.HandleFunc("POST /inbound/sms", s.handleInboundSMS)
+	s.mux.HandleFunc("GET /inbound", s.handleListInbound)
+	s.mux.HandleFunc("GET /contacts", s.handleListContacts)
+	s.mux.HandleFunc("POST /contacts", s.handleAddContact)
+	s.mux.HandleFunc("DELETE /contacts/{id}", s.handleRemoveContact)
+
+	s.mux.HandleFunc("GET /requests/time-off", s.handleListTimeOff)
+	s.mux.HandleFunc("GET /requests/swaps", s.handleListSwaps)
+	s.mux.HandleFunc("POST /requests/{kind}/{id}/{decision}", s.handleDecideRequest)
+
+	s.mux.HandleFunc("POST /commands", s.handleCommand)
+
+	s.mux.HandleFunc("POST /runs", s.handleRun)
+	s.mux.HandleFunc("GET /jobs", s.handleListJobs)
+	s.mux.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+	s.mux.HandleFunc("POST /jobs/{id}/cancel", s.handleCancelJob)
+
+	s.mux.HandleFunc("GET /rosters", s.handleListRosters)
+	s.mux.HandleFunc("GET /rosters/{id}", s.handleGetRoster)
+	s.mux.HandleFunc("POST /rosters/{id}/publish", s.handlePublishRoster)
+	s.mux.HandleFunc("POST /rosters/import", s.handleImportRoster)
+	s.mux.HandleFunc("GET /rosters/{id}/dashboard", s.handleRosterDashboard)
+	s.mux.HandleFunc("GET /rosters/{id}/calendar", s.handleRosterCalendar)
+	s.mux.HandleFunc("GET /dashboard", s.handleDashboard)
+	s.mux.HandleFunc("GET /week", s.handleWeek)
+	s.mux.HandleFunc("GET /payroll", s.handlePayroll)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// --- Helpers ---
+
+// apiError is the body of every non-2xx response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, apiError{Error: fmt.Sprintf(format, args...)})
+}
+
+// writeStoreError maps a Store failure: missing rows are 404, everything else is 500
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "%v", err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "%v", err)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, into any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(into); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: %v", err)
+		return false
+	}
+	return true
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id %q", r.PathValue("id"))
+		return 0, false
+	}
+	return id, true
+}
+
+// locationID resolves the ?location= filter (0 = the whole database)
+func (s *Server) locationID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	name := r.URL.Query().Get("location")
+	if name == "" {
+		return 0, true
+	}
+	l, err := s.store.LocationByName(name)
+	if err != nil {
+		writeStoreError(w, err)
+		return 0, false
+	}
+	return l.ID, true
+}
+
+// localDay is today at the store (0 = the main store's zone), as a date
+func (s *Server) localDay(locationID int) (time.Time, error) {
+	now, err := database.LocalNow(s.store, locationID)
+	if err != nil {
+		return now, err
+	}
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+}
+
+// storeFor applies the ?location= filter
+func (s *Server) storeFor(w http.ResponseWriter, r *http.Request) (database.Store, bool) {
+	id, ok := s.locationID(w, r)
+	if !ok || id == 0 {
+		return s.store, ok
+	}
+	return s.store.AtLocation(id), true
+}
+
+// --- Handlers ---
+
+func (s *Server) handleSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+func (s *Server) handleStrategies(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"strategies": scheduler.StrategyNames(),
+		"default":    scheduler.DefaultStrategy,
+	})
+}
+
+func (s *Server) handleListEmployees(w http.ResponseWriter, r *http.Request) {
+	store, ok := s.storeFor(w, r)
+	if !ok {
+		return
+	}
+	list := store.Employees
+	if r.URL.Query().Get("all") == "true" {
+		list = store.AllEmployees
+	}
+	employees, err := list()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out := []employeeJSON{}
+	for _, e := range employees {
+		out = append(out, toEmployee(e))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleAddEmployee(w http.ResponseWriter, r *http.Request) {
+	store, ok := s.storeFor(w, r)
+	if !ok {
+		return
+	}
+	var in employeeJSON
+	if !decode(w, r, &in) {
+		return
+	}
+	e := in.model()
+	id, err := store.AddEmployee(e)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	e.ID, e.Active = id, true
+	writeJSON(w, http.StatusCreated, toEmployee(e))
+}
+
+func (s *Server) handleUpdateEmployee(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	store, ok := s.storeFor(w, r)
+	if !ok {
+		return
+	}
+	var in employeeJSON
+	if !decode(w, r, &in) {
+		return
+	}
+	in.ID = id
+	if err := store.UpdateEmployee(in.model()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeStoreError(w, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	employees, err := s.store.AllEmployees()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	for _, e := range employees {
+		if e.ID == id {
+			writeJSON(w, http.StatusOK, toEmployee(e))
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "employee %d not found", id)
+}
+
+func (s *Server) handleDeactivateEmployee(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.store.DeactivateEmployee(id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleGetDemand(w http.ResponseWriter, r *http.Request) {
+	store, ok := s.storeFor(w, r)
+	if !ok {
+		return
+	}
+	demands, err := store.Demands()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out := []demandJSON{}
+	for _, d := range demands {
+		out = append(out, demandJSON{HourOfDay: d.HourOfDay, Needed: d.Needed})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleReplaceDemand(w http.ResponseWriter, r *http.Request) {
+	store, ok := s.storeFor(w, r)
+	if !ok {
+		return
+	}
+	var in []demandJSON
+	if !decode(w, r, &in) {
+		return
+	}
+	seen := make(map[int]bool)
+	var demands []models.Demand
+	for i, d := range in {
+		if d.HourOfDay < 0 || d.HourOfDay > 23 || d.Needed < 0 || seen[d.HourOfDay] {
+			writeError(w, http.StatusBadRequest, "item %d: hour_of_day must be a unique 0-23 and needed >= 0", i)
+			return
+		}
+		seen[d.HourOfDay] = true
+		demands = append(demands, models.Demand{HourOfDay: d.HourOfDay, Needed: d.Needed})
+	}
+	if err := store.ReplaceDemands(demands); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, in)
+}
+
+func (s *Server) handleListUnavailability(w http.ResponseWriter, r *http.Request) {
+	store := s.store
+	if raw := r.URL.Query().Get("date"); raw != "" {
+		day, err := time.Parse(database.DateLayout, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid date %q (want YYYY-MM-DD)", raw)
+			return
+		}
+		store = store.OnDay(day)
+	}
+	blocks, err := store.Unavailability()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out := []unavailabilityJSON{}
+	for _, u := range blocks {
+		out = append(out, toUnavailability(u))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleAddUnavailability(w http.ResponseWriter, r *http.Request) {
+	store, ok := s.storeFor(w, r)
+	if !ok {
+		return
+	}
+	var in unavailabilityJSON
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.StartHour < 0 || in.EndHour > 24 || in.EndHour <= in.StartHour {
+		writeError(w, http.StatusBadRequest, "need 0 <= start_hour < end_hour <= 24")
+		return
+	}
+	var day time.Time
+	if in.Date != "" {
+		parsed, err := time.Parse(database.DateLayout, in.Date)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid date %q (want YYYY-MM-DD)", in.Date)
+			return
+		}
+		day = parsed
+	}
+	if in.EmployeeID == 0 {
+		id, err := store.EmployeeIDByName(in.Employee)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "employee %q not found", in.Employee)
+			return
+		}
+		in.EmployeeID = id
+	}
+	err := store.AddUnavailability(models.Unavailability{EmployeeID: in.EmployeeID, EmployeeName: in.Employee,
+		StartHour: in.StartHour, EndHour: in.EndHour, Reason: in.Reason, Date: day})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, in)
+}
+
+// WithParser replaces the parser that reads POST /unavailability/messages
+func (s *Server) WithParser(p ai.ConstraintParser) *Server {
+	s.parser = p
+	return s
+}
+
+// WithTwilio makes POST /inbound/sms take requests with a valid X-Twilio-Signature.
+// webhookURL is the URL configured at the gateway ("" = the one the request was sent to).
+// Without it the webhook refuses every request, unless WithUnsignedSMS.
+func (s *Server) WithTwilio(authToken, webhookURL string) *Server {
+	s.twilioToken, s.smsURL = authToken, webhookURL
+	return s
+}
+
+// WithUnsignedSMS makes POST /inbound/sms take unsigned requests when there is no Twilio
+// auth token. Anyone who can reach the server can then text as any employee: local testing only.
+func (s *Server) WithUnsignedSMS() *Server {
+	s.unsignedSMS = true
+	return s
+}
+
+// handleInboundSMS is the SMS gateway's webhook: the sender's number names the employee,
+// and the reply is sent back to them as TwiML
+func (s *Server) handleInboundSMS(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid form: %v", err)
+		return
+	}
+	switch {
+	case s.twilioToken == "" && !s.unsignedSMS:
+		writeError(w, http.StatusForbidden, "SMS webhook disabled: no Twilio auth token configured")
+		return
+	case s.twilioToken != "":
+		webhookURL := s.smsURL
+		if webhookURL == "" {
+			scheme := "http"
+			if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+				scheme = "https"
+			}
+			webhookURL = scheme + "://" + r.Host + r.URL.RequestURI()
+		}
+		if !inbound.ValidTwilioSignature(s.twilioToken, webhookURL, r.PostForm, r.Header.Get("X-Twilio-Signature")) {
+			writeError(w, http.StatusForbidden, "invalid X-Twilio-Signature")
+			return
+		}
+	}
+	m, err := inbound.FromTwilio(r.PostForm)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	today, err := database.LocalNow(s.store, 0)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	reply := ""
+	record, outcome, err := inbound.Receive(r.Context(), s.store, s.parser, m, today)
+	switch {
+	case errors.Is(err, inbound.ErrDuplicate): // Already answered
+	case err != nil:
+		writeStoreError(w, err)
+		return
+	default:
+		reply = inbound.Reply(record, outcome)
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write(inbound.TwiML(reply))
+}
+
+func (s *Server) handleListInbound(w http.ResponseWriter, r *http.Request) {
+	messages, err := s.store.InboundMessages()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out := []inboundJSON{}
+	for _, m := range messages {
+		out = append(out, toInbound(m))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleListContacts(w http.ResponseWriter, r *http.Request) {
+	store, ok := s.storeFor(w, r)
+	if !ok {
+		return
+	}
+	contacts, err := store.Contacts()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out := []contactJSON{}
+	for _, c := range contacts {
+		out = append(out, contactJSON{ID: c.ID, EmployeeID: c.EmployeeID, Employee: c.EmployeeName, Address: c.Address})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleAddContact(w http.ResponseWriter, r *http.Request) {
+	var in contactJSON
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.EmployeeID == 0 && in.Employee != "" {
+		id, err := s.store.EmployeeIDByName(in.Employee)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "employee %q not found", in.Employee)
+			return
+		}
+		in.EmployeeID = id
+	}
+	id, err := s.store.AddContact(models.Contact{EmployeeID: in.EmployeeID, Address: in.Address})
+	switch {
+	case errors.Is(err, database.ErrContactTaken):
+		writeError(w, http.StatusConflict, "%v", err)
+		return
+	case errors.Is(err, sql.ErrNoRows):
+		writeStoreError(w, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	in.ID = id
+	writeJSON(w, http.StatusCreated, in)
+}
+
+func (s *Server) handleRemoveContact(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.store.RemoveContact(id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAIStats reports how the language models did since the server started
+func (s *Server) handleAIStats(w http.ResponseWriter, r *http.Request) {
+	stats := ai.ReadStats()
+	writeJSON(w, http.StatusOK, statsJSON{Parses: stats.Parses, Fallbacks: stats.Fallbacks, FallbackRate: stats.FallbackRate(),
+		CacheHits: stats.CacheHits, ModelCalls: stats.ModelCalls, InvalidAnswers: stats.InvalidAnswers})
+}
+
+func (s *Server) handleUnavailabilityMessage(w http.ResponseWriter, r *http.Request) {
+	locationID, ok := s.locationID(w, r)
+	if !ok {
+		return
+	}
+	store := s.store
+	if locationID != 0 {
+		store = store.AtLocation(locationID)
+	}
+	var in messageJSON
+	if !decode(w, r, &in) {
+		return
+	}
+	if strings.TrimSpace(in.Text) == "" {
+		writeError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+	today, err := database.LocalNow(s.store, locationID) // Relative dates count from the store's calendar
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if in.Today != "" {
+		parsed, err := time.Parse(database.DateLayout, in.Today)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid today %q (want YYYY-MM-DD)", in.Today)
+			return
+		}
+		today = parsed
+	}
+
+	senderID := 0
+	if in.From != "" {
+		if senderID, err = store.EmployeeIDByName(in.From); err != nil {
+			writeError(w, http.StatusNotFound, "employee %q not found", in.From)
+			return
+		}
+	}
+
+	outcome, err := ai.RecordFrom(r.Context(), store, s.parser, senderID, in.Text, today)
+	switch {
+	case errors.Is(err, ai.ErrUnknownEmployee), errors.Is(err, ai.ErrNothingFound):
+		writeError(w, http.StatusUnprocessableEntity, "%v", err)
+		return
+	case err != nil:
+		writeStoreError(w, err)
+		return
+	}
+	writeOutcome(w, in.Text, outcome)
+}
+
+func (s *Server) handleListPending(w http.ResponseWriter, r *http.Request) {
+	pending, err := s.store.PendingMessages()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out := []pendingJSON{}
+	for _, p := range pending {
+		out = append(out, pendingJSON{ID: p.ID, Text: p.Text, Question: p.Question, CreatedAt: &p.CreatedAt})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleAnswerPending(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var in messageJSON
+	if !decode(w, r, &in) {
+		return
+	}
+	if strings.TrimSpace(in.Text) == "" {
+		writeError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+	p, err := s.store.Pending(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	today, err := database.LocalNow(s.store, models.DefaultLocation)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if in.Today != "" {
+		if today, err = time.Parse(database.DateLayout, in.Today); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid today %q (want YYYY-MM-DD)", in.Today)
+			return
+		}
+	}
+	outcome, err := ai.Answer(s.store, id, in.Text, today)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeOutcome(w, p.Text, outcome)
+}
+
+// writeOutcome answers 201 with the saved constraints, or 202 with the question to ask
+func writeOutcome(w http.ResponseWriter, text string, outcome ai.Outcome) {
+	found := []constraintJSON{}
+	for _, c := range outcome.Constraints {
+		out := constraintJSON{unavailabilityJSON: toUnavailability(c.Unavailability), Intent: c.Intent,
+			SwapWith: c.SwapWith.Name, Confidence: c.Confidence}
+		if out.Intent == "" {
+			out.Intent = ai.IntentUnavailable
+		}
+		for _, a := range c.Unclear {
+			out.Unclear = append(out.Unclear, a.Field)
+		}
+		found = append(found, out)
+	}
+	if outcome.Held() {
+		writeJSON(w, http.StatusAccepted, pendingJSON{ID: outcome.PendingID, Text: text, Question: outcome.Question, Constraints: found})
+		return
+	}
+	writeJSON(w, http.StatusCreated, found)
+}
+
+// handleCommand previews a manager command ("what if Bob is off Friday?"), and saves it
+// when apply is set
+func (s *Server) handleCommand(w http.ResponseWriter, r *http.Request) {
+	locationID, ok := s.locationID(w, r)
+	if !ok {
+		return
+	}
+	store := s.store
+	if locationID != 0 {
+		store = store.AtLocation(locationID)
+	}
+	var in commandRequest
+	if !decode(w, r, &in) {
+		return
+	}
+	if strings.TrimSpace(in.Text) == "" {
+		writeError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+	today, err := database.LocalNow(s.store, locationID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if in.Today != "" {
+		if today, err = time.Parse(database.DateLayout, in.Today); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid today %q (want YYYY-MM-DD)", in.Today)
+			return
+		}
+	}
+
+	plan, err := ai.Preview(r.Context(), store, in.Text, today)
+	var conflict *scheduler.LockConflictError
+	switch {
+	case errors.Is(err, ai.ErrUnknownCommand), errors.Is(err, ai.ErrUnknownEmployee):
+		writeError(w, http.StatusUnprocessableEntity, "%v", err)
+		return
+	case errors.As(err, &conflict):
+		writeError(w, http.StatusConflict, "%v", err)
+		return
+	case err != nil:
+		writeStoreError(w, err)
+		return
+	}
+	out := toPlan(plan)
+	if !in.Apply {
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+	if plan.Change.Empty() {
+		writeError(w, http.StatusBadRequest, "nothing to apply: %q only reads the roster", plan.Command.Kind)
+		return
+	}
+	if in.Author == "" {
+		in.Author = "api"
+	}
+	if out.Versions, err = ai.Apply(store, plan, in.Author); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out.Applied = true
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (s *Server) handleListTimeOff(w http.ResponseWriter, r *http.Request) {
+	requests, err := s.store.TimeOffRequests(r.URL.Query().Get("status"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out := []timeOffJSON{}
+	for _, t := range requests {
+		out = append(out, toTimeOff(t))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleListSwaps(w http.ResponseWriter, r *http.Request) {
+	requests, err := s.store.SwapRequests(r.URL.Query().Get("status"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out := []swapJSON{}
+	for _, sw := range requests {
+		out = append(out, toSwap(sw))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleDecideRequest approves or denies a time-off or swap request:
+// POST /requests/time-off/3/approve, POST /requests/swaps/4/deny
+func (s *Server) handleDecideRequest(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	status, ok := map[string]string{"approve": models.RequestApproved, "deny": models.RequestDenied}[r.PathValue("decision")]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid decision %q (want approve or deny)", r.PathValue("decision"))
+		return
+	}
+	var err error
+	switch r.PathValue("kind") {
+	case "time-off":
+		err = s.store.DecideTimeOff(id, status)
+	case "swaps":
+		err = s.store.DecideSwap(id, status)
+	default:
+		writeError(w, http.StatusNotFound, "unknown request kind %q (want time-off or swaps)", r.PathValue("kind"))
+		return
+	}
+	switch {
+	case errors.Is(err, database.ErrRequestDecided):
+		writeError(w, http.StatusConflict, "%v", err)
+	case err != nil:
+		writeStoreError(w, err)
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{"id": id, "status": status})
+	}
+}
+
+// handleRun queues a run for one store: the default store without ?location=
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	locationID, ok := s.locationID(w, r)
+	if !ok {
+		return
+	}
+	if locationID == 0 {
+		locationID = models.DefaultLocation
+	}
+	var in runRequest
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Strategy == "" {
+		in.Strategy = scheduler.DefaultStrategy
+	}
+	if _, found := scheduler.LookupStrategy(in.Strategy); !found {
+		writeError(w, http.StatusBadRequest, "unknown strategy %q (known: %v)", in.Strategy, scheduler.StrategyNames())
+		return
+	}
+	now, err := database.LocalNow(s.store, locationID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	y, m, d := now.Date() // Today at the store
+	date := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if in.Date != "" {
+		parsed, err := time.Parse(database.DateLayout, in.Date)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid date %q (want YYYY-MM-DD)", in.Date)
+			return
+		}
+		date = parsed
+	}
+	if in.Author == "" {
+		in.Author = "api"
+	}
+
+	j, err := s.pool.Submit(models.Job{Strategy: in.Strategy, LocationID: locationID, Date: date, Author: in.Author})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/jobs/%d", j.ID))
+	writeJSON(w, http.StatusAccepted, toJob(j))
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	store, ok := s.storeFor(w, r)
+	if !ok {
+		return
+	}
+	list, err := store.Jobs()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out := []jobJSON{}
+	for _, j := range list {
+		out = append(out, toJob(j))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	j, err := s.store.Job(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toJob(j))
+}
+
+// handleCancelJob withdraws a queued job or stops a running one.
+// A running job stops at its next hour (or its worker's next check, in another process),
+// so poll until the status is cancelled.
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.pool.Cancel(id); err != nil {
+		if errors.Is(err, database.ErrJobNotQueued) {
+			writeError(w, http.StatusConflict, "%v", err)
+			return
+		}
+		writeStoreError(w, err)
+		return
+	}
+	j, err := s.store.Job(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, toJob(j))
+}
+
+func (s *Server) handleListRosters(w http.ResponseWriter, r *http.Request) {
+	store, ok := s.storeFor(w, r)
+	if !ok {
+		return
+	}
+	versions, err := store.ListRosterVersions()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out := []versionJSON{}
+	for _, v := range versions {
+		out = append(out, toVersion(v))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// exportTypes are the file formats served by GET /rosters/{id} besides the API's own JSON
+var exportTypes = map[string]string{
+	"csv":  "text/csv",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// handleGetRoster returns JSON, or a file with ?format=csv|xlsx (or Accept: text/csv).
+// Files take the exporter's ?layout= and comma-separated ?columns=.
+func (s *Server) handleGetRoster(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" && r.Header.Get("Accept") == "text/csv" {
+		format = "csv"
+	}
+	contentType, isFile := exportTypes[format]
+	if format != "" && format != "json" && !isFile {
+		writeError(w, http.StatusBadRequest, "unknown format %q (want json, csv or xlsx)", format)
+		return
+	}
+
+	var exporter scheduler.Exporter
+	if isFile {
+		opts := scheduler.ExportOptions{Layout: q.Get("layout")}
+		if q.Has("columns") {
+			opts.Columns = []string{}
+			for _, c := range strings.Split(q.Get("columns"), ",") {
+				if c = strings.TrimSpace(c); c != "" {
+					opts.Columns = append(opts.Columns, c)
+				}
+			}
+		}
+		e, err := scheduler.NewExporter(format, opts)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		exporter = e
+	}
+
+	roster, v, err := s.store.LoadRoster(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !isFile {
+		writeJSON(w, http.StatusOK, toRoster(roster, v))
+		return
+	}
+
+	// Build the file first: once the body starts, an error can no longer become a status code
+	var body bytes.Buffer
+	if err := exporter.Export(roster, &body); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=roster-%d.%s", id, format))
+	w.Write(body.Bytes())
+}
+
+func (s *Server) handlePublishRoster(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.store.PublishRoster(id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	_, v, err := s.store.LoadRoster(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toVersion(v))
+}
+
+// handleDashboard shows the latest roster of the ?location= scope
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	store, ok := s.storeFor(w, r)
+	if !ok {
+		return
+	}
+	s.writeDashboard(w, r, store, 0)
+}
+
+func (s *Server) handleRosterDashboard(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	s.writeDashboard(w, r, s.store, id)
+}
+
+// writeDashboard renders the HTML page, with the budget taken from ?budget=
+func (s *Server) writeDashboard(w http.ResponseWriter, r *http.Request, store database.Store, versionID int) {
+	budget := 0.0
+	if raw := r.URL.Query().Get("budget"); raw != "" {
+		b, err := strconv.ParseFloat(raw, 64)
+		if err != nil || b < 0 {
+			writeError(w, http.StatusBadRequest, "invalid budget %q", raw)
+			return
+		}
+		budget = b
+	}
+
+	page, err := dashboard.Load(store, versionID, budget)
+	if errors.Is(err, dashboard.ErrNoRoster) {
+		writeError(w, http.StatusNotFound, "%v", err)
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	dashboard.Render(w, page)
+}
+
+// handleRosterCalendar returns the roster as iCalendar, for one employee with ?employee=<id>
+func (s *Server) handleRosterCalendar(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	employeeID := 0
+	if raw := r.URL.Query().Get("employee"); raw != "" {
+		e, err := strconv.Atoi(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid employee %q", raw)
+			return
+		}
+		employeeID = e
+	}
+
+	roster, v, err := s.store.LoadRoster(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	locations, err := s.store.Locations()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	opts := scheduler.ICSOptions{Stamp: v.CreatedAt}
+	for _, l := range locations {
+		if l.ID == v.LocationID {
+			opts.Location = l.Name
+		}
+	}
+
+	var body bytes.Buffer
+	if err := scheduler.WriteICS(roster, employeeID, opts, &body); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=roster-%d.ics", id))
+	w.Write(body.Bytes())
+}
+
+// handleWeek renders the weekly grid holding ?start= (default: this week) as HTML, or PDF with ?format=pdf
+func (s *Server) handleWeek(w http.ResponseWriter, r *http.Request) {
+	locationID, ok := s.locationID(w, r)
+	if !ok {
+		return
+	}
+	store := s.store
+	if locationID != 0 {
+		store = store.AtLocation(locationID)
+	}
+	day, err := s.localDay(locationID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if raw := r.URL.Query().Get("start"); raw != "" {
+		d, err := time.Parse(database.DateLayout, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid start %q (want yyyy-mm-dd)", raw)
+			return
+		}
+		day = d
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "html" && format != "pdf" {
+		writeError(w, http.StatusBadRequest, "unknown format %q (want html or pdf)", format)
+		return
+	}
+
+	week, err := weekly.Load(store, day)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	render, contentType := weekly.RenderHTML, "text/html; charset=utf-8"
+	if format == "pdf" {
+		render, contentType = weekly.RenderPDF, "application/pdf"
+	}
+	var body bytes.Buffer
+	if err := render(&body, week); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if format == "pdf" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=week-%s.pdf", week.Start.Format(database.DateLayout)))
+	}
+	w.Write(body.Bytes())
+}
+
+// handlePayroll returns the payroll CSV for the days ?from= to ?to= (both inclusive)
+func (s *Server) handlePayroll(w http.ResponseWriter, r *http.Request) {
+	store, ok := s.storeFor(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	from, err := time.Parse(database.DateLayout, q.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid from %q (want yyyy-mm-dd)", q.Get("from"))
+		return
+	}
+	to, err := time.Parse(database.DateLayout, q.Get("to"))
+	if err != nil || to.Before(from) {
+		writeError(w, http.StatusBadRequest, "invalid to %q (want yyyy-mm-dd, not before from)", q.Get("to"))
+		return
+	}
+
+	report, err := payroll.Load(store, from, to.AddDate(0, 0, 1), payroll.DefaultRules)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	var body bytes.Buffer
+	opts := payroll.CSVOptions{Layout: q.Get("layout"), CompanyCode: q.Get("company"), BatchID: q.Get("batch")}
+	if err := payroll.WriteCSV(&body, report, opts); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=payroll-%s.csv", q.Get("from")))
+	w.Write(body.Bytes())
+}
+
+// handleImportRoster saves a hand-edited CSV or JSON export (the request body) as a draft.
+// The format comes from ?format= or the Content-Type; the day from ?date= (default today at the store).
+func (s *Server) handleImportRoster(w http.ResponseWriter, r *http.Request) {
+	locationID, ok := s.locationID(w, r)
+	if !ok {
+		return
+	}
+	store := s.store
+	if locationID != 0 {
+		store = store.AtLocation(locationID)
+	}
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "json"
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = "csv"
+		}
+	}
+	day, err := s.localDay(locationID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if raw := q.Get("date"); raw != "" {
+		parsed, err := time.Parse(database.DateLayout, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid date %q (want YYYY-MM-DD)", raw)
+			return
+		}
+		day = parsed
+	}
+	author := q.Get("author")
+	if author == "" {
+		author = "api"
+	}
+
+	roster, err := importer.ReadRoster(store, r.Body, format)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	edit, err := importer.SaveEdit(store, roster, day, author)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	_, v, err := s.store.LoadRoster(edit.VersionID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	out := importJSON{Version: toVersion(v), BaseID: edit.Base.ID, Changes: scheduler.ChangeCount(edit.Diff), Violations: []violationJSON{}}
+	introduced := make(map[string]bool)
+	for _, x := range edit.Introduced {
+		introduced[x.Reason] = true
+	}
+	for _, x := range edit.Violations {
+		out.Violations = append(out.Violations, violationJSON{Hour: x.Hour, EmployeeID: x.EmployeeID, Reason: x.Reason, Introduced: introduced[x.Reason]})
+	}
+	w.Header().Set("Location", fmt.Sprintf("/rosters/%d", edit.VersionID))
+	writeJSON(w, http.StatusCreated, out)
+}
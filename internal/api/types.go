@@ -0,0 +1,349 @@
+package api
+
+import (
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// The wire format is kept apart from the models so the JSON stays stable (snake_case)
+// while the internal structs evolve.
+
+type employeeJSON struct {
+	ID         int     `json:"id"`
+	Name       string  `json:"name"`
+	HourlyRate float64 `json:"hourly_rate"`
+	SkillLevel int     `json:"skill_level"`
+	Active     bool    `json:"active"`
+	LocationID int     `json:"location_id,omitempty"`
+	Floats     bool    `json:"floats"`
+}
+
+type demandJSON struct {
+	HourOfDay int `json:"hour_of_day"`
+	Needed    int `json:"needed"`
+}
+
+type unavailabilityJSON struct {
+	EmployeeID int    `json:"employee_id,omitempty"`
+	Employee   string `json:"employee,omitempty"`
+	StartHour  int    `json:"start_hour"`
+	EndHour    int    `json:"end_hour"`
+	Reason     string `json:"reason,omitempty"`
+	Date       string `json:"date,omitempty"` // Empty = every day
+}
+
+func toUnavailability(u models.Unavailability) unavailabilityJSON {
+	out := unavailabilityJSON{EmployeeID: u.EmployeeID, Employee: u.EmployeeName,
+		StartHour: u.StartHour, EndHour: u.EndHour, Reason: u.Reason}
+	if !u.Date.IsZero() {
+		out.Date = u.Date.Format(database.DateLayout)
+	}
+	return out
+}
+
+// messageJSON is a free-text message to read constraints from
+type messageJSON struct {
+	Text  string `json:"text"`
+	From  string `json:"from,omitempty"`  // The employee who sent it: who "I" is
+	Today string `json:"today,omitempty"` // What weekday names count from (default: today)
+}
+
+type constraintJSON struct {
+	unavailabilityJSON
+	Intent     string   `json:"intent"`              // unavailable, available, swap or time_off
+	SwapWith   string   `json:"swap_with,omitempty"` // The other person of a swap
+	Confidence float64  `json:"confidence"`
+	Unclear    []string `json:"unclear,omitempty"` // Fields still to ask about: employee, date, time or confirm
+}
+
+// timeOffJSON is a time-off request waiting for, or given, the manager's decision
+type timeOffJSON struct {
+	ID        int       `json:"id"`
+	Employee  string    `json:"employee"`
+	Date      string    `json:"date,omitempty"`
+	StartHour int       `json:"start_hour"`
+	EndHour   int       `json:"end_hour"`
+	Reason    string    `json:"reason,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type swapJSON struct {
+	ID        int       `json:"id"`
+	Employee  string    `json:"employee"`
+	With      string    `json:"with"`
+	Date      string    `json:"date,omitempty"`
+	StartHour int       `json:"start_hour"`
+	EndHour   int       `json:"end_hour"`
+	Message   string    `json:"message,omitempty"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// pendingJSON is a message held until Question is answered
+type pendingJSON struct {
+	ID          int              `json:"id"`
+	Text        string           `json:"text"`
+	Question    string           `json:"question"`
+	Constraints []constraintJSON `json:"constraints,omitempty"`
+	CreatedAt   *time.Time       `json:"created_at,omitempty"`
+}
+
+func toTimeOff(t models.TimeOffRequest) timeOffJSON {
+	out := timeOffJSON{ID: t.ID, Employee: t.EmployeeName, StartHour: t.StartHour, EndHour: t.EndHour,
+		Reason: t.Reason, Message: t.Message, Status: t.Status, CreatedAt: t.CreatedAt}
+	if !t.Date.IsZero() {
+		out.Date = t.Date.Format(database.DateLayout)
+	}
+	return out
+}
+
+func toSwap(w models.SwapRequest) swapJSON {
+	out := swapJSON{ID: w.ID, Employee: w.EmployeeName, With: w.WithName, StartHour: w.StartHour, EndHour: w.EndHour,
+		Message: w.Message, Status: w.Status, CreatedAt: w.CreatedAt}
+	if !w.Date.IsZero() {
+		out.Date = w.Date.Format(database.DateLayout)
+	}
+	return out
+}
+
+// contactJSON is a number or address an employee texts or emails from
+type contactJSON struct {
+	ID         int    `json:"id"`
+	EmployeeID int    `json:"employee_id,omitempty"`
+	Employee   string `json:"employee,omitempty"` // Name, when adding by name
+	Address    string `json:"address"`
+}
+
+// inboundJSON is a received SMS or email and what became of it
+type inboundJSON struct {
+	ID         int       `json:"id"`
+	Channel    string    `json:"channel"`
+	Sender     string    `json:"sender"`
+	EmployeeID int       `json:"employee_id,omitempty"`
+	Employee   string    `json:"employee,omitempty"`
+	Text       string    `json:"text"`
+	ExternalID string    `json:"external_id,omitempty"`
+	Status     string    `json:"status"`
+	PendingID  int       `json:"pending_id,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+func toInbound(m models.InboundMessage) inboundJSON {
+	return inboundJSON{ID: m.ID, Channel: m.Channel, Sender: m.Sender, EmployeeID: m.EmployeeID, Employee: m.EmployeeName,
+		Text: m.Text, ExternalID: m.ExternalID, Status: m.Status, PendingID: m.PendingID, Error: m.Error, ReceivedAt: m.ReceivedAt}
+}
+
+// statsJSON counts the language model's work since the server started
+type statsJSON struct {
+	Parses         int64   `json:"parses"`
+	Fallbacks      int64   `json:"fallbacks"`
+	FallbackRate   float64 `json:"fallback_rate"`
+	CacheHits      int64   `json:"cache_hits"`
+	ModelCalls     int64   `json:"model_calls"`
+	InvalidAnswers int64   `json:"invalid_answers"`
+}
+
+// commandRequest is a manager command in plain words
+type commandRequest struct {
+	Text   string `json:"text"`
+	Today  string `json:"today,omitempty"`  // What "Saturday" counts from (default: today)
+	Apply  bool   `json:"apply,omitempty"`  // Save the change (default: a dry run)
+	Author string `json:"author,omitempty"` // Recorded on the draft rosters when applied
+}
+
+type shiftJSON struct {
+	EmployeeID int    `json:"employee_id"`
+	Employee   string `json:"employee"`
+	StartHour  int    `json:"start_hour"`
+	EndHour    int    `json:"end_hour"`
+}
+
+type dayShiftsJSON struct {
+	Date   string      `json:"date"`
+	Shifts []shiftJSON `json:"shifts"` // Null when the day has no roster yet
+}
+
+type lockJSON struct {
+	Employee  string `json:"employee"`
+	Kind      string `json:"kind"`
+	Date      string `json:"date,omitempty"` // Empty = every day
+	StartHour int    `json:"start_hour"`
+	EndHour   int    `json:"end_hour"`
+}
+
+// trialJSON is one day re-run with the change, against the roster in force
+type trialJSON struct {
+	Date           string         `json:"date"`
+	Added          []shiftJSON    `json:"added"`
+	Removed        []shiftJSON    `json:"removed"`
+	Changed        [][2]shiftJSON `json:"changed"` // Before, after
+	CostBefore     float64        `json:"cost_before"`
+	CostAfter      float64        `json:"cost_after"`
+	UnfilledBefore int            `json:"unfilled_before"`
+	UnfilledAfter  int            `json:"unfilled_after"`
+}
+
+// planJSON is what a command found, would change or changed
+type planJSON struct {
+	Kind           string               `json:"kind"`
+	Working        []dayShiftsJSON      `json:"working,omitempty"`
+	Unavailability []unavailabilityJSON `json:"unavailability,omitempty"`
+	Locks          []lockJSON           `json:"locks,omitempty"`
+	Trials         []trialJSON          `json:"trials,omitempty"`
+	Notes          []string             `json:"notes,omitempty"`
+	Applied        bool                 `json:"applied"`
+	Versions       []int                `json:"versions,omitempty"` // The draft rosters saved when applied
+}
+
+func toShift(s models.Shift) shiftJSON {
+	return shiftJSON{EmployeeID: s.Employee.ID, Employee: s.Employee.Name, StartHour: s.StartHour, EndHour: s.EndHour}
+}
+
+func toShifts(shifts []models.Shift) []shiftJSON {
+	out := []shiftJSON{}
+	for _, s := range shifts {
+		out = append(out, toShift(s))
+	}
+	return out
+}
+
+func toPlan(p ai.Plan) planJSON {
+	out := planJSON{Kind: p.Command.Kind, Notes: p.Notes}
+	for _, d := range p.Working {
+		day := dayShiftsJSON{Date: d.Date.Format(database.DateLayout)}
+		if d.Shifts != nil {
+			day.Shifts = toShifts(d.Shifts)
+		}
+		out.Working = append(out.Working, day)
+	}
+	for _, u := range p.Change.Unavailability {
+		out.Unavailability = append(out.Unavailability, toUnavailability(u))
+	}
+	for _, l := range p.Change.Locks {
+		lock := lockJSON{Employee: l.EmployeeName, Kind: l.Kind, StartHour: l.StartHour, EndHour: l.EndHour}
+		if !l.Date.IsZero() {
+			lock.Date = l.Date.Format(database.DateLayout)
+		}
+		out.Locks = append(out.Locks, lock)
+	}
+	for _, t := range p.Trials {
+		trial := trialJSON{Date: t.Date.Format(database.DateLayout), Added: toShifts(t.Diff.Added), Removed: toShifts(t.Diff.Removed),
+			Changed: [][2]shiftJSON{}, CostAfter: t.After.TotalCost, UnfilledAfter: t.After.Unfilled}
+		for _, c := range t.Diff.Changed {
+			trial.Changed = append(trial.Changed, [2]shiftJSON{toShift(c.Before), toShift(c.After)})
+		}
+		if t.Before != nil {
+			trial.CostBefore, trial.UnfilledBefore = t.Before.TotalCost, t.Before.Unfilled
+		}
+		out.Trials = append(out.Trials, trial)
+	}
+	return out
+}
+
+type assignmentJSON struct {
+	Hour       int     `json:"hour"`
+	EmployeeID int     `json:"employee_id"`
+	Employee   string  `json:"employee"`
+	HourlyRate float64 `json:"hourly_rate"`
+	IsSenior   bool    `json:"is_senior"`
+}
+
+type versionJSON struct {
+	ID         int       `json:"id"`
+	Date       string    `json:"date,omitempty"`
+	LocationID int       `json:"location_id"`
+	Status     string    `json:"status"`
+	Author     string    `json:"author"`
+	CreatedAt  time.Time `json:"created_at"`
+	TotalCost  float64   `json:"total_cost"`
+	Unfilled   int       `json:"unfilled"`
+}
+
+type rosterJSON struct {
+	versionJSON
+	Assignments []assignmentJSON `json:"assignments"`
+}
+
+type violationJSON struct {
+	Hour       int    `json:"hour"` // -1 = the whole day
+	EmployeeID int    `json:"employee_id,omitempty"`
+	Reason     string `json:"reason"`
+	Introduced bool   `json:"introduced"` // Not already broken by the base version
+}
+
+type importJSON struct {
+	Version    versionJSON     `json:"version"`
+	BaseID     int             `json:"base_id,omitempty"` // The version in force that day before the import
+	Changes    int             `json:"changes"`           // Shift changes against the base
+	Violations []violationJSON `json:"violations"`
+}
+
+type jobJSON struct {
+	ID         int        `json:"id"`
+	Strategy   string     `json:"strategy"`
+	LocationID int        `json:"location_id,omitempty"`
+	Date       string     `json:"date,omitempty"`
+	Author     string     `json:"author"`
+	Status     string     `json:"status"`
+	Progress   float64    `json:"progress"`
+	Error      string     `json:"error,omitempty"`
+	RosterID   int        `json:"roster_id,omitempty"` // The draft version saved when done
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type runRequest struct {
+	Strategy string `json:"strategy"`
+	Date     string `json:"date"` // YYYY-MM-DD, default today
+	Author   string `json:"author"`
+}
+
+func toEmployee(e models.Employee) employeeJSON {
+	return employeeJSON{ID: e.ID, Name: e.Name, HourlyRate: e.HourlyRate, SkillLevel: e.SkillLevel,
+		Active: e.Active, LocationID: e.LocationID, Floats: e.Floats}
+}
+
+func (e employeeJSON) model() models.Employee {
+	return models.Employee{ID: e.ID, Name: e.Name, HourlyRate: e.HourlyRate, SkillLevel: e.SkillLevel,
+		LocationID: e.LocationID, Floats: e.Floats}
+}
+
+func toVersion(v models.RosterVersion) versionJSON {
+	out := versionJSON{ID: v.ID, LocationID: v.LocationID, Status: v.Status, Author: v.Author,
+		CreatedAt: v.CreatedAt, TotalCost: v.TotalCost, Unfilled: v.Unfilled}
+	if !v.Date.IsZero() {
+		out.Date = v.Date.Format(database.DateLayout)
+	}
+	return out
+}
+
+func toRoster(r *models.Roster, v models.RosterVersion) rosterJSON {
+	out := rosterJSON{versionJSON: toVersion(v), Assignments: []assignmentJSON{}}
+	for _, a := range r.Assignments {
+		out.Assignments = append(out.Assignments, assignmentJSON{Hour: a.Hour, EmployeeID: a.Employee.ID,
+			Employee: a.Employee.Name, HourlyRate: a.Employee.HourlyRate, IsSenior: a.IsSenior})
+	}
+	return out
+}
+
+func toJob(j models.Job) jobJSON {
+	out := jobJSON{ID: j.ID, Strategy: j.Strategy, LocationID: j.LocationID, Author: j.Author, Status: j.Status,
+		Progress: j.Progress, Error: j.Error, RosterID: j.RosterID, CreatedAt: j.CreatedAt}
+	if !j.Date.IsZero() {
+		out.Date = j.Date.Format(database.DateLayout)
+	}
+	if !j.StartedAt.IsZero() {
+		out.StartedAt = &j.StartedAt
+	}
+	if !j.FinishedAt.IsZero() {
+		out.FinishedAt = &j.FinishedAt
+	}
+	return out
+}
@@ -0,0 +1,201 @@
+// Package dashboard renders a roster as a self-contained HTML page: a Gantt chart of the
+// shifts, demand vs staffed per hour, cost vs budget and the unfilled slots.
+// The page has inline CSS and no scripts, so it works offline and as an email attachment.
+package dashboard
+
+import (
+	_ "embed"
+	"errors"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+//go:embed dashboard.html
+var pageSource string
+
+var page = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"pct":    func(f float64) float64 { return float64(int(f*10+0.5)) / 10 },
+	"cap100": func(f float64) float64 { return min(f, 100) },
+}).Parse(pageSource))
+
+// ErrNoRoster is returned by Load when asked for the latest version and none is saved
+var ErrNoRoster = errors.New("no roster versions saved yet")
+
+// Page is everything the template shows
+type Page struct {
+	Title       string
+	Location    string
+	Version     models.RosterVersion
+	GeneratedAt time.Time
+
+	Open, Close int     // Hour range of the chart, Close exclusive
+	HourWidth   float64 // One hour, percent of the chart width
+	Hours       []Hour
+	Rows        []Row
+
+	TotalCost  float64
+	Budget     float64 // 0 = no budget set
+	BudgetUsed float64 // Percent of the budget (may exceed 100)
+	OverBudget bool
+	Unfilled   int
+	Shortages  []Hour // Hours with unfilled slots or no Senior on site
+}
+
+// Hour is one column of the demand chart
+type Hour struct {
+	Hour     int
+	Needed   int
+	Staffed  int
+	Unfilled int
+	Senior   bool
+	Cost     float64
+	Height   float64 // Bar height, percent of the busiest hour
+	Fill     float64 // Staffed share of the bar, percent
+}
+
+// Row is one employee in the Gantt chart
+type Row struct {
+	Name     string
+	Senior   bool
+	Hours    int
+	Cost     float64
+	Segments []Segment
+}
+
+// Segment is one shift, positioned as a percentage of the opening hours
+type Segment struct {
+	StartHour, EndHour int
+	Left, Width        float64
+	Senior             bool
+}
+
+// Build lays out a roster against the demand curve it was meant to cover
+func Build(roster *models.Roster, version models.RosterVersion, demands []models.Demand, budget float64) Page {
+	p := Page{Title: "Roster", Version: version, GeneratedAt: time.Now(), TotalCost: roster.TotalCost, Budget: budget}
+
+	// 1. Per-hour totals
+	byHour := make(map[int]*Hour)
+	hourOf := func(h int) *Hour {
+		if byHour[h] == nil {
+			byHour[h] = &Hour{Hour: h}
+		}
+		return byHour[h]
+	}
+	for _, d := range demands {
+		hourOf(d.HourOfDay).Needed = d.Needed
+	}
+	for _, a := range roster.Assignments {
+		h := hourOf(a.Hour)
+		h.Staffed++
+		h.Cost += a.Employee.HourlyRate
+		if a.Employee.SkillLevel >= 2 {
+			h.Senior = true
+		}
+	}
+
+	peak := 1
+	for _, h := range byHour {
+		if h.Needed > h.Staffed {
+			h.Unfilled = h.Needed - h.Staffed
+		}
+		peak = max(peak, h.Needed, h.Staffed)
+	}
+	for _, h := range byHour {
+		h.Height = 100 * float64(max(h.Needed, h.Staffed)) / float64(peak)
+		h.Fill = 100 * float64(h.Staffed) / float64(max(h.Needed, h.Staffed, 1))
+		p.Hours = append(p.Hours, *h)
+	}
+	sort.Slice(p.Hours, func(i, j int) bool { return p.Hours[i].Hour < p.Hours[j].Hour })
+
+	if len(p.Hours) > 0 {
+		p.Open, p.Close = p.Hours[0].Hour, p.Hours[len(p.Hours)-1].Hour+1
+	}
+	for _, h := range p.Hours {
+		p.Unfilled += h.Unfilled
+		if h.Unfilled > 0 || (h.Staffed > 0 && !h.Senior) {
+			p.Shortages = append(p.Shortages, h)
+		}
+	}
+
+	// 2. Gantt rows, one per employee, in order of first shift
+	span := float64(max(p.Close-p.Open, 1))
+	p.HourWidth = 100 / span
+	rows := make(map[int]*Row)
+	var order []int
+	for _, s := range scheduler.Shifts(roster) {
+		r := rows[s.Employee.ID]
+		if r == nil {
+			r = &Row{Name: s.Employee.Name, Senior: s.Employee.SkillLevel >= 2}
+			rows[s.Employee.ID] = r
+			order = append(order, s.Employee.ID)
+		}
+		length := s.EndHour - s.StartHour
+		r.Hours += length
+		r.Cost += float64(length) * s.Employee.HourlyRate
+		r.Segments = append(r.Segments, Segment{
+			StartHour: s.StartHour, EndHour: s.EndHour,
+			Left:   100 * float64(s.StartHour-p.Open) / span,
+			Width:  100 * float64(length) / span,
+			Senior: r.Senior,
+		})
+	}
+	for _, id := range order {
+		p.Rows = append(p.Rows, *rows[id])
+	}
+
+	// 3. Budget
+	if budget > 0 {
+		p.BudgetUsed = 100 * roster.TotalCost / budget
+		p.OverBudget = roster.TotalCost > budget
+	}
+	return p
+}
+
+// Load builds the page for a saved roster version (0 = the latest one the Store can see).
+// Demand is read as it is now: versions do not keep a copy of the curve they were built for.
+func Load(store database.Store, versionID int, budget float64) (Page, error) {
+	if versionID == 0 {
+		versions, err := store.ListRosterVersions()
+		if err != nil {
+			return Page{}, err
+		}
+		if len(versions) == 0 {
+			return Page{}, ErrNoRoster
+		}
+		versionID = versions[0].ID
+	}
+
+	roster, version, err := store.LoadRoster(versionID)
+	if err != nil {
+		return Page{}, err
+	}
+	demands, err := store.AtLocation(version.LocationID).Demands()
+	if err != nil {
+		return Page{}, err
+	}
+
+	p := Build(roster, version, demands, budget)
+	locations, err := store.Locations()
+	if err != nil {
+		return Page{}, err
+	}
+	if len(locations) > 1 {
+		for _, l := range locations {
+			if l.ID == version.LocationID {
+				p.Location = l.Name
+			}
+		}
+	}
+	return p, nil
+}
+
+// Render writes the page as HTML
+func Render(w io.Writer, p Page) error {
+	return page.Execute(w, p)
+}
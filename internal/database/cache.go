@@ -0,0 +1,23 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// CachedParse returns the result saved under key, if any
+func (s *SQLStore) CachedParse(key string) (string, bool, error) {
+	var result string
+	err := s.queryRow("SELECT result FROM parse_cache WHERE key = ?", key).Scan(&result)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	return result, err == nil, err
+}
+
+// CacheParse saves result under key, replacing what was there
+func (s *SQLStore) CacheParse(key, result string) error {
+	_, err := s.exec(`INSERT INTO parse_cache (key, result, created_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET result = excluded.result, created_at = excluded.created_at`, key, result, now())
+	return err
+}
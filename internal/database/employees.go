@@ -0,0 +1,158 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// AddEmployee hires someone and returns their ID
+func AddEmployee(db *sql.DB, e models.Employee) (int, error) {
+	return NewSQLiteStore(db).AddEmployee(e)
+}
+
+// UpdateEmployee changes name, rate, skill level, floating and home store (matched by ID)
+func UpdateEmployee(db *sql.DB, e models.Employee) error {
+	return NewSQLiteStore(db).UpdateEmployee(e)
+}
+
+// DeactivateEmployee removes someone from scheduling without deleting their history
+func DeactivateEmployee(db *sql.DB, id int) error {
+	return NewSQLiteStore(db).DeactivateEmployee(id)
+}
+
+// ListEmployees returns the whole crew, including inactive staff
+func ListEmployees(db *sql.DB) ([]models.Employee, error) {
+	return NewSQLiteStore(db).AllEmployees()
+}
+
+func (s *SQLStore) Employees() ([]models.Employee, error) {
+	return s.employees("active = ?", true)
+}
+
+func (s *SQLStore) AllEmployees() ([]models.Employee, error) {
+	return s.employees("")
+}
+
+func (s *SQLStore) employees(cond string, args ...any) ([]models.Employee, error) {
+	where, args := s.scoped(cond, "location_id", args)
+	rows, err := s.query("SELECT id, name, hourly_rate, skill_level, active, location_id, floats FROM employees"+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var employees []models.Employee
+	for rows.Next() {
+		var e models.Employee
+		if err := rows.Scan(&e.ID, &e.Name, &e.HourlyRate, &e.SkillLevel, &e.Active, &e.LocationID, &e.Floats); err != nil {
+			return nil, err
+		}
+		employees = append(employees, e)
+	}
+	return employees, rows.Err()
+}
+
+func (s *SQLStore) EmployeeIDByName(name string) (int, error) {
+	var id int
+	where, args := s.scoped("name = ? AND active = ?", "location_id", []any{name, true})
+	err := s.queryRow("SELECT id FROM employees"+where+" ORDER BY id", args...).Scan(&id)
+	return id, err
+}
+
+func (s *SQLStore) AddEmployee(e models.Employee) (int, error) {
+	if err := validateEmployee(e); err != nil {
+		return 0, err
+	}
+	return s.insert(s.db, "INSERT INTO employees (name, hourly_rate, skill_level, active, location_id, floats) VALUES (?, ?, ?, ?, ?, ?)",
+		e.Name, e.HourlyRate, e.SkillLevel, true, s.writeLocation(e.LocationID), e.Floats)
+}
+
+func (s *SQLStore) UpdateEmployee(e models.Employee) error {
+	if err := validateEmployee(e); err != nil {
+		return err
+	}
+	if e.LocationID == 0 { // A store scoped with AtLocation moves the employee there
+		e.LocationID = s.location
+	}
+	var res sql.Result
+	var err error
+	if e.LocationID == 0 {
+		res, err = s.exec("UPDATE employees SET name = ?, hourly_rate = ?, skill_level = ?, floats = ? WHERE id = ?",
+			e.Name, e.HourlyRate, e.SkillLevel, e.Floats, e.ID)
+	} else {
+		res, err = s.exec("UPDATE employees SET name = ?, hourly_rate = ?, skill_level = ?, floats = ?, location_id = ? WHERE id = ?",
+			e.Name, e.HourlyRate, e.SkillLevel, e.Floats, e.LocationID, e.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("employee %d: %w", e.ID, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// SaveEmployees hires the employees with no ID and updates the others, active again:
+// a crew list is who works here now. Home stores move as UpdateEmployee moves them. All or none are saved.
+func (s *SQLStore) SaveEmployees(crew []models.Employee) error {
+	for _, e := range crew {
+		if err := validateEmployee(e); err != nil {
+			return err
+		}
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, e := range crew {
+		if e.ID == 0 {
+			if _, err := s.insert(tx, "INSERT INTO employees (name, hourly_rate, skill_level, active, location_id, floats) VALUES (?, ?, ?, ?, ?, ?)",
+				e.Name, e.HourlyRate, e.SkillLevel, true, s.writeLocation(e.LocationID), e.Floats); err != nil {
+				return err
+			}
+			continue
+		}
+		location := e.LocationID
+		if location == 0 {
+			location = s.location
+		}
+		res, err := tx.Exec(s.rebind("UPDATE employees SET name = ?, hourly_rate = ?, skill_level = ?, floats = ?, active = ?, location_id = COALESCE(NULLIF(?, 0), location_id) WHERE id = ?"),
+			e.Name, e.HourlyRate, e.SkillLevel, e.Floats, true, location, e.ID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("employee %d: %w", e.ID, sql.ErrNoRows)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) DeactivateEmployee(id int) error {
+	res, err := s.exec("UPDATE employees SET active = ? WHERE id = ?", false, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("employee %d: %w", id, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// validateEmployee enforces what the schedulers assume about the crew
+func validateEmployee(e models.Employee) error {
+	if e.Name == "" {
+		return fmt.Errorf("employee name is required")
+	}
+	if e.HourlyRate <= 0 {
+		return fmt.Errorf("employee %q: hourly rate must be positive", e.Name)
+	}
+	if e.SkillLevel < 1 || e.SkillLevel > 2 {
+		return fmt.Errorf("employee %q: skill level must be 1 (Junior) or 2 (Senior)", e.Name)
+	}
+	return nil
+}
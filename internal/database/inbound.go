@@ -0,0 +1,177 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// ErrContactTaken is returned when adding an address another employee already uses
+var ErrContactTaken = errors.New("address already belongs to an employee")
+
+// ErrInboundReceived is returned when adding a message whose gateway ID was already received
+var ErrInboundReceived = errors.New("inbound message already received")
+
+// Contacts lists the staff's numbers and addresses, by employee
+func (s *SQLStore) Contacts() ([]models.Contact, error) {
+	where, args := s.scoped("", "e.location_id", nil)
+	rows, err := s.query(`SELECT c.id, c.employee_id, e.name, c.address
+		FROM employee_contacts c JOIN employees e ON e.id = c.employee_id`+where+` ORDER BY e.name, c.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []models.Contact
+	for rows.Next() {
+		var c models.Contact
+		if err := rows.Scan(&c.ID, &c.EmployeeID, &c.EmployeeName, &c.Address); err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, c)
+	}
+	return contacts, rows.Err()
+}
+
+func (s *SQLStore) AddContact(c models.Contact) (int, error) {
+	address, err := normalizeAddress(c.Address)
+	if err != nil {
+		return 0, err
+	}
+	var owner string
+	err = s.queryRow("SELECT e.name FROM employee_contacts c JOIN employees e ON e.id = c.employee_id WHERE c.address = ?", address).Scan(&owner)
+	if err == nil {
+		return 0, fmt.Errorf("%s: %w (%s)", address, ErrContactTaken, owner)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	if err := s.queryRow("SELECT id FROM employees WHERE id = ?", c.EmployeeID).Scan(new(int)); err != nil {
+		return 0, fmt.Errorf("employee %d: %w", c.EmployeeID, err)
+	}
+	return s.insert(s.db, "INSERT INTO employee_contacts (employee_id, address) VALUES (?, ?)", c.EmployeeID, address)
+}
+
+func (s *SQLStore) RemoveContact(id int) error {
+	res, err := s.exec("DELETE FROM employee_contacts WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("contact %d: %w", id, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// EmployeeByContact finds the active employee who uses a number or address
+func (s *SQLStore) EmployeeByContact(address string) (int, error) {
+	normal, err := normalizeAddress(address)
+	if err != nil {
+		return 0, fmt.Errorf("contact %q: %w", address, sql.ErrNoRows)
+	}
+	var id int
+	where, args := s.scoped("c.address = ? AND e.active = ?", "e.location_id", []any{normal, true})
+	err = s.queryRow("SELECT e.id FROM employee_contacts c JOIN employees e ON e.id = c.employee_id"+where, args...).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("contact %s: %w", normal, err)
+	}
+	return id, nil
+}
+
+// AddInbound keeps a message. A gateway ID is kept once per channel: the unique index settles
+// two deliveries racing, and the second gets ErrInboundReceived.
+func (s *SQLStore) AddInbound(m models.InboundMessage) (int, error) {
+	received := time.Now()
+	if !m.ReceivedAt.IsZero() {
+		received = m.ReceivedAt
+	}
+	id, err := s.insert(s.db, `INSERT INTO inbound_messages (channel, sender, employee_id, text, external_id, status, pending_id, error, received_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (channel, external_id) WHERE external_id <> '' DO NOTHING`,
+		m.Channel, m.Sender, m.EmployeeID, m.Text, m.ExternalID, m.Status, m.PendingID, m.Error, received.UTC().Format(time.RFC3339))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s %s: %w", m.Channel, m.ExternalID, ErrInboundReceived)
+	}
+	return id, err
+}
+
+// UpdateInbound records what became of a kept message: its employee, status, pending message and error
+func (s *SQLStore) UpdateInbound(m models.InboundMessage) error {
+	res, err := s.exec("UPDATE inbound_messages SET employee_id = ?, status = ?, pending_id = ?, error = ? WHERE id = ?",
+		m.EmployeeID, m.Status, m.PendingID, m.Error, m.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("inbound message %d: %w", m.ID, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// InboundMessages lists the messages received, oldest first
+func (s *SQLStore) InboundMessages() ([]models.InboundMessage, error) {
+	rows, err := s.query(`SELECT m.id, m.channel, m.sender, m.employee_id, COALESCE(e.name, ''), m.text, m.external_id,
+		m.status, m.pending_id, m.error, m.received_at
+		FROM inbound_messages m LEFT JOIN employees e ON e.id = m.employee_id ORDER BY m.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.InboundMessage
+	for rows.Next() {
+		var m models.InboundMessage
+		var received string
+		if err := rows.Scan(&m.ID, &m.Channel, &m.Sender, &m.EmployeeID, &m.EmployeeName, &m.Text, &m.ExternalID,
+			&m.Status, &m.PendingID, &m.Error, &received); err != nil {
+			return nil, err
+		}
+		m.ReceivedAt, _ = time.Parse(time.RFC3339, received)
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// InboundReceived reports whether a message with the gateway's ID was already received
+func (s *SQLStore) InboundReceived(channel, externalID string) (bool, error) {
+	if externalID == "" {
+		return false, nil
+	}
+	err := s.queryRow("SELECT id FROM inbound_messages WHERE channel = ? AND external_id = ?", channel, externalID).Scan(new(int))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// normalizeAddress is how an address is saved and looked up: a lower-case email address,
+// or an international phone number as + and digits ("+1 (555) 010-0001" is "+15550100001")
+func normalizeAddress(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if strings.Contains(address, "@") {
+		a, err := mail.ParseAddress(address)
+		if err != nil {
+			return "", fmt.Errorf("invalid email address %q", address)
+		}
+		return strings.ToLower(a.Address), nil
+	}
+	var b strings.Builder
+	for i, r := range address {
+		switch {
+		case i == 0 && r == '+', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case strings.ContainsRune(" -().", r):
+		default:
+			return "", fmt.Errorf("invalid phone number %q", address)
+		}
+	}
+	if phone := b.String(); strings.HasPrefix(phone, "+") && len(phone) >= 8 {
+		return phone, nil
+	}
+	return "", fmt.Errorf("invalid phone number %q (want the international format, e.g. +15550100001)", address)
+}
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// ErrJobNotQueued is returned when cancelling a job that already finished
+var ErrJobNotQueued = errors.New("job is not queued or running")
+
+const jobColumns = `id, strategy, location_id, roster_date, author, status, progress,
+	COALESCE(error, ''), COALESCE(roster_id, 0), created_at, COALESCE(started_at, ''), COALESCE(finished_at, ''), cancel_requested`
+
+func (s *SQLStore) EnqueueJob(j models.Job) (int, error) {
+	if j.LocationID == 0 {
+		j.LocationID = s.location
+	}
+	return s.insert(s.db, `INSERT INTO jobs (strategy, location_id, roster_date, author, status, progress, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		j.Strategy, j.LocationID, formatDate(j.Date), j.Author, models.JobQueued, 0.0, now())
+}
+
+// ClaimJob marks the oldest queued job as running. The status check in the UPDATE makes
+// the claim safe when several workers (or processes) race for the same row.
+func (s *SQLStore) ClaimJob() (models.Job, bool, error) {
+	for {
+		var id int
+		err := s.queryRow("SELECT id FROM jobs WHERE status = ? ORDER BY id LIMIT 1", models.JobQueued).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Job{}, false, nil
+		}
+		if err != nil {
+			return models.Job{}, false, err
+		}
+
+		stamp := now()
+		res, err := s.exec("UPDATE jobs SET status = ?, started_at = ?, heartbeat_at = ? WHERE id = ? AND status = ?",
+			models.JobRunning, stamp, stamp, id, models.JobQueued)
+		if err != nil {
+			return models.Job{}, false, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue // Someone else got it first
+		}
+		j, err := s.Job(id)
+		return j, err == nil, err
+	}
+}
+
+func (s *SQLStore) UpdateJobProgress(id int, progress float64) error {
+	_, err := s.exec("UPDATE jobs SET progress = ? WHERE id = ?", progress, id)
+	return err
+}
+
+func (s *SQLStore) FinishJob(id int, status string, rosterID int, errMsg string) error {
+	query := "UPDATE jobs SET status = ?, roster_id = ?, error = ?, finished_at = ? WHERE id = ?"
+	if status == models.JobDone {
+		query = "UPDATE jobs SET status = ?, roster_id = ?, error = ?, finished_at = ?, progress = 1 WHERE id = ?"
+	}
+	_, err := s.exec(query, status, rosterID, errMsg, now(), id)
+	return err
+}
+
+// CancelJob cancels a job that no worker has picked up yet, and flags a running one
+// for its worker to stop (see models.Job.CancelRequested)
+func (s *SQLStore) CancelJob(id int) error {
+	res, err := s.exec("UPDATE jobs SET status = ?, finished_at = ? WHERE id = ? AND status = ?",
+		models.JobCancelled, now(), id, models.JobQueued)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	res, err = s.exec("UPDATE jobs SET cancel_requested = ? WHERE id = ? AND status = ?", true, id, models.JobRunning)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := s.Job(id); err != nil {
+			return err
+		}
+		return fmt.Errorf("job %d: %w", id, ErrJobNotQueued)
+	}
+	return nil
+}
+
+// RequeueJob puts a running job back in the queue (its worker is shutting down)
+func (s *SQLStore) RequeueJob(id int) error {
+	_, err := s.exec("UPDATE jobs SET status = ?, progress = 0, started_at = NULL WHERE id = ? AND status = ?",
+		models.JobQueued, id, models.JobRunning)
+	return err
+}
+
+// TouchJob records that a worker is still on a running job
+func (s *SQLStore) TouchJob(id int) error {
+	_, err := s.exec("UPDATE jobs SET heartbeat_at = ? WHERE id = ? AND status = ?", now(), id, models.JobRunning)
+	return err
+}
+
+// RequeueStaleJobs puts back running jobs whose worker has not touched them since before:
+// it died with them. Jobs a live worker is on, in this process or another, are left alone.
+func (s *SQLStore) RequeueStaleJobs(before time.Time) (int, error) {
+	res, err := s.exec("UPDATE jobs SET status = ?, progress = 0, started_at = NULL WHERE status = ? AND COALESCE(heartbeat_at, started_at, '') < ?",
+		models.JobQueued, models.JobRunning, before.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLStore) Job(id int) (models.Job, error) {
+	j, err := scanJob(s.queryRow("SELECT "+jobColumns+" FROM jobs WHERE id = ?", id))
+	if err != nil {
+		return j, fmt.Errorf("job %d: %w", id, err)
+	}
+	return j, nil
+}
+
+// Jobs returns the newest jobs first (a scoped store only sees its own location's)
+func (s *SQLStore) Jobs() ([]models.Job, error) {
+	where, args := s.scoped("", "location_id", nil)
+	rows, err := s.query("SELECT "+jobColumns+" FROM jobs"+where+" ORDER BY id DESC LIMIT 100", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+func scanJob(s scanner) (models.Job, error) {
+	var j models.Job
+	var date, created, started, finished string
+	err := s.Scan(&j.ID, &j.Strategy, &j.LocationID, &date, &j.Author, &j.Status, &j.Progress,
+		&j.Error, &j.RosterID, &created, &started, &finished, &j.CancelRequested)
+	if err != nil {
+		return j, err
+	}
+	j.Date, _ = parseDate(date)
+	j.CreatedAt, _ = time.Parse(time.RFC3339, created)
+	j.StartedAt, _ = time.Parse(time.RFC3339, started)
+	j.FinishedAt, _ = time.Parse(time.RFC3339, finished)
+	return j, nil
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
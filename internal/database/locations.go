@@ -0,0 +1,145 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// AddLocation opens a new store and returns its ID
+func AddLocation(db *sql.DB, name, region string) (int, error) {
+	return NewSQLiteStore(db).AddLocation(models.Location{Name: name, Region: region})
+}
+
+// ListLocations returns every store, ordered by ID
+func ListLocations(db *sql.DB) ([]models.Location, error) {
+	return NewSQLiteStore(db).Locations()
+}
+
+// SetTravelTime records how many minutes a floater needs between two stores
+func SetTravelTime(db *sql.DB, fromID, toID, minutes int) error {
+	return NewSQLiteStore(db).SetTravelTime(models.TravelTime{FromID: fromID, ToID: toID, Minutes: minutes})
+}
+
+func (s *SQLStore) Locations() ([]models.Location, error) {
+	rows, err := s.query("SELECT id, name, region, timezone FROM locations ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locations []models.Location
+	for rows.Next() {
+		var l models.Location
+		if err := rows.Scan(&l.ID, &l.Name, &l.Region, &l.Timezone); err != nil {
+			return nil, err
+		}
+		locations = append(locations, l)
+	}
+	return locations, rows.Err()
+}
+
+func (s *SQLStore) LocationByName(name string) (models.Location, error) {
+	var l models.Location
+	err := s.queryRow("SELECT id, name, region, timezone FROM locations WHERE name = ?", name).Scan(&l.ID, &l.Name, &l.Region, &l.Timezone)
+	if err != nil {
+		return l, fmt.Errorf("location %q: %w", name, err)
+	}
+	return l, nil
+}
+
+func (s *SQLStore) AddLocation(l models.Location) (int, error) {
+	if l.Name == "" {
+		return 0, fmt.Errorf("location name is required")
+	}
+	if err := checkTimezone(l.Timezone); err != nil {
+		return 0, err
+	}
+	return s.insert(s.db, "INSERT INTO locations (name, region, timezone) VALUES (?, ?, ?)", l.Name, l.Region, l.Timezone)
+}
+
+// SetLocationTimezone changes a store's time zone ("" = the server's local time)
+func (s *SQLStore) SetLocationTimezone(id int, zone string) error {
+	if err := checkTimezone(zone); err != nil {
+		return err
+	}
+	res, err := s.exec("UPDATE locations SET timezone = ? WHERE id = ?", zone, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("location %d: %w", id, sql.ErrNoRows)
+	}
+	return nil
+}
+
+func checkTimezone(zone string) error {
+	if _, err := time.LoadLocation(zone); err != nil {
+		return fmt.Errorf("unknown time zone %q (want an IANA name such as Europe/Lisbon)", zone)
+	}
+	return nil
+}
+
+// LocalNow is the current time in a store's time zone (0 = the default store)
+func LocalNow(store Store, locationID int) (time.Time, error) {
+	if locationID == 0 {
+		locationID = models.DefaultLocation
+	}
+	locations, err := store.Locations()
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, l := range locations {
+		if l.ID == locationID {
+			return time.Now().In(l.Zone()), nil
+		}
+	}
+	return time.Now(), nil
+}
+
+func (s *SQLStore) TravelTimes() ([]models.TravelTime, error) {
+	rows, err := s.query("SELECT from_location_id, to_location_id, minutes FROM location_travel ORDER BY from_location_id, to_location_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var times []models.TravelTime
+	for rows.Next() {
+		var t models.TravelTime
+		if err := rows.Scan(&t.FromID, &t.ToID, &t.Minutes); err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, rows.Err()
+}
+
+// SetTravelTime stores the pair in one direction only: readers treat it as symmetric
+func (s *SQLStore) SetTravelTime(t models.TravelTime) error {
+	if t.FromID == t.ToID {
+		return fmt.Errorf("travel time needs two different locations")
+	}
+	if t.Minutes < 0 {
+		return fmt.Errorf("travel time cannot be negative")
+	}
+	if t.FromID > t.ToID {
+		t.FromID, t.ToID = t.ToID, t.FromID
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(s.rebind("DELETE FROM location_travel WHERE from_location_id = ? AND to_location_id = ?"), t.FromID, t.ToID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.rebind("INSERT INTO location_travel (from_location_id, to_location_id, minutes) VALUES (?, ?, ?)"), t.FromID, t.ToID, t.Minutes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// AddLock pins an employee to (or forbids them from) the hours [start, end)
+func AddLock(db *sql.DB, empID, start, end int, kind string) (int, error) {
+	return NewSQLiteStore(db).AddLock(models.Lock{EmployeeID: empID, StartHour: start, EndHour: end, Kind: kind})
+}
+
+// RemoveLock deletes a manager override
+func RemoveLock(db *sql.DB, id int) error {
+	return NewSQLiteStore(db).RemoveLock(id)
+}
+
+// ListLocks returns every override. EmployeeName is empty when the employee no longer exists.
+func ListLocks(db *sql.DB) ([]models.Lock, error) {
+	return NewSQLiteStore(db).Locks()
+}
+
+func (s *SQLStore) AddLock(l models.Lock) (int, error) {
+	return s.insertLock(s.db, l)
+}
+
+func (s *SQLStore) insertLock(q interface {
+	QueryRow(string, ...any) *sql.Row
+}, l models.Lock) (int, error) {
+	if l.Kind != models.LockPinned && l.Kind != models.LockForbidden {
+		return 0, fmt.Errorf("invalid lock kind %q", l.Kind)
+	}
+	return s.insert(q, "INSERT INTO assignment_locks (employee_id, start_hour, end_hour, kind, day) VALUES (?, ?, ?, ?, ?)",
+		l.EmployeeID, l.StartHour, l.EndHour, l.Kind, nullDate(l.Date))
+}
+
+func (s *SQLStore) RemoveLock(id int) error {
+	res, err := s.exec("DELETE FROM assignment_locks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("lock %d: %w", id, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// Locks follows the employee: a scoped store only sees locks on its own crew, and a store
+// scoped with OnDay only the locks of that day
+func (s *SQLStore) Locks() ([]models.Lock, error) {
+	cond, args := "", []any(nil)
+	if !s.day.IsZero() {
+		cond, args = "(l.day IS NULL OR l.day = ?)", []any{formatDate(s.day)}
+	}
+	where, args := s.scoped(cond, "e.location_id", args)
+	rows, err := s.query(`SELECT l.id, l.employee_id, COALESCE(e.name, ''), l.start_hour, l.end_hour, l.kind, COALESCE(l.day, '')
+		FROM assignment_locks l LEFT JOIN employees e ON e.id = l.employee_id`+where+`
+		ORDER BY l.start_hour, l.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locks []models.Lock
+	for rows.Next() {
+		var l models.Lock
+		var day string
+		if err := rows.Scan(&l.ID, &l.EmployeeID, &l.EmployeeName, &l.StartHour, &l.EndHour, &l.Kind, &day); err != nil {
+			return nil, err
+		}
+		l.Date, _ = parseDate(day)
+		locks = append(locks, l)
+	}
+	return locks, rows.Err()
+}
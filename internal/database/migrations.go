@@ -0,0 +1,385 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migration is one numbered, reversible schema change.
+// Never edit a released migration: append a new one instead.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// sqliteMigrations is the schema history, oldest first.
+// Migrations 1-4 use IF NOT EXISTS so databases created before schema_version
+// existed (the old single CREATE blob) are adopted without losing data.
+// Every new migration needs its PostgreSQL twin in postgresMigrations.
+var sqliteMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up: `
+		CREATE TABLE IF NOT EXISTS employees (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT,
+			hourly_rate REAL,
+			skill_level INTEGER
+		);
+		CREATE TABLE IF NOT EXISTS demands (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			hour_of_day INTEGER,
+			needed INTEGER
+		);
+		CREATE TABLE IF NOT EXISTS unavailability (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			employee_id INTEGER,
+			start_hour INTEGER,
+			end_hour INTEGER,
+			reason TEXT,
+			FOREIGN KEY(employee_id) REFERENCES employees(id)
+		);`,
+		Down: `
+		DROP TABLE IF EXISTS unavailability;
+		DROP TABLE IF EXISTS demands;
+		DROP TABLE IF EXISTS employees;`,
+	},
+	{
+		Version: 2,
+		Name:    "on-call windows",
+		Up: `
+		CREATE TABLE IF NOT EXISTS on_call (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			employee_id INTEGER,
+			start_hour INTEGER,
+			end_hour INTEGER,
+			FOREIGN KEY(employee_id) REFERENCES employees(id)
+		);`,
+		Down: `DROP TABLE IF EXISTS on_call;`,
+	},
+	{
+		Version: 3,
+		Name:    "roster versions",
+		Up: `
+		CREATE TABLE IF NOT EXISTS roster_versions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			roster_date TEXT,
+			status TEXT,
+			author TEXT,
+			created_at TEXT,
+			total_cost REAL,
+			unfilled INTEGER
+		);
+		CREATE TABLE IF NOT EXISTS roster_assignments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			version_id INTEGER,
+			hour INTEGER,
+			employee_id INTEGER,
+			employee_name TEXT,
+			hourly_rate REAL,
+			skill_level INTEGER,
+			is_senior INTEGER,
+			FOREIGN KEY(version_id) REFERENCES roster_versions(id)
+		);`,
+		Down: `
+		DROP TABLE IF EXISTS roster_assignments;
+		DROP TABLE IF EXISTS roster_versions;`,
+	},
+	{
+		Version: 4,
+		Name:    "assignment locks",
+		Up: `
+		CREATE TABLE IF NOT EXISTS assignment_locks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			employee_id INTEGER,
+			start_hour INTEGER,
+			end_hour INTEGER,
+			kind TEXT,
+			FOREIGN KEY(employee_id) REFERENCES employees(id)
+		);`,
+		Down: `DROP TABLE IF EXISTS assignment_locks;`,
+	},
+	{
+		Version: 5,
+		Name:    "employee status",
+		Up:      `ALTER TABLE employees ADD COLUMN active INTEGER NOT NULL DEFAULT 1;`,
+		Down:    `ALTER TABLE employees DROP COLUMN active;`,
+	},
+	{
+		Version: 6,
+		Name:    "locations",
+		Up: `
+		CREATE TABLE locations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			region TEXT NOT NULL DEFAULT ''
+		);
+		INSERT INTO locations (id, name, region) VALUES (1, 'Main', '');
+		CREATE TABLE location_travel (
+			from_location_id INTEGER,
+			to_location_id INTEGER,
+			minutes INTEGER,
+			PRIMARY KEY (from_location_id, to_location_id)
+		);
+		ALTER TABLE employees ADD COLUMN location_id INTEGER NOT NULL DEFAULT 1;
+		ALTER TABLE employees ADD COLUMN floats INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE demands ADD COLUMN location_id INTEGER NOT NULL DEFAULT 1;
+		ALTER TABLE roster_versions ADD COLUMN location_id INTEGER NOT NULL DEFAULT 1;`,
+		Down: `
+		ALTER TABLE roster_versions DROP COLUMN location_id;
+		ALTER TABLE demands DROP COLUMN location_id;
+		ALTER TABLE employees DROP COLUMN floats;
+		ALTER TABLE employees DROP COLUMN location_id;
+		DROP TABLE IF EXISTS location_travel;
+		DROP TABLE IF EXISTS locations;`,
+	},
+	{
+		Version: 7,
+		Name:    "scheduling jobs",
+		Up: `
+		CREATE TABLE jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			strategy TEXT,
+			location_id INTEGER,
+			roster_date TEXT,
+			author TEXT,
+			status TEXT,
+			progress REAL,
+			error TEXT,
+			roster_id INTEGER,
+			created_at TEXT,
+			started_at TEXT,
+			finished_at TEXT
+		);
+		CREATE INDEX jobs_status ON jobs (status, id);`,
+		Down: `DROP TABLE IF EXISTS jobs;`,
+	},
+	{
+		Version: 8,
+		Name:    "dated unavailability",
+		// NULL = every day, as before
+		Up:   `ALTER TABLE unavailability ADD COLUMN day TEXT;`,
+		Down: `ALTER TABLE unavailability DROP COLUMN day;`,
+	},
+	{
+		Version: 9,
+		Name:    "location time zones",
+		Up:      `ALTER TABLE locations ADD COLUMN timezone TEXT NOT NULL DEFAULT '';`,
+		Down:    `ALTER TABLE locations DROP COLUMN timezone;`,
+	},
+	{
+		Version: 10,
+		Name:    "pending messages",
+		// draft is the parser's output as JSON, completed by the answers
+		Up: `CREATE TABLE pending_messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			text TEXT NOT NULL,
+			question TEXT NOT NULL,
+			draft TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);`,
+		Down: `DROP TABLE pending_messages;`,
+	},
+	{
+		Version: 11,
+		Name:    "staff requests",
+		// Offers of extra hours are dated on-call windows (NULL = every day, as before)
+		Up: `
+		ALTER TABLE on_call ADD COLUMN day TEXT;
+		CREATE TABLE time_off_requests (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			employee_id INTEGER NOT NULL,
+			day TEXT,
+			start_hour INTEGER NOT NULL,
+			end_hour INTEGER NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			message TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);
+		CREATE TABLE swap_requests (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			employee_id INTEGER NOT NULL,
+			with_employee_id INTEGER NOT NULL,
+			day TEXT,
+			start_hour INTEGER NOT NULL,
+			end_hour INTEGER NOT NULL,
+			message TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);`,
+		Down: `
+		DROP TABLE swap_requests;
+		DROP TABLE time_off_requests;
+		ALTER TABLE on_call DROP COLUMN day;`,
+	},
+	{
+		Version: 12,
+		Name:    "dated locks",
+		// A manager command pins someone on one day only (NULL = every day, as before)
+		Up:   `ALTER TABLE assignment_locks ADD COLUMN day TEXT;`,
+		Down: `ALTER TABLE assignment_locks DROP COLUMN day;`,
+	},
+	{
+		Version: 13,
+		Name:    "parse cache",
+		// Model answers by a hash of the model and its prompt, so a message is not sent twice
+		Up: `CREATE TABLE parse_cache (
+			key TEXT PRIMARY KEY,
+			result TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);`,
+		Down: `DROP TABLE parse_cache;`,
+	},
+	{
+		Version: 14,
+		Name:    "inbound messages",
+		// Staff numbers and addresses, and every SMS and email received with what became of it
+		Up: `
+		CREATE TABLE employee_contacts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			employee_id INTEGER NOT NULL,
+			address TEXT NOT NULL UNIQUE
+		);
+		CREATE TABLE inbound_messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			channel TEXT NOT NULL,
+			sender TEXT NOT NULL,
+			employee_id INTEGER NOT NULL DEFAULT 0,
+			text TEXT NOT NULL,
+			external_id TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			pending_id INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			received_at TEXT NOT NULL
+		);`,
+		Down: `
+		DROP TABLE inbound_messages;
+		DROP TABLE employee_contacts;`,
+	},
+	{
+		Version: 15,
+		Name:    "job cancel requests",
+		// Cancelling a running job flags it, so the worker stops it in whichever process runs it
+		Up:   `ALTER TABLE jobs ADD COLUMN cancel_requested INTEGER NOT NULL DEFAULT 0;`,
+		Down: `ALTER TABLE jobs DROP COLUMN cancel_requested;`,
+	},
+	{
+		Version: 16,
+		Name:    "inbound message IDs",
+		// A gateway retrying a message while the first delivery is being read keeps it once
+		Up:   `CREATE UNIQUE INDEX inbound_external_id ON inbound_messages (channel, external_id) WHERE external_id <> '';`,
+		Down: `DROP INDEX inbound_external_id;`,
+	},
+	{
+		Version: 17,
+		Name:    "pending message senders",
+		// A held SMS or email is answered by the sender's next message (0 = not sent by staff)
+		Up:   `ALTER TABLE pending_messages ADD COLUMN sender_id INTEGER NOT NULL DEFAULT 0;`,
+		Down: `ALTER TABLE pending_messages DROP COLUMN sender_id;`,
+	},
+	{
+		Version: 18,
+		Name:    "job heartbeats",
+		// Workers stamp the jobs they are on, so a new process only takes back those whose worker died
+		Up:   `ALTER TABLE jobs ADD COLUMN heartbeat_at TEXT;`,
+		Down: `ALTER TABLE jobs DROP COLUMN heartbeat_at;`,
+	},
+}
+
+var sqliteDialect = dialect{name: "sqlite", migrations: sqliteMigrations}
+
+// Migrations returns the known schema history, oldest first
+func Migrations() []Migration {
+	return append([]Migration(nil), sqliteMigrations...)
+}
+
+// LatestVersion is the schema version this binary expects
+func LatestVersion() int {
+	return sqliteMigrations[len(sqliteMigrations)-1].Version
+}
+
+// SchemaVersion returns the version of the last applied migration (0 = empty or pre-migration database)
+func SchemaVersion(db *sql.DB) (int, error) {
+	if err := ensureVersionTable(db); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	return version, err
+}
+
+// Migrate moves the schema up or down to the target version, one migration per transaction.
+// A database newer than this binary is refused rather than silently misread.
+func Migrate(db *sql.DB, target int) error {
+	return migrate(NewSQLiteStore(db), target)
+}
+
+func migrate(s *SQLStore, target int) error {
+	migrations := s.dialect.migrations
+	latest := migrations[len(migrations)-1].Version
+	if target < 0 || target > latest {
+		return fmt.Errorf("unknown schema version %d (latest is %d)", target, latest)
+	}
+
+	current, err := SchemaVersion(s.db)
+	if err != nil {
+		return err
+	}
+	if current > latest {
+		return fmt.Errorf("database schema v%d is newer than this binary (v%d)", current, latest)
+	}
+
+	// 1. Up: apply everything after current, in order
+	for _, m := range migrations {
+		if m.Version <= current || m.Version > target {
+			continue
+		}
+		if err := s.apply(m, m.Up, "INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)",
+			m.Version, m.Name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			return err
+		}
+	}
+
+	// 2. Down: revert newest first
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+		if err := s.apply(m, m.Down, "DELETE FROM schema_version WHERE version = ?", m.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply runs one migration script and records it in schema_version atomically
+func (s *SQLStore) apply(m Migration, script, record string, args ...any) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return fmt.Errorf("migration %03d (%s): %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.Exec(s.rebind(record), args...); err != nil {
+		return fmt.Errorf("migration %03d (%s): %w", m.Version, m.Name, err)
+	}
+	return tx.Commit()
+}
+
+func ensureVersionTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT,
+		applied_at TEXT
+	)`)
+	return err
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+func (s *SQLStore) AddPending(p models.PendingMessage) (int, error) {
+	return s.insert(s.db, "INSERT INTO pending_messages (text, question, draft, sender_id, created_at) VALUES (?, ?, ?, ?, ?)",
+		p.Text, p.Question, p.Draft, p.SenderID, now())
+}
+
+// UpdatePending stores the next question and the draft completed so far
+func (s *SQLStore) UpdatePending(p models.PendingMessage) error {
+	res, err := s.exec("UPDATE pending_messages SET question = ?, draft = ? WHERE id = ?", p.Question, p.Draft, p.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("pending message %d: %w", p.ID, sql.ErrNoRows)
+	}
+	return nil
+}
+
+func (s *SQLStore) Pending(id int) (models.PendingMessage, error) {
+	p, err := scanPending(s.queryRow("SELECT id, text, question, draft, sender_id, created_at FROM pending_messages WHERE id = ?", id))
+	if err != nil {
+		return p, fmt.Errorf("pending message %d: %w", id, err)
+	}
+	return p, nil
+}
+
+// PendingFrom returns the latest message an employee sent that is still waiting for their answer
+func (s *SQLStore) PendingFrom(employeeID int) (models.PendingMessage, bool, error) {
+	p, err := scanPending(s.queryRow(`SELECT id, text, question, draft, sender_id, created_at FROM pending_messages
+		WHERE sender_id = ? ORDER BY id DESC LIMIT 1`, employeeID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, false, nil
+	}
+	return p, err == nil, err
+}
+
+// PendingMessages returns the messages still waiting for an answer, oldest first
+func (s *SQLStore) PendingMessages() ([]models.PendingMessage, error) {
+	rows, err := s.query("SELECT id, text, question, draft, sender_id, created_at FROM pending_messages ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []models.PendingMessage
+	for rows.Next() {
+		p, err := scanPending(rows)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
+
+// ResolvePending saves what the completed message asked for (possibly nothing) and forgets it
+func (s *SQLStore) ResolvePending(id int, r models.Requests) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(s.rebind("DELETE FROM pending_messages WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("pending message %d: %w", id, sql.ErrNoRows)
+	}
+	if err := s.insertRequests(tx, r); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanPending(s scanner) (models.PendingMessage, error) {
+	var p models.PendingMessage
+	var created string
+	if err := s.Scan(&p.ID, &p.Text, &p.Question, &p.Draft, &p.SenderID, &created); err != nil {
+		return p, err
+	}
+	p.CreatedAt, _ = time.Parse(time.RFC3339, created)
+	return p, nil
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// ErrRequestDecided is returned when deciding a request that is no longer pending
+var ErrRequestDecided = errors.New("request already decided")
+
+// SaveRequests writes everything one staff message asked for, all or none
+func (s *SQLStore) SaveRequests(r models.Requests) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.insertRequests(tx, r); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) insertRequests(tx *sql.Tx, r models.Requests) error {
+	if err := s.insertUnavailabilities(tx, r.Unavailability); err != nil {
+		return err
+	}
+	for _, o := range r.OnCall {
+		if _, err := tx.Exec(s.rebind("INSERT INTO on_call (employee_id, start_hour, end_hour, day) VALUES (?, ?, ?, ?)"),
+			o.EmployeeID, o.StartHour, o.EndHour, nullDate(o.Date)); err != nil {
+			return err
+		}
+	}
+	for _, t := range r.TimeOff {
+		if _, err := tx.Exec(s.rebind(`INSERT INTO time_off_requests (employee_id, day, start_hour, end_hour, reason, message, status, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+			t.EmployeeID, nullDate(t.Date), t.StartHour, t.EndHour, t.Reason, t.Message, models.RequestPending, now()); err != nil {
+			return err
+		}
+	}
+	for _, w := range r.Swaps {
+		if _, err := tx.Exec(s.rebind(`INSERT INTO swap_requests (employee_id, with_employee_id, day, start_hour, end_hour, message, status, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+			w.EmployeeID, w.WithID, nullDate(w.Date), w.StartHour, w.EndHour, w.Message, models.RequestPending, now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TimeOffRequests lists requests with the given status ("" = any), oldest first
+func (s *SQLStore) TimeOffRequests(status string) ([]models.TimeOffRequest, error) {
+	where, args := "", []any(nil)
+	if status != "" {
+		where, args = " WHERE t.status = ?", []any{status}
+	}
+	rows, err := s.query(`SELECT t.id, t.employee_id, COALESCE(e.name, ''), COALESCE(t.day, ''), t.start_hour, t.end_hour,
+		t.reason, t.message, t.status, t.created_at
+		FROM time_off_requests t LEFT JOIN employees e ON e.id = t.employee_id`+where+` ORDER BY t.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.TimeOffRequest
+	for rows.Next() {
+		var t models.TimeOffRequest
+		var day, created string
+		if err := rows.Scan(&t.ID, &t.EmployeeID, &t.EmployeeName, &day, &t.StartHour, &t.EndHour,
+			&t.Reason, &t.Message, &t.Status, &created); err != nil {
+			return nil, err
+		}
+		t.Date, _ = parseDate(day)
+		t.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		requests = append(requests, t)
+	}
+	return requests, rows.Err()
+}
+
+// DecideTimeOff approves or denies a pending request. Approved time off is saved as
+// unavailability in the same transaction.
+func (s *SQLStore) DecideTimeOff(id int, status string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.decide(tx, "time_off_requests", id, status); err != nil {
+		return err
+	}
+	if status == models.RequestApproved {
+		var u models.Unavailability
+		var day string
+		err := tx.QueryRow(s.rebind("SELECT employee_id, COALESCE(day, ''), start_hour, end_hour, reason FROM time_off_requests WHERE id = ?"), id).
+			Scan(&u.EmployeeID, &day, &u.StartHour, &u.EndHour, &u.Reason)
+		if err != nil {
+			return err
+		}
+		u.Date, _ = parseDate(day)
+		if u.Reason == "" {
+			u.Reason = "Time off"
+		}
+		if err := s.insertUnavailabilities(tx, []models.Unavailability{u}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SwapRequests lists requests with the given status ("" = any), oldest first
+func (s *SQLStore) SwapRequests(status string) ([]models.SwapRequest, error) {
+	where, args := "", []any(nil)
+	if status != "" {
+		where, args = " WHERE w.status = ?", []any{status}
+	}
+	rows, err := s.query(`SELECT w.id, w.employee_id, COALESCE(a.name, ''), w.with_employee_id, COALESCE(b.name, ''),
+		COALESCE(w.day, ''), w.start_hour, w.end_hour, w.message, w.status, w.created_at
+		FROM swap_requests w
+		LEFT JOIN employees a ON a.id = w.employee_id
+		LEFT JOIN employees b ON b.id = w.with_employee_id`+where+` ORDER BY w.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.SwapRequest
+	for rows.Next() {
+		var w models.SwapRequest
+		var day, created string
+		if err := rows.Scan(&w.ID, &w.EmployeeID, &w.EmployeeName, &w.WithID, &w.WithName,
+			&day, &w.StartHour, &w.EndHour, &w.Message, &w.Status, &created); err != nil {
+			return nil, err
+		}
+		w.Date, _ = parseDate(day)
+		w.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		requests = append(requests, w)
+	}
+	return requests, rows.Err()
+}
+
+// DecideSwap approves or denies a pending swap. The roster itself is edited by the manager.
+func (s *SQLStore) DecideSwap(id int, status string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.decide(tx, "swap_requests", id, status); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// decide moves a pending request of table to status
+func (s *SQLStore) decide(tx *sql.Tx, table string, id int, status string) error {
+	if status != models.RequestApproved && status != models.RequestDenied {
+		return fmt.Errorf("invalid decision %q (want %s or %s)", status, models.RequestApproved, models.RequestDenied)
+	}
+	res, err := tx.Exec(s.rebind("UPDATE "+table+" SET status = ? WHERE id = ? AND status = ?"), status, id, models.RequestPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil
+	}
+	var current string
+	if err := tx.QueryRow(s.rebind("SELECT status FROM "+table+" WHERE id = ?"), id).Scan(&current); err != nil {
+		return fmt.Errorf("request %d: %w", id, err)
+	}
+	return fmt.Errorf("request %d is %s: %w", id, current, ErrRequestDecided)
+}
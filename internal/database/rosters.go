@@ -0,0 +1,239 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// DateLayout is how calendar days are stored (SQLite has no DATE type)
+const DateLayout = "2006-01-02"
+
+// SaveRoster stores a roster as a new version and returns its ID.
+// Assignments keep a copy of the employee's name, rate and skill at save time,
+// so old versions stay readable after the crew changes.
+func SaveRoster(db *sql.DB, roster *models.Roster, status, author string) (int, error) {
+	return NewSQLiteStore(db).SaveRoster(roster, status, author)
+}
+
+// LoadRoster reads a saved version back into a Roster
+func LoadRoster(db *sql.DB, id int) (*models.Roster, models.RosterVersion, error) {
+	return NewSQLiteStore(db).LoadRoster(id)
+}
+
+// ListRosterVersions returns every saved version, newest first
+func ListRosterVersions(db *sql.DB) ([]models.RosterVersion, error) {
+	return NewSQLiteStore(db).ListRosterVersions()
+}
+
+// PublishRoster promotes a draft version to published
+func PublishRoster(db *sql.DB, id int) error {
+	return NewSQLiteStore(db).PublishRoster(id)
+}
+
+// EffectiveRosters loads the roster in force for each day (and store) in [from, to):
+// the newest published version, or the newest draft if none is published.
+// Undated versions are ignored. Rosters come back in version order.
+func EffectiveRosters(store Store, from, to time.Time) ([]*models.Roster, []models.RosterVersion, error) {
+	versions, err := store.ListRosterVersions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 1. Pick one version per day and store (the list is newest first)
+	type slot struct {
+		day      string
+		location int
+	}
+	chosen := make(map[slot]models.RosterVersion)
+	for _, v := range versions {
+		if v.Date.IsZero() || v.Date.Before(from) || !v.Date.Before(to) {
+			continue
+		}
+		key := slot{formatDate(v.Date), v.LocationID}
+		current, ok := chosen[key]
+		if !ok || (current.Status != models.RosterPublished && v.Status == models.RosterPublished) {
+			chosen[key] = v
+		}
+	}
+
+	// 2. Load them in version order, so callers do not depend on map order
+	var picked []models.RosterVersion
+	for _, v := range chosen {
+		picked = append(picked, v)
+	}
+	sort.Slice(picked, func(i, j int) bool { return picked[i].ID < picked[j].ID })
+
+	var rosters []*models.Roster
+	for _, v := range picked {
+		roster, _, err := store.LoadRoster(v.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		rosters = append(rosters, roster)
+	}
+	return rosters, picked, nil
+}
+
+func (s *SQLStore) SaveRoster(roster *models.Roster, status, author string) (int, error) {
+	if status != models.RosterDraft && status != models.RosterPublished {
+		return 0, fmt.Errorf("invalid roster status %q", status)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := s.insertRoster(tx, roster, status, author)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// SaveChange saves the blocks, the locks and the rosters they lead to as drafts, all or none,
+// and returns the new version IDs in order
+func (s *SQLStore) SaveChange(blocks []models.Unavailability, locks []models.Lock, drafts []*models.Roster, author string) ([]int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.insertUnavailabilities(tx, blocks); err != nil {
+		return nil, err
+	}
+	for _, l := range locks {
+		if _, err := s.insertLock(tx, l); err != nil {
+			return nil, err
+		}
+	}
+	var versions []int
+	for _, roster := range drafts {
+		id, err := s.insertRoster(tx, roster, models.RosterDraft, author)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, id)
+	}
+	return versions, tx.Commit()
+}
+
+func (s *SQLStore) insertRoster(tx *sql.Tx, roster *models.Roster, status, author string) (int, error) {
+	id, err := s.insert(tx, `INSERT INTO roster_versions (roster_date, location_id, status, author, created_at, total_cost, unfilled)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		formatDate(roster.Date), s.writeLocation(roster.LocationID), status, author, time.Now().UTC().Format(time.RFC3339), roster.TotalCost, roster.Unfilled)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, a := range roster.Assignments {
+		_, err := tx.Exec(s.rebind(`INSERT INTO roster_assignments
+			(version_id, hour, employee_id, employee_name, hourly_rate, skill_level, is_senior)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`),
+			id, a.Hour, a.Employee.ID, a.Employee.Name, a.Employee.HourlyRate, a.Employee.SkillLevel, a.IsSenior)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+func (s *SQLStore) LoadRoster(id int) (*models.Roster, models.RosterVersion, error) {
+	v, err := scanVersion(s.queryRow(`SELECT id, roster_date, location_id, status, author, created_at, total_cost, unfilled
+		FROM roster_versions WHERE id = ?`, id))
+	if err != nil {
+		return nil, v, fmt.Errorf("roster version %d: %w", id, err)
+	}
+
+	rows, err := s.query(`SELECT hour, employee_id, employee_name, hourly_rate, skill_level, is_senior
+		FROM roster_assignments WHERE version_id = ? ORDER BY hour, id`, id)
+	if err != nil {
+		return nil, v, err
+	}
+	defer rows.Close()
+
+	roster := &models.Roster{Date: v.Date, LocationID: v.LocationID, TotalCost: v.TotalCost, Unfilled: v.Unfilled}
+	for rows.Next() {
+		var a models.Assignment
+		if err := rows.Scan(&a.Hour, &a.Employee.ID, &a.Employee.Name, &a.Employee.HourlyRate, &a.Employee.SkillLevel, &a.IsSenior); err != nil {
+			return nil, v, err
+		}
+		roster.Assignments = append(roster.Assignments, a)
+	}
+	return roster, v, rows.Err()
+}
+
+func (s *SQLStore) ListRosterVersions() ([]models.RosterVersion, error) {
+	where, args := s.scoped("", "location_id", nil)
+	rows, err := s.query(`SELECT id, roster_date, location_id, status, author, created_at, total_cost, unfilled
+		FROM roster_versions`+where+` ORDER BY id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []models.RosterVersion
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+func (s *SQLStore) PublishRoster(id int) error {
+	res, err := s.exec("UPDATE roster_versions SET status = ? WHERE id = ?", models.RosterPublished, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("roster version %d: %w", id, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanVersion(s scanner) (models.RosterVersion, error) {
+	var v models.RosterVersion
+	var date, created string
+	if err := s.Scan(&v.ID, &date, &v.LocationID, &v.Status, &v.Author, &created, &v.TotalCost, &v.Unfilled); err != nil {
+		return v, err
+	}
+	v.Date, _ = parseDate(date)
+	v.CreatedAt, _ = time.Parse(time.RFC3339, created)
+	return v, nil
+}
+
+func formatDate(d time.Time) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(DateLayout)
+}
+
+// nullDate is a day column value: NULL for the zero day (every day)
+func nullDate(d time.Time) any {
+	if d.IsZero() {
+		return nil
+	}
+	return formatDate(d)
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(DateLayout, s)
+}
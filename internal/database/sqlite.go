@@ -10,7 +10,22 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+// InitDB opens the database and applies any pending schema migrations
 func InitDB(dsn string) (*sql.DB, error) {
+	db, err := OpenDB(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Migrate(db, LatestVersion()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// OpenDB opens the database without touching the schema (used by `shiftopt migrate`)
+func OpenDB(dsn string) (*sql.DB, error) {
 	if dsn == "" {
 		dsn = "shiftopt.db" // Default to prod file
 	}
@@ -20,43 +35,37 @@ func InitDB(dsn string) (*sql.DB, error) {
 		return nil, err
 	}
 
-	schema := `
-	CREATE TABLE IF NOT EXISTS employees (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT,
-		hourly_rate REAL,
-		skill_level INTEGER
-	);
-	CREATE TABLE IF NOT EXISTS demands (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		hour_of_day INTEGER,
-		needed INTEGER
-	);
-	CREATE TABLE IF NOT EXISTS unavailability (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		employee_id INTEGER,
-		start_hour INTEGER,
-		end_hour INTEGER,
-		reason TEXT,
-		FOREIGN KEY(employee_id) REFERENCES employees(id)
-	);
-	`
-	_, err = db.Exec(schema)
-	return db, err
+	// Every connection to ":memory:" is a brand new, empty database.
+	// Pin the pool to one connection so transactions and queries see the same data.
+	if dsn == ":memory:" {
+		db.SetMaxOpenConns(1)
+	}
+	return db, nil
 }
 
 // AddUnavailability allows us to block specific slots
 func AddUnavailability(db *sql.DB, empID, start, end int, reason string) error {
-	_, err := db.Exec("INSERT INTO unavailability (employee_id, start_hour, end_hour, reason) VALUES (?, ?, ?, ?)",
-		empID, start, end, reason)
-	return err
+	return NewSQLiteStore(db).AddUnavailability(models.Unavailability{
+		EmployeeID: empID, StartHour: start, EndHour: end, Reason: reason,
+	})
+}
+
+// AddOnCall registers a standby window used when repairing a published roster
+func AddOnCall(db *sql.DB, empID, start, end int) error {
+	return NewSQLiteStore(db).AddOnCall(models.OnCall{EmployeeID: empID, StartHour: start, EndHour: end})
 }
 
+// SeedData replaces the crew and demand with demo data, and clears what the old crew left behind
 func SeedData(db *sql.DB) {
 	// 1. Initialize the Random Source based on current time
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	db.Exec("DELETE FROM employees; DELETE FROM demands;")
+	// Everything that points at employee IDs goes with the crew: the new crew may reuse the IDs.
+	// Stores, travel times and the parse cache stay.
+	db.Exec(`DELETE FROM roster_assignments; DELETE FROM jobs; DELETE FROM roster_versions;
+		DELETE FROM unavailability; DELETE FROM on_call; DELETE FROM assignment_locks; DELETE FROM employee_contacts;
+		DELETE FROM time_off_requests; DELETE FROM swap_requests; DELETE FROM pending_messages; DELETE FROM inbound_messages;
+		DELETE FROM employees; DELETE FROM demands;`)
 
 	// 2. Employees (We keep this pool stable for now, representing "Fixed Staff")
 	employees := []models.Employee{
@@ -118,8 +127,6 @@ func ProcessNaturalLanguageConstraint(db *sql.DB, text string) error {
 
 // Let's create a simpler helper that finds ID by Name
 func GetEmployeeIDByName(db *sql.DB, name string) (int, error) {
-	var id int
-	err := db.QueryRow("SELECT id FROM employees WHERE name = ?", name).Scan(&id)
-	return id, err
+	return NewSQLiteStore(db).EmployeeIDByName(name)
 }
 
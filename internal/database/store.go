@@ -0,0 +1,333 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// Store is the persistence boundary: everything the schedulers and commands read or write.
+// SQLite and PostgreSQL share one implementation (SQLStore); only the SQL dialect differs.
+//
+// A Store sees every location until it is scoped with AtLocation. Scoped stores only read
+// that store's crew, demand, locks and roster versions, and write new rows to it.
+// Unscoped writes go to models.DefaultLocation.
+type Store interface {
+	// Stores and the travel times between them
+	AtLocation(id int) Store
+	Locations() ([]models.Location, error)
+	LocationByName(name string) (models.Location, error)
+	AddLocation(l models.Location) (int, error)
+	SetLocationTimezone(id int, zone string) error
+	TravelTimes() ([]models.TravelTime, error)
+	SetTravelTime(t models.TravelTime) error
+
+	// Workforce. Employees and EmployeeIDByName only see active staff.
+	// UpdateEmployee keeps the home store when LocationID is 0, or moves the employee to the scoped store.
+	// SaveEmployees hires or updates a whole crew list in one transaction, reactivating who is on it.
+	Employees() ([]models.Employee, error)
+	AllEmployees() ([]models.Employee, error)
+	EmployeeIDByName(name string) (int, error)
+	AddEmployee(e models.Employee) (int, error)
+	UpdateEmployee(e models.Employee) error
+	SaveEmployees(crew []models.Employee) error
+	DeactivateEmployee(id int) error
+
+	// Demand curve, ordered by hour
+	Demands() ([]models.Demand, error)
+	ReplaceDemands(demands []models.Demand) error
+
+	// Availability (the "Anti-Roster") and standby windows.
+	// On a store scoped with OnDay, Unavailability and OnCall only return the windows that apply that day.
+	// AddUnavailabilities saves all the blocks or none.
+	OnDay(day time.Time) Store
+	Unavailability() ([]models.Unavailability, error)
+	AddUnavailability(u models.Unavailability) error
+	AddUnavailabilities(blocks []models.Unavailability) error
+	OnCall() ([]models.OnCall, error)
+	AddOnCall(o models.OnCall) error
+
+	// Messages held until the sender answers a clarification question.
+	// ResolvePending saves the blocks and drops the message in one transaction.
+	// PendingFrom finds the latest one an employee sent (ok = false when there is none).
+	AddPending(p models.PendingMessage) (int, error)
+	UpdatePending(p models.PendingMessage) error
+	Pending(id int) (models.PendingMessage, error)
+	PendingMessages() ([]models.PendingMessage, error)
+	PendingFrom(employeeID int) (p models.PendingMessage, ok bool, err error)
+	ResolvePending(id int, r models.Requests) error
+
+	// Parse results of the language models, by content hash
+	CachedParse(key string) (result string, ok bool, err error)
+	CacheParse(key, result string) error
+
+	// Staff phone numbers and email addresses, and the messages received from them.
+	// Addresses are normalised; EmployeeByContact only finds active staff.
+	// AddInbound keeps each gateway ID once per channel (ErrInboundReceived).
+	Contacts() ([]models.Contact, error)
+	AddContact(c models.Contact) (int, error)
+	RemoveContact(id int) error
+	EmployeeByContact(address string) (int, error)
+	AddInbound(m models.InboundMessage) (int, error)
+	UpdateInbound(m models.InboundMessage) error
+	InboundMessages() ([]models.InboundMessage, error)
+	InboundReceived(channel, externalID string) (bool, error)
+
+	// Staff requests. SaveRequests writes everything one message asked for in one transaction.
+	// Only pending requests can be decided; approving time off saves it as unavailability.
+	SaveRequests(r models.Requests) error
+	TimeOffRequests(status string) ([]models.TimeOffRequest, error)
+	DecideTimeOff(id int, status string) error
+	SwapRequests(status string) ([]models.SwapRequest, error)
+	DecideSwap(id int, status string) error
+
+	// Manager overrides
+	Locks() ([]models.Lock, error)
+	AddLock(l models.Lock) (int, error)
+	RemoveLock(id int) error
+
+	// Roster versions. SaveChange writes what a manager command applies in one transaction:
+	// its blocks, its locks and the rosters re-run with them, as drafts.
+	SaveRoster(roster *models.Roster, status, author string) (int, error)
+	SaveChange(blocks []models.Unavailability, locks []models.Lock, drafts []*models.Roster, author string) ([]int, error)
+	LoadRoster(id int) (*models.Roster, models.RosterVersion, error)
+	ListRosterVersions() ([]models.RosterVersion, error)
+	PublishRoster(id int) error
+
+	// Scheduling jobs. ClaimJob hands the oldest queued job to exactly one worker,
+	// who touches it while it runs; RequeueStaleJobs takes back those no one touched since before.
+	EnqueueJob(j models.Job) (int, error)
+	ClaimJob() (models.Job, bool, error)
+	UpdateJobProgress(id int, progress float64) error
+	FinishJob(id int, status string, rosterID int, errMsg string) error
+	CancelJob(id int) error
+	RequeueJob(id int) error
+	TouchJob(id int) error
+	RequeueStaleJobs(before time.Time) (int, error)
+	Job(id int) (models.Job, error)
+	Jobs() ([]models.Job, error)
+}
+
+// dialect captures what differs between the SQL backends
+type dialect struct {
+	name       string
+	numbered   bool // Placeholders are $1, $2... instead of ?
+	migrations []Migration
+}
+
+// SQLStore implements Store on top of database/sql
+type SQLStore struct {
+	db       *sql.DB
+	dialect  dialect
+	location int       // 0 = every location
+	day      time.Time // Zero = every day
+}
+
+// NewSQLiteStore wraps a database opened with InitDB
+func NewSQLiteStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: sqliteDialect}
+}
+
+// DB exposes the underlying connection pool
+func (s *SQLStore) DB() *sql.DB {
+	return s.db
+}
+
+// AtLocation returns a view of the same database scoped to one store
+func (s *SQLStore) AtLocation(id int) Store {
+	return &SQLStore{db: s.db, dialect: s.dialect, location: id, day: s.day}
+}
+
+// OnDay returns a view of the same database scoped to one calendar day
+func (s *SQLStore) OnDay(day time.Time) Store {
+	return &SQLStore{db: s.db, dialect: s.dialect, location: s.location, day: day}
+}
+
+// writeLocation is where new rows go when the caller does not say
+func (s *SQLStore) writeLocation(id int) int {
+	if id != 0 {
+		return id
+	}
+	if s.location != 0 {
+		return s.location
+	}
+	return models.DefaultLocation
+}
+
+// scoped appends the location filter to a WHERE clause (cond may be empty)
+func (s *SQLStore) scoped(cond, column string, args []any) (string, []any) {
+	if s.location == 0 {
+		if cond == "" {
+			return "", args
+		}
+		return " WHERE " + cond, args
+	}
+	if cond == "" {
+		return " WHERE " + column + " = ?", append(args, s.location)
+	}
+	return " WHERE " + cond + " AND " + column + " = ?", append(args, s.location)
+}
+
+// rebind rewrites ? placeholders for dialects that number them
+func (s *SQLStore) rebind(query string) string {
+	if !s.dialect.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *SQLStore) exec(query string, args ...any) (sql.Result, error) {
+	return s.db.Exec(s.rebind(query), args...)
+}
+
+func (s *SQLStore) query(query string, args ...any) (*sql.Rows, error) {
+	return s.db.Query(s.rebind(query), args...)
+}
+
+func (s *SQLStore) queryRow(query string, args ...any) *sql.Row {
+	return s.db.QueryRow(s.rebind(query), args...)
+}
+
+// insert runs an INSERT ... RETURNING id (LastInsertId is not portable)
+func (s *SQLStore) insert(q interface {
+	QueryRow(string, ...any) *sql.Row
+}, query string, args ...any) (int, error) {
+	var id int
+	err := q.QueryRow(s.rebind(query+" RETURNING id"), args...).Scan(&id)
+	return id, err
+}
+
+func (s *SQLStore) Demands() ([]models.Demand, error) {
+	where, args := s.scoped("", "location_id", nil)
+	rows, err := s.query("SELECT id, hour_of_day, needed, location_id FROM demands"+where+" ORDER BY hour_of_day", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var demands []models.Demand
+	for rows.Next() {
+		var d models.Demand
+		if err := rows.Scan(&d.ID, &d.HourOfDay, &d.Needed, &d.LocationID); err != nil {
+			return nil, err
+		}
+		demands = append(demands, d)
+	}
+	return demands, rows.Err()
+}
+
+// ReplaceDemands swaps the demand curve of one location in one transaction
+func (s *SQLStore) ReplaceDemands(demands []models.Demand) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	location := s.writeLocation(0)
+	if _, err := tx.Exec(s.rebind("DELETE FROM demands WHERE location_id = ?"), location); err != nil {
+		return err
+	}
+	for _, d := range demands {
+		if _, err := tx.Exec(s.rebind("INSERT INTO demands (hour_of_day, needed, location_id) VALUES (?, ?, ?)"), d.HourOfDay, d.Needed, location); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) Unavailability() ([]models.Unavailability, error) {
+	where, args := "", []any(nil)
+	if !s.day.IsZero() {
+		where, args = " WHERE u.day IS NULL OR u.day = ?", []any{formatDate(s.day)}
+	}
+	rows, err := s.query(`SELECT u.employee_id, COALESCE(e.name, ''), u.start_hour, u.end_hour, COALESCE(u.reason, ''), COALESCE(u.day, '')
+		FROM unavailability u LEFT JOIN employees e ON e.id = u.employee_id`+where+`
+		ORDER BY u.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []models.Unavailability
+	for rows.Next() {
+		var u models.Unavailability
+		var day string
+		if err := rows.Scan(&u.EmployeeID, &u.EmployeeName, &u.StartHour, &u.EndHour, &u.Reason, &day); err != nil {
+			return nil, err
+		}
+		u.Date, _ = parseDate(day)
+		blocks = append(blocks, u)
+	}
+	return blocks, rows.Err()
+}
+
+func (s *SQLStore) AddUnavailability(u models.Unavailability) error {
+	return s.AddUnavailabilities([]models.Unavailability{u})
+}
+
+func (s *SQLStore) AddUnavailabilities(blocks []models.Unavailability) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.insertUnavailabilities(tx, blocks); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) insertUnavailabilities(tx *sql.Tx, blocks []models.Unavailability) error {
+	for _, u := range blocks {
+		if _, err := tx.Exec(s.rebind("INSERT INTO unavailability (employee_id, start_hour, end_hour, reason, day) VALUES (?, ?, ?, ?, ?)"),
+			u.EmployeeID, u.StartHour, u.EndHour, u.Reason, nullDate(u.Date)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) OnCall() ([]models.OnCall, error) {
+	where, args := "", []any(nil)
+	if !s.day.IsZero() {
+		where, args = " WHERE day IS NULL OR day = ?", []any{formatDate(s.day)}
+	}
+	rows, err := s.query("SELECT employee_id, start_hour, end_hour, COALESCE(day, '') FROM on_call"+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []models.OnCall
+	for rows.Next() {
+		var o models.OnCall
+		var day string
+		if err := rows.Scan(&o.EmployeeID, &o.StartHour, &o.EndHour, &day); err != nil {
+			return nil, err
+		}
+		o.Date, _ = parseDate(day)
+		windows = append(windows, o)
+	}
+	return windows, rows.Err()
+}
+
+func (s *SQLStore) AddOnCall(o models.OnCall) error {
+	_, err := s.exec("INSERT INTO on_call (employee_id, start_hour, end_hour, day) VALUES (?, ?, ?, ?)",
+		o.EmployeeID, o.StartHour, o.EndHour, nullDate(o.Date))
+	return err
+}
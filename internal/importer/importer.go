@@ -0,0 +1,203 @@
+// Package importer loads the crew, the demand curve, unavailability and hand-edited rosters
+// from CSV or JSON files.
+// Files are validated row by row before anything is written: one bad row rejects the whole file.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RowError is one rejected row. Row is the line number for CSV (the header is line 1)
+// and the 1-based position in the array for JSON.
+type RowError struct {
+	Row     int
+	Field   string
+	Message string
+}
+
+func (e RowError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+	}
+	return fmt.Sprintf("row %d, %s: %s", e.Row, e.Field, e.Message)
+}
+
+// ValidationError lists every rejected row of a file
+type ValidationError struct {
+	File string
+	Rows []RowError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d invalid row(s)", e.File, len(e.Rows))
+	for _, r := range e.Rows {
+		b.WriteString("\n  " + r.Error())
+	}
+	return b.String()
+}
+
+// record is one input row, keyed by lower-case column name
+type record struct {
+	row    int
+	fields map[string]string
+}
+
+// readFile picks the decoder from the file extension (.csv or .json)
+func readFile(path string) ([]record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return read(f, strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."), path)
+}
+
+// read decodes a stream in the given format ("csv" or "json"); name is used in errors
+func read(r io.Reader, format, name string) ([]record, error) {
+	switch format {
+	case "csv":
+		return readCSV(r)
+	case "json":
+		return readJSON(r)
+	default:
+		return nil, fmt.Errorf("%s: unsupported file type (want .csv or .json)", name)
+	}
+}
+
+// readCSV expects a header row naming the columns
+func readCSV(r io.Reader) ([]record, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty file: a header row is required")
+	}
+
+	header := rows[0]
+	var records []record
+	for i, row := range rows[1:] {
+		rec := record{row: i + 2, fields: make(map[string]string)}
+		for j, col := range header {
+			if j < len(row) {
+				rec.fields[strings.ToLower(strings.TrimSpace(col))] = strings.TrimSpace(row[j])
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// readJSON expects an array of flat objects, or an object holding them in "rows"
+// (the roster JSON export). Numbers and strings are both accepted.
+func readJSON(r io.Reader) ([]record, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	var rows []map[string]any
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "{") {
+		var doc struct {
+			Rows []map[string]any `json:"rows"`
+		}
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		rows = doc.Rows
+	} else if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, err
+	}
+
+	var records []record
+	for i, row := range rows {
+		rec := record{row: i + 1, fields: make(map[string]string)}
+		for k, v := range row {
+			switch v := v.(type) {
+			case string:
+				rec.fields[strings.ToLower(k)] = strings.TrimSpace(v)
+			case float64:
+				rec.fields[strings.ToLower(k)] = strconv.FormatFloat(v, 'f', -1, 64)
+			case nil:
+			default:
+				rec.fields[strings.ToLower(k)] = fmt.Sprint(v)
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// checker collects the errors of one record while its fields are parsed
+type checker struct {
+	rec  record
+	errs *[]RowError
+}
+
+func (c checker) fail(field, format string, args ...any) {
+	*c.errs = append(*c.errs, RowError{Row: c.rec.row, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c checker) text(field string, required bool) string {
+	v := c.rec.fields[field]
+	if v == "" && required {
+		c.fail(field, "required")
+	}
+	return v
+}
+
+func (c checker) integer(field string, min, max int) int {
+	v, ok := c.rec.fields[field]
+	if !ok || v == "" {
+		c.fail(field, "required")
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		c.fail(field, "%q is not a whole number", v)
+		return 0
+	}
+	if n < min || n > max {
+		c.fail(field, "%d is out of range [%d, %d]", n, min, max)
+	}
+	return n
+}
+
+// boolean reads an optional yes/no column (missing = false)
+func (c checker) boolean(field string) bool {
+	v := strings.ToLower(c.rec.fields[field])
+	switch v {
+	case "", "false", "no", "0":
+		return false
+	case "true", "yes", "1":
+		return true
+	}
+	c.fail(field, "%q is not true or false", v)
+	return false
+}
+
+func (c checker) positive(field string) float64 {
+	v, ok := c.rec.fields[field]
+	if !ok || v == "" {
+		c.fail(field, "required")
+		return 0
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		c.fail(field, "%q is not a number", v)
+		return 0
+	}
+	if f <= 0 {
+		c.fail(field, "must be positive")
+	}
+	return f
+}
@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// Result says what an import changed
+type Result struct {
+	Added   int
+	Updated int
+}
+
+// Employees imports columns name, hourly_rate, skill_level (1 = Junior, 2 = Senior)
+// and optionally floats (true/false). New hires join the Store's location.
+// Employees are matched by name: known names are updated (and rehired if they had left),
+// new names are hired.
+func Employees(store database.Store, path string) (Result, error) {
+	var res Result
+	records, err := readFile(path)
+	if err != nil {
+		return res, err
+	}
+
+	// 1. Validate every row
+	var errs []RowError
+	var crew []models.Employee
+	seen := make(map[string]int)
+	for _, rec := range records {
+		c := checker{rec: rec, errs: &errs}
+		e := models.Employee{
+			Name:       c.text("name", true),
+			HourlyRate: c.positive("hourly_rate"),
+			SkillLevel: c.integer("skill_level", 1, 2),
+			Floats:     c.boolean("floats"),
+		}
+		if first, dup := seen[e.Name]; dup && e.Name != "" {
+			c.fail("name", "duplicate of row %d", first)
+		}
+		seen[e.Name] = rec.row
+		crew = append(crew, e)
+	}
+	if len(errs) > 0 {
+		return res, &ValidationError{File: path, Rows: errs}
+	}
+
+	// 2. Upsert by name, all or none. Someone on the list who had left is active again.
+	existing, err := store.AllEmployees()
+	if err != nil {
+		return res, err
+	}
+	byName := make(map[string]models.Employee)
+	for _, e := range existing {
+		byName[e.Name] = e
+	}
+	for i, e := range crew {
+		old, ok := byName[e.Name]
+		if !ok {
+			res.Added++
+			continue
+		}
+		crew[i].ID = old.ID
+		if _, set := records[i].fields["floats"]; !set {
+			crew[i].Floats = old.Floats
+		}
+		res.Updated++
+	}
+	if err := store.SaveEmployees(crew); err != nil {
+		return Result{}, err
+	}
+	return res, nil
+}
+
+// Demands imports columns hour_of_day (0-23), needed, and replaces the whole demand curve
+func Demands(store database.Store, path string) (Result, error) {
+	var res Result
+	records, err := readFile(path)
+	if err != nil {
+		return res, err
+	}
+
+	var errs []RowError
+	var demands []models.Demand
+	seen := make(map[int]int)
+	for _, rec := range records {
+		c := checker{rec: rec, errs: &errs}
+		d := models.Demand{
+			HourOfDay: c.integer("hour_of_day", 0, 23),
+			Needed:    c.integer("needed", 0, 1000),
+		}
+		if first, dup := seen[d.HourOfDay]; dup {
+			c.fail("hour_of_day", "duplicate of row %d", first)
+		}
+		seen[d.HourOfDay] = rec.row
+		demands = append(demands, d)
+	}
+	if len(errs) > 0 {
+		return res, &ValidationError{File: path, Rows: errs}
+	}
+
+	if err := store.ReplaceDemands(demands); err != nil {
+		return res, err
+	}
+	res.Added = len(demands)
+	return res, nil
+}
+
+// Unavailability imports columns employee, start_hour, end_hour (exclusive), reason.
+// The employee must already exist and be active.
+func Unavailability(store database.Store, path string) (Result, error) {
+	var res Result
+	records, err := readFile(path)
+	if err != nil {
+		return res, err
+	}
+
+	var errs []RowError
+	var blocks []models.Unavailability
+	for _, rec := range records {
+		c := checker{rec: rec, errs: &errs}
+		u := models.Unavailability{
+			EmployeeName: c.text("employee", true),
+			StartHour:    c.integer("start_hour", 0, 23),
+			EndHour:      c.integer("end_hour", 1, 24),
+			Reason:       c.text("reason", false),
+		}
+		if u.EndHour <= u.StartHour {
+			c.fail("end_hour", "must be after start_hour (%d)", u.StartHour)
+		}
+		if u.EmployeeName != "" {
+			id, err := store.EmployeeIDByName(u.EmployeeName)
+			if err != nil {
+				c.fail("employee", "unknown or inactive employee %q", u.EmployeeName)
+			}
+			u.EmployeeID = id
+		}
+		blocks = append(blocks, u)
+	}
+	if len(errs) > 0 {
+		return res, &ValidationError{File: path, Rows: errs}
+	}
+
+	if err := store.AddUnavailabilities(blocks); err != nil {
+		return res, err
+	}
+	res.Added = len(blocks)
+	return res, nil
+}
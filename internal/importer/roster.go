@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+// Roster reads back a roster exported as CSV or JSON, in the hourly or the shift layout,
+// so that edits made in a spreadsheet can be saved as a new version.
+// Employees are matched by name against the Store's active crew, and their current rate
+// and skill are used whatever the file says. Only the time and name columns are read.
+// The roster is neither validated nor saved: see SaveEdit.
+func Roster(store database.Store, path string) (*models.Roster, error) {
+	records, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return roster(store, records, path)
+}
+
+// ReadRoster is Roster for a stream in the given format ("csv" or "json")
+func ReadRoster(store database.Store, r io.Reader, format string) (*models.Roster, error) {
+	records, err := read(r, format, "roster")
+	if err != nil {
+		return nil, err
+	}
+	return roster(store, records, "roster")
+}
+
+func roster(store database.Store, records []record, name string) (*models.Roster, error) {
+	crew, err := store.Employees()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]models.Employee)
+	for _, e := range crew {
+		byName[e.Name] = e
+	}
+
+	// 1. Each row is one hour or one shift of one employee
+	var errs []RowError
+	roster := &models.Roster{}
+	seen := make(map[[2]int]int) // {employee, hour} -> row
+	for _, rec := range records {
+		rename(rec, "employee", "employee name")
+		rename(rec, "start_hour", "start")
+		rename(rec, "end_hour", "end")
+
+		c := checker{rec: rec, errs: &errs}
+		who := c.text("employee", true)
+		var start, end int
+		if _, hourly := rec.fields["hour"]; hourly {
+			start = c.clock("hour", 0, 23)
+			end = start + 1
+		} else {
+			start = c.clock("start_hour", 0, 23)
+			end = c.clock("end_hour", 1, 24)
+			if end <= start {
+				c.fail("end_hour", "%02d:00 is not after %02d:00", end, start)
+			}
+		}
+
+		e, known := byName[who]
+		if who != "" && !known {
+			c.fail("employee", "%q is not an active employee", who)
+		}
+		if !known || end <= start {
+			continue
+		}
+		for h := start; h < end; h++ {
+			if first, dup := seen[[2]int{e.ID, h}]; dup {
+				c.fail("employee", "%s already works at %02d:00 (row %d)", who, h, first)
+				break
+			}
+			seen[[2]int{e.ID, h}] = rec.row
+			roster.Assignments = append(roster.Assignments, models.Assignment{Hour: h, Employee: e, IsSenior: e.SkillLevel >= 2})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{File: name, Rows: errs}
+	}
+
+	// 2. Same order as the schedulers produce
+	sort.SliceStable(roster.Assignments, func(i, j int) bool {
+		a, b := roster.Assignments[i], roster.Assignments[j]
+		if a.Hour != b.Hour {
+			return a.Hour < b.Hour
+		}
+		return a.Employee.ID < b.Employee.ID
+	})
+	for _, a := range roster.Assignments {
+		roster.TotalCost += a.Employee.HourlyRate
+	}
+	return roster, nil
+}
+
+// rename moves an alternative column name to the one the parser reads
+func rename(rec record, to, from string) {
+	if _, ok := rec.fields[to]; ok {
+		return
+	}
+	if v, ok := rec.fields[from]; ok {
+		rec.fields[to] = v
+	}
+}
+
+// clock reads an hour written as "08:00" (the export) or 8
+func (c checker) clock(field string, min, max int) int {
+	v, ok := c.rec.fields[field]
+	if !ok || v == "" {
+		c.fail(field, "required")
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(v, ":00"))
+	if err != nil {
+		c.fail(field, "%q is not an hour (08:00 or 8)", v)
+		return 0
+	}
+	if n < min || n > max {
+		c.fail(field, "%d is out of range [%d, %d]", n, min, max)
+	}
+	return n
+}
+
+// Edit is the outcome of SaveEdit
+type Edit struct {
+	VersionID  int
+	Roster     *models.Roster
+	Base       models.RosterVersion // The version in force that day before the edit (ID 0 = none)
+	Diff       models.RosterDiff    // Base -> edited roster
+	Violations []models.Violation   // Every rule the edited roster breaks
+	Introduced []models.Violation   // The subset the base version did not already break
+}
+
+// SaveEdit validates an imported roster for one day, compares it with the version
+// in force that day, and saves it as a new draft. Violations are reported, not refused:
+// the manager decides whether to publish.
+func SaveEdit(store database.Store, roster *models.Roster, day time.Time, author string) (Edit, error) {
+	roster.Date = day
+	edit := Edit{Roster: roster}
+
+	// 1. The version it was edited from: the one in force that day, in the same store
+	bases, versions, err := database.EffectiveRosters(store, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return edit, err
+	}
+	known := make(map[string]bool)
+	if len(bases) == 1 {
+		edit.Base = versions[0]
+		roster.LocationID = edit.Base.LocationID
+		edit.Diff = scheduler.DiffRosters(bases[0], roster)
+		before, err := scheduler.Validate(store, bases[0])
+		if err != nil {
+			return edit, err
+		}
+		for _, v := range before {
+			known[v.Reason] = true
+		}
+	}
+
+	// 2. Check the edit and refresh its cost and unfilled count
+	edit.Violations, err = scheduler.Validate(store, roster)
+	if err != nil {
+		return edit, err
+	}
+	for _, v := range edit.Violations {
+		if !known[v.Reason] {
+			edit.Introduced = append(edit.Introduced, v)
+		}
+	}
+
+	// 3. Keep it as a draft
+	edit.VersionID, err = store.SaveRoster(roster, models.RosterDraft, author)
+	return edit, err
+}
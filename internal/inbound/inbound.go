@@ -0,0 +1,129 @@
+// Package inbound receives staff messages from outside: SMS through a Twilio-style webhook
+// and email from a maildir. The sender's number or address names the employee ("I" in the
+// text), the text is read like `shiftopt message`, and every message is kept with what
+// became of it for audit.
+package inbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// ErrDuplicate is returned for a message the gateway delivers again
+var ErrDuplicate = errors.New("message already received")
+
+// Message is one SMS or email as the gateway delivered it
+type Message struct {
+	Channel    string // models.ChannelSMS or models.ChannelEmail
+	From       string // Phone number or email address
+	Text       string
+	ExternalID string    // Twilio MessageSid or email Message-ID (optional)
+	ReceivedAt time.Time // Zero = now
+}
+
+// Receive reads one message from a staff contact and saves what it asks for, like
+// ai.RecordFrom. When the sender's last message is held on a question, this one is the
+// answer (see ai.Answer). Unknown senders and unreadable texts are not errors: the message is kept
+// as rejected, with the reason. The error is for a message that could not be kept at all.
+//
+// today is the store's local time now. Relative dates ("tomorrow") count from the day the
+// message was sent when it says (ReceivedAt, read in today's zone), so old mail keeps its meaning.
+func Receive(ctx context.Context, store database.Store, parser ai.ConstraintParser, m Message, today time.Time) (models.InboundMessage, ai.Outcome, error) {
+	record := models.InboundMessage{Channel: m.Channel, Sender: m.From, Text: m.Text, ExternalID: m.ExternalID, ReceivedAt: m.ReceivedAt}
+	if record.ReceivedAt.IsZero() {
+		record.ReceivedAt = time.Now()
+	}
+	if !m.ReceivedAt.IsZero() {
+		today = m.ReceivedAt.In(today.Location())
+	}
+
+	// 1. Each message once: kept before it is read, so a redelivery racing it is turned away
+	record.Status = models.InboundNew
+	id, err := store.AddInbound(record)
+	if errors.Is(err, database.ErrInboundReceived) {
+		return record, ai.Outcome{}, fmt.Errorf("%s %s: %w", m.Channel, m.ExternalID, ErrDuplicate)
+	}
+	if err != nil {
+		return record, ai.Outcome{}, err
+	}
+	record.ID = id
+
+	// 2. Who sent it, and what it asks for
+	var outcome ai.Outcome
+	record.EmployeeID, err = store.EmployeeByContact(m.From)
+	switch {
+	case err != nil:
+		record.Status, record.Error = models.InboundRejected, fmt.Sprintf("unknown sender %s", m.From)
+	case strings.TrimSpace(m.Text) == "":
+		record.Status, record.Error = models.InboundRejected, "empty message"
+	default:
+		outcome, err = read(ctx, store, parser, record.EmployeeID, m.Text, today)
+		switch {
+		case err != nil:
+			record.Status, record.Error = models.InboundRejected, err.Error()
+		case outcome.Held():
+			record.Status, record.PendingID = models.InboundHeld, outcome.PendingID
+		default:
+			record.Status = models.InboundSaved
+		}
+	}
+
+	// 3. Keep what became of it for audit
+	return record, outcome, store.UpdateInbound(record)
+}
+
+// read records a sender's message, or answers the question their last message is held on:
+// the reply to a question texted back comes in as a message of its own
+func read(ctx context.Context, store database.Store, parser ai.ConstraintParser, senderID int, text string, today time.Time) (ai.Outcome, error) {
+	p, open, err := store.PendingFrom(senderID)
+	if err != nil {
+		return ai.Outcome{}, err
+	}
+	if open {
+		return ai.Answer(store, p.ID, text, today)
+	}
+	return ai.RecordFrom(ctx, store, parser, senderID, text, today)
+}
+
+// Reply is the answer to send back to the sender: what was saved, the question of a held
+// message, or how to write one. Unknown senders get no reply ("").
+func Reply(record models.InboundMessage, outcome ai.Outcome) string {
+	switch {
+	case record.Status == models.InboundHeld:
+		return outcome.Question
+	case record.Status == models.InboundSaved:
+		var noted []string
+		for _, c := range outcome.Constraints {
+			noted = append(noted, describe(c))
+		}
+		return "Thanks, noted: " + strings.Join(noted, "; ") + "."
+	case record.EmployeeID == 0:
+		return ""
+	}
+	return `Sorry, I could not read that. Try e.g. "I can't work Friday morning" or "Can I take next Monday off?"`
+}
+
+// describe puts one constraint in a few words: "Fri 6 Mar 08:00-12:00 off"
+func describe(c ai.Constraint) string {
+	when := "every day"
+	if !c.Date.IsZero() {
+		when = c.Date.Format("Mon 2 Jan")
+	}
+	what := "off"
+	switch c.Intent {
+	case ai.IntentAvailable:
+		what = "available"
+	case ai.IntentSwap:
+		what = "swap with " + c.SwapWith.Name + " requested"
+	case ai.IntentTimeOff:
+		what = "time off requested"
+	}
+	return fmt.Sprintf("%s %02d:00-%02d:00 %s", when, c.StartHour, c.EndHour, what)
+}
@@ -0,0 +1,172 @@
+package inbound
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// Maildir is a mail directory (new/, cur/, tmp/) that staff email is delivered to, by the
+// mail server or by fetchmail, mbsync or getmail from an IMAP mailbox
+type Maildir string
+
+// Result is what became of one mail
+type Result struct {
+	File    string
+	Record  models.InboundMessage
+	Outcome ai.Outcome
+}
+
+// Receive reads the new mail, oldest first, and moves each one to cur/ once it is kept,
+// so it is read once. Mail that cannot be read is kept as rejected too.
+func (d Maildir) Receive(ctx context.Context, store database.Store, parser ai.ConstraintParser, today time.Time) ([]Result, error) {
+	entries, err := os.ReadDir(filepath.Join(string(d), "new"))
+	if err != nil {
+		return nil, fmt.Errorf("maildir %s: %w", d, err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names) // Delivery names start with the time
+
+	var results []Result
+	for _, name := range names {
+		res := Result{File: name}
+		m, err := readMailFile(filepath.Join(string(d), "new", name))
+		switch {
+		case err != nil:
+			res.Record = models.InboundMessage{Channel: models.ChannelEmail, Status: models.InboundRejected, Error: err.Error(), ReceivedAt: time.Now()}
+			res.Record.ID, err = store.AddInbound(res.Record)
+		default:
+			res.Record, res.Outcome, err = Receive(ctx, store, parser, m, today)
+		}
+		duplicate := errors.Is(err, ErrDuplicate)
+		if err != nil && !duplicate {
+			return results, err
+		}
+		if err := d.seen(name); err != nil {
+			return results, err
+		}
+		if !duplicate {
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// seen moves a new mail to cur/, flagged as seen
+func (d Maildir) seen(name string) error {
+	base, _, _ := strings.Cut(name, ":")
+	return os.Rename(filepath.Join(string(d), "new", name), filepath.Join(string(d), "cur", base+":2,S"))
+}
+
+func readMailFile(path string) (Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Message{}, err
+	}
+	defer f.Close()
+	return ReadMail(f)
+}
+
+// ReadMail reads one email: the sender's address, and the text of the first plain-text
+// part without quoted replies or signature (the subject when that is empty)
+func ReadMail(r io.Reader) (Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return Message{}, fmt.Errorf("unreadable email: %v", err)
+	}
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return Message{}, fmt.Errorf("email From %q: %v", msg.Header.Get("From"), err)
+	}
+	m := Message{Channel: models.ChannelEmail, From: from.Address, ExternalID: strings.Trim(msg.Header.Get("Message-ID"), "<> ")}
+	if date, err := msg.Header.Date(); err == nil {
+		m.ReceivedAt = date
+	}
+
+	body, err := plainText(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return m, fmt.Errorf("email from %s: %v", m.From, err)
+	}
+	m.Text = ownWords(body)
+	if m.Text == "" {
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil {
+			subject = msg.Header.Get("Subject")
+		}
+		m.Text = strings.TrimSpace(subject)
+	}
+	return m, nil
+}
+
+// plainText is the text/plain body, looked for in multipart mail
+func plainText(contentType, encoding string, body io.Reader) (string, error) {
+	media, params, err := mime.ParseMediaType(contentType)
+	if contentType == "" || err != nil {
+		media = "text/plain"
+	}
+	if strings.HasPrefix(media, "multipart/") {
+		parts := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := parts.NextRawPart()
+			if err == io.EOF {
+				return "", nil
+			}
+			if err != nil {
+				return "", err
+			}
+			text, err := plainText(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil || text != "" {
+				return text, err
+			}
+		}
+	}
+	if media != "text/plain" {
+		return "", nil
+	}
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	text, err := io.ReadAll(body)
+	return string(text), err
+}
+
+// ownWords drops the quoted message of a reply and the signature
+func ownWords(body string) string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+		if line == "--" || strings.HasPrefix(line, "-----Original Message") ||
+			(strings.HasPrefix(line, "On ") && strings.HasSuffix(line, "wrote:")) {
+			break
+		}
+		if !strings.HasPrefix(line, ">") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,67 @@
+package inbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// FromTwilio reads the form fields of an SMS webhook (From, Body and MessageSid), as
+// Twilio and compatible gateways post them
+func FromTwilio(form url.Values) (Message, error) {
+	m := Message{Channel: models.ChannelSMS, From: strings.TrimSpace(form.Get("From")), Text: strings.TrimSpace(form.Get("Body")),
+		ExternalID: form.Get("MessageSid")}
+	if m.From == "" {
+		return m, fmt.Errorf("the SMS has no From number")
+	}
+	return m, nil
+}
+
+// TwilioSignature is the X-Twilio-Signature a webhook request carries: the HMAC-SHA1,
+// keyed with the account's auth token, of the webhook URL followed by every POST field
+// name and value, sorted by name
+func TwilioSignature(authToken, webhookURL string, form url.Values) string {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(webhookURL))
+	for _, name := range names {
+		for _, value := range form[name] {
+			mac.Write([]byte(name + value))
+		}
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ValidTwilioSignature checks a request's X-Twilio-Signature
+func ValidTwilioSignature(authToken, webhookURL string, form url.Values, signature string) bool {
+	return hmac.Equal([]byte(TwilioSignature(authToken, webhookURL, form)), []byte(signature))
+}
+
+// TwiML is the webhook response that texts reply back to the sender (none when empty)
+func TwiML(reply string) []byte {
+	type message struct {
+		Body string `xml:",chardata"`
+	}
+	type response struct {
+		XMLName  xml.Name  `xml:"Response"`
+		Messages []message `xml:"Message"`
+	}
+	out := response{}
+	if reply != "" {
+		out.Messages = []message{{Body: reply}}
+	}
+	body, _ := xml.Marshal(out)
+	return append([]byte(xml.Header), body...)
+}
@@ -0,0 +1,237 @@
+// Package jobs runs scheduling jobs in the background. Jobs are persisted through the Store,
+// so they survive restarts and can be queued by one process and worked by another.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+// PollInterval is how often idle workers look for jobs queued by other processes,
+// and how often running jobs are touched
+const PollInterval = 2 * time.Second
+
+// StaleAfter is how long a running job can go untouched before it counts as abandoned
+const StaleAfter = 15 * PollInterval
+
+// Pool is a fixed set of workers taking jobs from the Store, oldest first
+type Pool struct {
+	store   database.Store
+	workers int
+	wake    chan struct{}
+
+	mu      sync.Mutex
+	running map[int]context.CancelFunc // Jobs this process is working on
+	wg      sync.WaitGroup
+}
+
+// NewPool prepares a pool of n workers (at least one) on an unscoped Store
+func NewPool(store database.Store, n int) *Pool {
+	if n < 1 {
+		n = 1
+	}
+	return &Pool{store: store, workers: n, wake: make(chan struct{}, n), running: make(map[int]context.CancelFunc)}
+}
+
+// Start requeues jobs a dead process left running, then starts the workers. Idle workers
+// keep looking for abandoned jobs, so one left by a crash just before the start is taken back too.
+// The workers stop when ctx is cancelled; jobs interrupted that way go back to the queue.
+func (p *Pool) Start(ctx context.Context) error {
+	if err := p.requeueStale(); err != nil {
+		return err
+	}
+
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.work(ctx)
+	}
+	return nil
+}
+
+// requeueStale puts back the running jobs no worker has touched for StaleAfter
+func (p *Pool) requeueStale() error {
+	n, err := p.store.RequeueStaleJobs(time.Now().Add(-StaleAfter))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("[Jobs] Requeued %d abandoned job(s)", n)
+	}
+	return nil
+}
+
+// Wait blocks until every worker has stopped
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+// Submit queues a scheduling run and returns the persisted job
+func (p *Pool) Submit(j models.Job) (models.Job, error) {
+	if j.Strategy == "" {
+		j.Strategy = scheduler.DefaultStrategy
+	}
+	if _, ok := scheduler.LookupStrategy(j.Strategy); !ok {
+		return j, fmt.Errorf("unknown strategy %q (known: %v)", j.Strategy, scheduler.StrategyNames())
+	}
+
+	id, err := p.store.EnqueueJob(j)
+	if err != nil {
+		return j, err
+	}
+	select {
+	case p.wake <- struct{}{}:
+	default: // Every worker already has a wake-up pending
+	}
+	return p.store.Job(id)
+}
+
+// Cancel withdraws a queued job, or stops a running one: at once when this process is
+// working on it, otherwise when its worker next checks the job (every PollInterval)
+func (p *Pool) Cancel(id int) error {
+	if err := p.store.CancelJob(id); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	cancel, running := p.running[id]
+	p.mu.Unlock()
+	if running {
+		cancel()
+	}
+	return nil
+}
+
+func (p *Pool) work(ctx context.Context) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain the queue, then sleep until something new arrives
+		for ctx.Err() == nil {
+			j, ok, err := p.store.ClaimJob()
+			if err != nil {
+				log.Printf("[Jobs] Claim failed: %v", err)
+				break
+			}
+			if !ok {
+				break
+			}
+			p.run(ctx, j)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-ticker.C:
+			if err := p.requeueStale(); err != nil {
+				log.Printf("[Jobs] Requeue failed: %v", err)
+			}
+		}
+	}
+}
+
+func (p *Pool) run(ctx context.Context, j models.Job) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p.mu.Lock()
+	p.running[j.ID] = cancel
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.running, j.ID)
+		p.mu.Unlock()
+	}()
+
+	// A cancel that came before the job was registered above, or from another process,
+	// is only a flag on the job
+	if p.cancelRequested(j.ID) {
+		cancel()
+	}
+	go p.watch(jobCtx, j.ID, cancel)
+
+	// Progress is written at most every 5%, not once per hour of the day
+	last := 0.0
+	jobCtx = scheduler.WithProgress(jobCtx, func(done float64) {
+		if done-last >= 0.05 {
+			last = done
+			p.store.UpdateJobProgress(j.ID, done)
+		}
+	})
+
+	rosterID, err := execute(jobCtx, p.store, j)
+
+	switch {
+	case err == nil:
+		err = p.store.FinishJob(j.ID, models.JobDone, rosterID, "")
+	case errors.Is(err, context.Canceled) && ctx.Err() != nil:
+		// Shutting down: leave the job for the next start
+		err = p.store.RequeueJob(j.ID)
+	case errors.Is(err, context.Canceled):
+		err = p.store.FinishJob(j.ID, models.JobCancelled, 0, "cancelled")
+	default:
+		err = p.store.FinishJob(j.ID, models.JobFailed, 0, err.Error())
+	}
+	if err != nil {
+		log.Printf("[Jobs] Job %d: could not record the outcome: %v", j.ID, err)
+	}
+}
+
+// watch touches a running job, so no other process takes it back, and cancels it
+// when a cancel is requested through the Store
+func (p *Pool) watch(ctx context.Context, id int, cancel context.CancelFunc) {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.store.TouchJob(id); err != nil {
+				log.Printf("[Jobs] Job %d: could not touch it: %v", id, err)
+			}
+			if p.cancelRequested(id) {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func (p *Pool) cancelRequested(id int) bool {
+	j, err := p.store.Job(id)
+	return err == nil && j.CancelRequested
+}
+
+// execute runs the job's strategy and saves the roster as a draft version
+func execute(ctx context.Context, store database.Store, j models.Job) (int, error) {
+	strategy, ok := scheduler.LookupStrategy(j.Strategy)
+	if !ok {
+		return 0, fmt.Errorf("unknown strategy %q", j.Strategy)
+	}
+	locationID := j.LocationID
+	if locationID == 0 {
+		locationID = models.DefaultLocation // One store: never every store's crew and demand at once
+	}
+	store = store.AtLocation(locationID)
+	if !j.Date.IsZero() {
+		store = store.OnDay(j.Date)
+	}
+
+	roster, err := strategy(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+	roster.Date = j.Date
+	roster.LocationID = locationID
+	return store.SaveRoster(roster, models.RosterDraft, j.Author)
+}
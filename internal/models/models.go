@@ -1,18 +1,54 @@
 package models
 
+import "time"
+
 // Employee: The resource we need to schedule
 type Employee struct {
 	ID         int
 	Name       string
 	HourlyRate float64
 	SkillLevel int 
+	Active     bool // Inactive employees are kept for history but never scheduled
+	LocationID int  // Home store
+	Floats     bool // Can be sent to nearby stores in the same region
+}
+
+// Location is one store. Stores in the same Region can share floating staff.
+type Location struct {
+	ID       int
+	Name     string
+	Region   string
+	Timezone string // IANA name, e.g. "Europe/Lisbon" ("" = the server's local time)
+}
+
+// Zone is the store's time zone, the server's local time when unset or unknown
+func (l Location) Zone() *time.Location {
+	if l.Timezone == "" {
+		return time.Local
+	}
+	zone, err := time.LoadLocation(l.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return zone
+}
+
+// DefaultLocation is the store every pre-existing row belongs to
+const DefaultLocation = 1
+
+// TravelTime is how long a floater needs to get from one store to another (either direction)
+type TravelTime struct {
+	FromID  int
+	ToID    int
+	Minutes int
 }
 
 // Demand: The requirement 
 type Demand struct {
-	ID        int
-	HourOfDay int 
-	Needed    int 
+	ID         int
+	HourOfDay  int 
+	Needed     int 
+	LocationID int
 }
 
 // SchedulePlan: The result of our calculation
@@ -32,15 +68,215 @@ type Assignment struct {
 
 // Roster holds the complete plan for the day
 type Roster struct {
+	Date        time.Time // The day this roster covers (zero for an undated simulation)
+	LocationID  int       // The store this roster covers (0 = not tied to one)
 	Assignments []Assignment
 	TotalCost   float64
 	Unfilled    int
 }
 
+// Roster lifecycle: drafts are work in progress, published rosters have been shared with staff
+const (
+	RosterDraft     = "draft"
+	RosterPublished = "published"
+)
+
+// RosterVersion is the metadata of a roster saved to the database
+type RosterVersion struct {
+	ID         int
+	Date       time.Time
+	LocationID int
+	Status     string
+	Author     string
+	CreatedAt  time.Time
+	TotalCost  float64
+	Unfilled   int
+}
+
 // Unavailability represents a blocked time slot
 type Unavailability struct {
+	EmployeeID   int    // Resolved from EmployeeName when saved (0 = not yet resolved)
 	EmployeeName string // The AI identifies the person by name
 	StartHour    int
 	EndHour      int
 	Reason       string
+	Date         time.Time // The day it applies to (zero = every day)
+}
+
+// PendingMessage is a free-text message whose constraints are held until the sender
+// answers Question. Draft is the parser's output as JSON, completed one answer at a time.
+type PendingMessage struct {
+	ID        int
+	Text      string
+	Question  string
+	Draft     string
+	SenderID  int // The employee who sent it, whose next SMS or email answers it (0 = unknown)
+	CreatedAt time.Time
+}
+
+// OnCall represents a standby window: the employee is not rostered,
+// but can be called in to cover a gap during these hours
+type OnCall struct {
+	EmployeeID int
+	StartHour  int
+	EndHour    int
+	Date       time.Time // The day it applies to (zero = every day)
+}
+
+// Staff requests wait for a manager's decision
+const (
+	RequestPending  = "pending"
+	RequestApproved = "approved"
+	RequestDenied   = "denied"
+)
+
+// TimeOffRequest is time off asked for in advance. Approving it saves it as Unavailability.
+type TimeOffRequest struct {
+	ID           int
+	EmployeeID   int
+	EmployeeName string
+	Date         time.Time // Zero = every day
+	StartHour    int
+	EndHour      int
+	Reason       string
+	Message      string // The staff message it came from
+	Status       string
+	CreatedAt    time.Time
+}
+
+// SwapRequest is two employees asking to trade their shifts on a day
+type SwapRequest struct {
+	ID           int
+	EmployeeID   int
+	EmployeeName string
+	WithID       int
+	WithName     string
+	Date         time.Time
+	StartHour    int
+	EndHour      int
+	Message      string
+	Status       string
+	CreatedAt    time.Time
+}
+
+// Requests is everything one staff message asked for, saved together
+type Requests struct {
+	Unavailability []Unavailability
+	OnCall         []OnCall
+	TimeOff        []TimeOffRequest
+	Swaps          []SwapRequest
+}
+
+// Contact is a phone number or email address an employee sends messages from
+type Contact struct {
+	ID           int
+	EmployeeID   int
+	EmployeeName string
+	Address      string // E.164 phone number ("+15550100001") or email address, normalised when saved
+}
+
+// Channels inbound messages arrive on
+const (
+	ChannelSMS   = "sms"
+	ChannelEmail = "email"
+)
+
+// What became of an inbound message
+const (
+	InboundNew      = "new"      // Kept, not read yet (left so when reading it failed)
+	InboundSaved    = "saved"    // Parsed and saved
+	InboundHeld     = "held"     // Waiting for the answer to a question (see PendingID)
+	InboundRejected = "rejected" // Unknown sender or unreadable text (see Error)
+)
+
+// InboundMessage is a staff SMS or email as it was received, kept for audit
+type InboundMessage struct {
+	ID           int
+	Channel      string
+	Sender       string // The number or address it came from, as received
+	EmployeeID   int    // 0 when the sender is not a contact
+	EmployeeName string
+	Text         string
+	ExternalID   string // The gateway's ID (Twilio MessageSid, email Message-ID), so redeliveries are read once
+	Status       string
+	PendingID    int
+	Error        string
+	ReceivedAt   time.Time
+}
+
+// Shift is a contiguous block of hours worked by one employee (EndHour is exclusive)
+type Shift struct {
+	Employee  Employee
+	StartHour int
+	EndHour   int
+}
+
+// ShiftChange pairs an existing shift with the shift that replaced it
+type ShiftChange struct {
+	Before Shift
+	After  Shift
+}
+
+// RosterDiff lists the shift-level changes between two rosters
+type RosterDiff struct {
+	Added     []Shift
+	Removed   []Shift
+	Changed   []ShiftChange
+	CostDelta float64
+}
+
+// Lock kinds: a pinned employee must work the window, a forbidden one must not
+const (
+	LockPinned    = "pinned"
+	LockForbidden = "forbidden"
+)
+
+// Lock is a manager override that every scheduler treats as a hard constraint
+type Lock struct {
+	ID           int
+	EmployeeID   int
+	EmployeeName string
+	StartHour    int
+	EndHour      int
+	Kind         string
+	Date         time.Time // The day it applies to (zero = every day)
+}
+
+// LockConflict explains why a lock cannot be honoured
+type LockConflict struct {
+	Lock   Lock
+	Reason string
+}
+
+// Violation is a hard rule a roster breaks (Hour -1 = the whole day, EmployeeID 0 = nobody in particular)
+type Violation struct {
+	Hour       int
+	EmployeeID int
+	Reason     string
+}
+
+// Job lifecycle: queued -> running -> done | failed | cancelled
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a scheduling run waiting for, or handled by, a worker
+type Job struct {
+	ID              int
+	Strategy        string
+	LocationID      int       // 0 = the whole database
+	Date            time.Time // The day the roster will cover
+	Author          string
+	Status          string
+	Progress        float64 // 0..1
+	Error           string
+	RosterID        int  // The draft version saved when done
+	CancelRequested bool // Cancelled while running: the worker stops at its next check
+	CreatedAt       time.Time
+	StartedAt       time.Time
+	FinishedAt      time.Time
 }
@@ -0,0 +1,82 @@
+package payroll
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Layouts understood by WriteCSV
+const (
+	LayoutGeneric = "generic" // One self-describing row per employee
+	LayoutADP     = "adp"     // ADP PayData import: Co Code, Batch ID, File #, Reg/O/T hours, Hours 3
+)
+
+// Layouts lists the CSV layouts WriteCSV knows
+func Layouts() []string {
+	return []string{LayoutGeneric, LayoutADP}
+}
+
+// CSVOptions carry the identifiers payroll systems want on every row
+type CSVOptions struct {
+	Layout      string
+	CompanyCode string // ADP company code
+	BatchID     string // ADP batch (default: the period start as yyyymmdd)
+	PremiumCode string // ADP earnings code for premium hours (default "PRM")
+}
+
+// WriteCSV writes one row per employee. ADP expects its own employee file numbers:
+// the ShiftOpt employee ID is written there, so keep the two in step.
+func WriteCSV(w io.Writer, report Report, opts CSVOptions) error {
+	writer := csv.NewWriter(w)
+	var rows [][]string
+
+	switch opts.Layout {
+	case LayoutGeneric, "":
+		rows = append(rows, []string{"Employee ID", "Employee Name", "Period Start", "Period End",
+			"Regular Hours", "Overtime Hours", "Premium Hours", "Total Hours", "Hourly Rate", "Gross Pay"})
+		// The period end is inclusive here: payroll systems think in whole days
+		from, to := report.From.Format("2006-01-02"), report.To.AddDate(0, 0, -1).Format("2006-01-02")
+		for _, l := range report.Lines {
+			rows = append(rows, []string{strconv.Itoa(l.EmployeeID), l.Name, from, to,
+				strconv.Itoa(l.RegularHours), strconv.Itoa(l.OvertimeHours), strconv.Itoa(l.PremiumHours), strconv.Itoa(l.Hours()),
+				money(l.Rate), money(l.Gross)})
+		}
+
+	case LayoutADP:
+		if opts.CompanyCode == "" {
+			return fmt.Errorf("the adp layout needs a company code")
+		}
+		batch := opts.BatchID
+		if batch == "" {
+			batch = report.From.Format("20060102")
+		}
+		code := opts.PremiumCode
+		if code == "" {
+			code = "PRM"
+		}
+		rows = append(rows, []string{"Co Code", "Batch ID", "File #", "Reg Hours", "O/T Hours", "Hours 3 Code", "Hours 3 Amount"})
+		for _, l := range report.Lines {
+			premiumCode, premium := "", ""
+			if l.PremiumHours > 0 {
+				premiumCode, premium = code, strconv.Itoa(l.PremiumHours)
+			}
+			rows = append(rows, []string{opts.CompanyCode, batch, fmt.Sprintf("%06d", l.EmployeeID),
+				strconv.Itoa(l.RegularHours), strconv.Itoa(l.OvertimeHours), premiumCode, premium})
+		}
+
+	default:
+		return fmt.Errorf("unknown payroll layout %q (want %s)", opts.Layout, strings.Join(Layouts(), " or "))
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+func money(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,141 @@
+// Package payroll turns the rosters of a pay period into hours and gross pay per employee,
+// and writes them in the CSV layouts payroll systems import.
+package payroll
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// Rules decide which hours are paid extra. Every hour lands in exactly one bucket:
+// overtime first, then premium, otherwise regular. Start from DefaultRules.
+type Rules struct {
+	DailyOvertime  int     // Hours per day before overtime starts (0 = no daily limit)
+	WeeklyOvertime int     // Hours per Monday-Sunday week before overtime starts (0 = no weekly limit)
+	OvertimeRate   float64 // Multiplier on the hourly rate, e.g. 1.5
+	PremiumRate    float64 // Multiplier for Sunday and night hours, e.g. 1.25
+	NightStart     int     // Hours from NightStart to midnight are premium
+	NightEnd       int     // Hours from midnight to NightEnd are premium
+}
+
+// DefaultRules: overtime after 8h a day or 40h a week at time and a half,
+// Sundays and 22:00-06:00 at a 25% premium
+var DefaultRules = Rules{
+	DailyOvertime:  8,
+	WeeklyOvertime: 40,
+	OvertimeRate:   1.5,
+	PremiumRate:    1.25,
+	NightStart:     22,
+	NightEnd:       6,
+}
+
+// Line is one employee's pay for the period
+type Line struct {
+	EmployeeID    int
+	Name          string
+	Rate          float64 // The rate of the employee's last hour in the period
+	RegularHours  int
+	OvertimeHours int
+	PremiumHours  int
+	Gross         float64
+}
+
+// Hours is the total paid time
+func (l Line) Hours() int {
+	return l.RegularHours + l.OvertimeHours + l.PremiumHours
+}
+
+// Report is the payroll of one period [From, To)
+type Report struct {
+	From, To time.Time
+	Lines    []Line // By name
+	Hours    int
+	Gross    float64
+}
+
+// Compute pays every hour of the rosters dated in [from, to).
+// Rosters come from database.EffectiveRosters, so each day is counted once per store.
+// Weekly overtime only counts hours inside the period: start periods on a Monday.
+func Compute(rosters []*models.Roster, from, to time.Time, rules Rules) Report {
+	report := Report{From: from, To: to}
+
+	// 1. Collect every worked hour, in time order
+	type worked struct {
+		day      time.Time
+		hour     int
+		employee models.Employee
+	}
+	var hours []worked
+	for _, r := range rosters {
+		if r.Date.Before(from) || !r.Date.Before(to) {
+			continue
+		}
+		for _, a := range r.Assignments {
+			hours = append(hours, worked{r.Date, a.Hour, a.Employee})
+		}
+	}
+	sort.Slice(hours, func(i, j int) bool {
+		if !hours[i].day.Equal(hours[j].day) {
+			return hours[i].day.Before(hours[j].day)
+		}
+		return hours[i].hour < hours[j].hour
+	})
+
+	// 2. Bucket each hour against the running daily and weekly counts
+	lines := make(map[int]*Line)
+	perDay := make(map[string]int)  // employee|day
+	perWeek := make(map[string]int) // employee|monday
+	for _, w := range hours {
+		e := w.employee
+		l := lines[e.ID]
+		if l == nil {
+			l = &Line{EmployeeID: e.ID, Name: e.Name}
+			lines[e.ID] = l
+		}
+		l.Name, l.Rate = e.Name, e.HourlyRate
+
+		dayKey := fmt.Sprintf("%d|%s", e.ID, w.day.Format("2006-01-02"))
+		weekKey := fmt.Sprintf("%d|%s", e.ID, monday(w.day).Format("2006-01-02"))
+		perDay[dayKey]++
+		perWeek[weekKey]++
+
+		switch {
+		case (rules.DailyOvertime > 0 && perDay[dayKey] > rules.DailyOvertime) ||
+			(rules.WeeklyOvertime > 0 && perWeek[weekKey] > rules.WeeklyOvertime):
+			l.OvertimeHours++
+			l.Gross += e.HourlyRate * rules.OvertimeRate
+		case w.day.Weekday() == time.Sunday || (rules.NightStart > 0 && w.hour >= rules.NightStart) || w.hour < rules.NightEnd:
+			l.PremiumHours++
+			l.Gross += e.HourlyRate * rules.PremiumRate
+		default:
+			l.RegularHours++
+			l.Gross += e.HourlyRate
+		}
+	}
+
+	// 3. Lines by name
+	for _, l := range lines {
+		report.Lines = append(report.Lines, *l)
+		report.Hours += l.Hours()
+		report.Gross += l.Gross
+	}
+	sort.Slice(report.Lines, func(i, j int) bool { return report.Lines[i].Name < report.Lines[j].Name })
+	return report
+}
+
+func monday(day time.Time) time.Time {
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// Load computes the payroll of [from, to) from the rosters in force on each day
+func Load(store database.Store, from, to time.Time, rules Rules) (Report, error) {
+	rosters, _, err := database.EffectiveRosters(store, from, to)
+	if err != nil {
+		return Report{}, err
+	}
+	return Compute(rosters, from, to, rules), nil
+}
@@ -0,0 +1,130 @@
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// Shifts collapses the hourly assignments of a roster into contiguous blocks per employee.
+// Result is ordered by start hour, then employee name.
+func Shifts(roster *models.Roster) []models.Shift {
+	// 1. Group hours by employee
+	hours := make(map[int][]int)
+	people := make(map[int]models.Employee)
+	for _, a := range roster.Assignments {
+		hours[a.Employee.ID] = append(hours[a.Employee.ID], a.Hour)
+		people[a.Employee.ID] = a.Employee
+	}
+
+	// 2. Cut each employee's hours into blocks wherever there is a gap
+	var shifts []models.Shift
+	for id, hs := range hours {
+		sort.Ints(hs)
+		current := models.Shift{Employee: people[id], StartHour: hs[0], EndHour: hs[0] + 1}
+		for _, h := range hs[1:] {
+			if h == current.EndHour {
+				current.EndHour++
+				continue
+			}
+			if h < current.EndHour {
+				continue // Duplicate hour, already covered
+			}
+			shifts = append(shifts, current)
+			current = models.Shift{Employee: people[id], StartHour: h, EndHour: h + 1}
+		}
+		shifts = append(shifts, current)
+	}
+
+	sort.Slice(shifts, func(i, j int) bool {
+		if shifts[i].StartHour != shifts[j].StartHour {
+			return shifts[i].StartHour < shifts[j].StartHour
+		}
+		return shifts[i].Employee.Name < shifts[j].Employee.Name
+	})
+	return shifts
+}
+
+// DiffRosters compares two rosters shift by shift.
+// A shift that exists in both with different hours is reported as Changed,
+// everything else is either Added (only in after) or Removed (only in before).
+func DiffRosters(before, after *models.Roster) models.RosterDiff {
+	diff := models.RosterDiff{CostDelta: after.TotalCost - before.TotalCost}
+
+	oldByEmp := make(map[int][]models.Shift)
+	for _, s := range Shifts(before) {
+		oldByEmp[s.Employee.ID] = append(oldByEmp[s.Employee.ID], s)
+	}
+	newByEmp := make(map[int][]models.Shift)
+	for _, s := range Shifts(after) {
+		newByEmp[s.Employee.ID] = append(newByEmp[s.Employee.ID], s)
+	}
+
+	for id, newShifts := range newByEmp {
+		oldShifts := oldByEmp[id]
+		matched := make([]bool, len(oldShifts))
+
+		for _, ns := range newShifts {
+			// 1. Identical shift: nothing to report
+			// 2. Overlapping shift: the block was moved, extended or cut
+			found := -1
+			for i, old := range oldShifts {
+				if matched[i] {
+					continue
+				}
+				if old.StartHour == ns.StartHour && old.EndHour == ns.EndHour {
+					found = i
+					break
+				}
+				if found < 0 && old.StartHour < ns.EndHour && ns.StartHour < old.EndHour {
+					found = i
+				}
+			}
+
+			if found < 0 {
+				diff.Added = append(diff.Added, ns)
+				continue
+			}
+			matched[found] = true
+			old := oldShifts[found]
+			if old.StartHour != ns.StartHour || old.EndHour != ns.EndHour {
+				diff.Changed = append(diff.Changed, models.ShiftChange{Before: old, After: ns})
+			}
+		}
+
+		for i, old := range oldShifts {
+			if !matched[i] {
+				diff.Removed = append(diff.Removed, old)
+			}
+		}
+		delete(oldByEmp, id)
+	}
+
+	// Employees that disappeared entirely
+	for _, oldShifts := range oldByEmp {
+		diff.Removed = append(diff.Removed, oldShifts...)
+	}
+
+	sortShifts(diff.Added)
+	sortShifts(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return lessShift(diff.Changed[i].Before, diff.Changed[j].Before)
+	})
+	return diff
+}
+
+// ChangeCount is the number of shifts touched by the diff (the "disruption")
+func ChangeCount(diff models.RosterDiff) int {
+	return len(diff.Added) + len(diff.Removed) + len(diff.Changed)
+}
+
+func sortShifts(shifts []models.Shift) {
+	sort.Slice(shifts, func(i, j int) bool { return lessShift(shifts[i], shifts[j]) })
+}
+
+func lessShift(a, b models.Shift) bool {
+	if a.StartHour != b.StartHour {
+		return a.StartHour < b.StartHour
+	}
+	return a.Employee.Name < b.Employee.Name
+}
@@ -1,44 +1,323 @@
 package scheduler
 
 import (
+	"bytes"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/iannsp/shiftopt/internal/models"
 )
 
-func ExportToCSV(roster *models.Roster, filename string) error {
+// Exporter writes a roster in one file format
+type Exporter interface {
+	Export(roster *models.Roster, w io.Writer) error
+}
+
+// Layouts: how many rows a roster becomes
+const (
+	LayoutHourly = "hourly" // One row per employee per hour worked
+	LayoutShift  = "shift"  // One row per contiguous shift
+)
+
+// Optional columns, after the time and employee name that every export has
+const (
+	ColumnRole   = "role"
+	ColumnRate   = "rate"
+	ColumnCost   = "cost"
+	ColumnSafety = "safety"
+)
+
+// DefaultColumns match the original CSV export
+var DefaultColumns = []string{ColumnRole, ColumnRate, ColumnSafety}
+
+// ExportOptions shape the rows (zero value = hourly layout with DefaultColumns)
+type ExportOptions struct {
+	Layout  string
+	Columns []string
+}
+
+// ExportFormats lists the formats NewExporter knows
+func ExportFormats() []string {
+	return []string{"csv", "json", "xlsx"}
+}
+
+// NewExporter returns the exporter for a format, with validated options
+func NewExporter(format string, opts ExportOptions) (Exporter, error) {
+	table, err := newTable(opts)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "csv":
+		return csvExporter{table}, nil
+	case "json":
+		return jsonExporter{table}, nil
+	case "xlsx":
+		return xlsxExporter{table}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q (want %s)", format, strings.Join(ExportFormats(), ", "))
+}
+
+// ExportFile writes the roster to a file, removing it again if the export fails halfway
+func ExportFile(roster *models.Roster, exporter Exporter, filename string) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	if err := exporter.Export(roster, file); err != nil {
+		file.Close()
+		os.Remove(filename)
+		return err
+	}
+	return file.Close()
+}
+
+// ExportToCSV writes the roster to a CSV file with the default columns
+func ExportToCSV(roster *models.Roster, filename string) error {
+	exporter, _ := NewExporter("csv", ExportOptions{})
+	return ExportFile(roster, exporter, filename)
+}
+
+// WriteCSV writes one row per assignment with the default columns
+func WriteCSV(roster *models.Roster, w io.Writer) error {
+	exporter, _ := NewExporter("csv", ExportOptions{})
+	return exporter.Export(roster, w)
+}
+
+// --- Rows and columns, shared by every format ---
+
+// clock is an hour of the day: "08:00" in text formats, 8 in JSON
+type clock int
+
+// exportRow is one hour (hourly layout) or one shift
+type exportRow struct {
+	employee   models.Employee
+	start, end int
+	safety     bool // Safety senior for at least one of the hours
+}
+
+type column struct {
+	header string // CSV and XLSX
+	key    string // JSON
+	value  func(r exportRow) any
+}
+
+// table is the validated column set of an export
+type table struct {
+	layout  string
+	columns []column
+}
+
+func newTable(opts ExportOptions) (table, error) {
+	t := table{layout: opts.Layout}
+	if t.layout == "" {
+		t.layout = LayoutHourly
+	}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	// 1. Time and employee come first
+	switch t.layout {
+	case LayoutHourly:
+		t.columns = append(t.columns, column{"Hour", "hour", func(r exportRow) any { return clock(r.start) }})
+	case LayoutShift:
+		t.columns = append(t.columns,
+			column{"Start", "start_hour", func(r exportRow) any { return clock(r.start) }},
+			column{"End", "end_hour", func(r exportRow) any { return clock(r.end) }},
+			column{"Hours", "hours", func(r exportRow) any { return r.end - r.start }})
+	default:
+		return table{}, fmt.Errorf("unknown layout %q (want %s or %s)", opts.Layout, LayoutHourly, LayoutShift)
+	}
+	t.columns = append(t.columns, column{"Employee Name", "employee", func(r exportRow) any { return r.employee.Name }})
 
-	writer.Write([]string{"Hour", "Employee Name", "Role", "Hourly Rate", "Is Safety Senior?"})
+	// 2. Then the optional columns, in the order asked for
+	names := opts.Columns
+	if names == nil {
+		names = DefaultColumns
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			return table{}, fmt.Errorf("column %q listed twice", name)
+		}
+		seen[name] = true
 
-	for _, a := range roster.Assignments {
-		role := "Junior"
-		if a.Employee.SkillLevel == 2 {
-			role = "Senior"
+		switch name {
+		case ColumnRole:
+			t.columns = append(t.columns, column{"Role", "role", func(r exportRow) any { return roleName(r.employee) }})
+		case ColumnRate:
+			t.columns = append(t.columns, column{"Hourly Rate", "hourly_rate", func(r exportRow) any { return r.employee.HourlyRate }})
+		case ColumnCost:
+			t.columns = append(t.columns, column{"Cost", "cost", func(r exportRow) any {
+				return r.employee.HourlyRate * float64(r.end-r.start)
+			}})
+		case ColumnSafety:
+			t.columns = append(t.columns, column{"Is Safety Senior?", "safety_senior", func(r exportRow) any { return r.safety }})
+		default:
+			return table{}, fmt.Errorf("unknown column %q (want %s, %s, %s or %s)", name, ColumnRole, ColumnRate, ColumnCost, ColumnSafety)
 		}
-		isSafety := "No"
+	}
+	return t, nil
+}
+
+// rows lays the roster out, in assignment order (hourly) or by start hour (shift)
+func (t table) rows(roster *models.Roster) []exportRow {
+	var rows []exportRow
+	if t.layout == LayoutHourly {
+		for _, a := range roster.Assignments {
+			rows = append(rows, exportRow{employee: a.Employee, start: a.Hour, end: a.Hour + 1, safety: a.IsSenior})
+		}
+		return rows
+	}
+
+	safety := make(map[[2]int]bool) // {employee, hour}
+	for _, a := range roster.Assignments {
 		if a.IsSenior {
-			isSafety = "YES"
+			safety[[2]int{a.Employee.ID, a.Hour}] = true
+		}
+	}
+	for _, s := range Shifts(roster) {
+		r := exportRow{employee: s.Employee, start: s.StartHour, end: s.EndHour}
+		for h := s.StartHour; h < s.EndHour; h++ {
+			r.safety = r.safety || safety[[2]int{s.Employee.ID, h}]
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+func (t table) headers() []string {
+	headers := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		headers[i] = c.header
+	}
+	return headers
+}
+
+func roleName(e models.Employee) string {
+	if e.SkillLevel == 2 {
+		return "Senior"
+	}
+	return "Junior"
+}
+
+// text renders a cell for the text formats
+func text(v any) string {
+	switch v := v.(type) {
+	case clock:
+		return fmt.Sprintf("%02d:00", int(v))
+	case float64:
+		return fmt.Sprintf("%.2f", v)
+	case bool:
+		if v {
+			return "YES"
 		}
+		return "No"
+	case int:
+		return strconv.Itoa(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// --- CSV ---
 
-		writer.Write([]string{
-			fmt.Sprintf("%02d:00", a.Hour),
-			a.Employee.Name,
-			role,
-			fmt.Sprintf("%.2f", a.Employee.HourlyRate),
-			isSafety,
-		})
+type csvExporter struct{ table }
+
+func (e csvExporter) Export(roster *models.Roster, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(e.headers()); err != nil {
+		return err
+	}
+	for _, r := range e.rows(roster) {
+		record := make([]string, len(e.columns))
+		for i, c := range e.columns {
+			record[i] = text(c.value(r))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
 	}
-	fmt.Printf("Success: Roster exported to %s\n", filename)
-	return nil
+	writer.Flush()
+	return writer.Error()
 }
 
+// --- JSON ---
+
+type jsonExporter struct{ table }
+
+// Export writes {"date", "location_id", "total_cost", "unfilled", "rows": [...]},
+// with the row keys in column order. Costs that are not numbers (NaN, Inf) are null.
+func (e jsonExporter) Export(roster *models.Roster, w io.Writer) error {
+	doc := struct {
+		Date       string    `json:"date"`
+		LocationID int       `json:"location_id"`
+		TotalCost  any       `json:"total_cost"`
+		Unfilled   int       `json:"unfilled"`
+		Rows       []jsonRow `json:"rows"`
+	}{LocationID: roster.LocationID, TotalCost: finite(math.Round(roster.TotalCost*100) / 100), Unfilled: roster.Unfilled, Rows: []jsonRow{}}
+	if !roster.Date.IsZero() {
+		doc.Date = roster.Date.Format("2006-01-02")
+	}
+	for _, r := range e.rows(roster) {
+		row := jsonRow{keys: make([]string, len(e.columns)), values: make([]any, len(e.columns))}
+		for j, c := range e.columns {
+			v := c.value(r)
+			switch x := v.(type) {
+			case clock:
+				v = int(x)
+			case float64:
+				v = finite(x)
+			}
+			row.keys[j], row.values[j] = c.key, v
+		}
+		doc.Rows = append(doc.Rows, row)
+	}
+
+	raw, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(raw, '\n'))
+	return err
+}
+
+// jsonRow is a JSON object that keeps its keys in order, unlike a map
+type jsonRow struct {
+	keys   []string
+	values []any
+}
+
+func (r jsonRow) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range r.keys {
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// finite is x, or nil for the values JSON has no number for
+func finite(x float64) any {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return nil
+	}
+	return x
+}
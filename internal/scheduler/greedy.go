@@ -5,21 +5,25 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/iannsp/shiftopt/internal/database"
 	"github.com/iannsp/shiftopt/internal/models"
 )
 
 func RunGreedy(db *sql.DB) {
+	Greedy(database.NewSQLiteStore(db))
+}
+
+// Greedy is RunGreedy against any Store
+func Greedy(store database.Store) {
 	fmt.Println("\n--- Running Greedy Scheduler (Internal Pkg) ---")
 
-	// 1. Fetch Employees
-	rows, _ := db.Query("SELECT id, name, hourly_rate FROM employees")
-	var employees []models.Employee
-	for rows.Next() {
-		var e models.Employee
-		rows.Scan(&e.ID, &e.Name, &e.HourlyRate)
-		employees = append(employees, e)
+	// 1. Fetch Employees, Demands and Manager overrides (Pinned / Forbidden)
+	in, err := loadSnapshot(store)
+	if err != nil {
+		fmt.Printf("CRITICAL: %v\n", err)
+		return
 	}
-	rows.Close()
+	employees, locks := in.employees, in.locks
 
 	// Sort by Cost
 	sort.Slice(employees, func(i, j int) bool {
@@ -27,29 +31,42 @@ func RunGreedy(db *sql.DB) {
 	})
 
 	// 2. Schedule
-	dRows, _ := db.Query("SELECT hour_of_day, needed FROM demands ORDER BY hour_of_day")
 	var plan []models.SchedulePlan
 	totalCost := 0.0
 
-	for dRows.Next() {
-		var hour, needed int
-		dRows.Scan(&hour, &needed)
+	for _, hour := range in.hours {
+		needed := in.demand[hour]
 
 		if needed > len(employees) {
 			continue
 		}
 
-		for i := 0; i < needed; i++ {
-			emp := employees[i]
+		// Pinned staff take their slots first, then the cheapest allowed fill the rest
+		assigned := 0
+		for _, emp := range employees {
+			if locks.isPinned(emp.ID, hour) {
+				plan = append(plan, models.SchedulePlan{Hour: hour, Employee: emp.Name, Cost: emp.HourlyRate})
+				totalCost += emp.HourlyRate
+				assigned++
+			}
+		}
+
+		for _, emp := range employees {
+			if assigned >= needed {
+				break
+			}
+			if locks.isPinned(emp.ID, hour) || locks.isForbidden(emp.ID, hour) {
+				continue
+			}
 			plan = append(plan, models.SchedulePlan{
 				Hour:     hour,
 				Employee: emp.Name,
 				Cost:     emp.HourlyRate,
 			})
 			totalCost += emp.HourlyRate
+			assigned++
 		}
 	}
-	dRows.Close()
 
 	fmt.Printf("Optimization Complete. Daily Cost: $%.2f\n", totalCost)
 }
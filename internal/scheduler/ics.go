@@ -0,0 +1,193 @@
+package scheduler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// ICSOptions describe the calendar around the shifts
+type ICSOptions struct {
+	Location string    // Store name shown on every event
+	Stamp    time.Time // When the roster was issued: DTSTAMP and SEQUENCE (zero = now)
+}
+
+// WriteICS writes the shifts of one employee (employeeID 0 = everyone) as an iCalendar file.
+//
+// Event UIDs depend only on the day, the store, the employee and the shift's position in
+// their day, never on the roster version. Republishing a roster therefore updates the
+// events already in a phone calendar (the newer SEQUENCE wins) instead of duplicating them.
+// Times are floating local times: a 08:00 shift is 08:00 wherever the store is.
+func WriteICS(roster *models.Roster, employeeID int, opts ICSOptions, w io.Writer) error {
+	stamp := opts.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	day := roster.Date
+	if day.IsZero() {
+		y, m, d := time.Now().Date()
+		day = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	// 1. Which hours did each person cover as the safety senior?
+	safety := make(map[int]map[int]bool)
+	for _, a := range roster.Assignments {
+		if a.IsSenior {
+			if safety[a.Employee.ID] == nil {
+				safety[a.Employee.ID] = make(map[int]bool)
+			}
+			safety[a.Employee.ID][a.Hour] = true
+		}
+	}
+
+	ics := &icsWriter{w: w}
+	ics.line("BEGIN:VCALENDAR")
+	ics.line("VERSION:2.0")
+	ics.line("PRODID:-//shiftopt//roster//EN")
+	ics.line("CALSCALE:GREGORIAN")
+	ics.line("METHOD:PUBLISH")
+	ics.line("X-WR-CALNAME:" + escapeICS(calendarName(roster, employeeID, opts.Location)))
+
+	// 2. One event per shift
+	ordinal := make(map[int]int)
+	for _, s := range Shifts(roster) {
+		if employeeID != 0 && s.Employee.ID != employeeID {
+			continue
+		}
+		ordinal[s.Employee.ID]++
+
+		role := "Junior"
+		if s.Employee.SkillLevel == 2 {
+			role = "Senior"
+		}
+		summary := fmt.Sprintf("Shift: %s (%s)", s.Employee.Name, role)
+		description := fmt.Sprintf("Role: %s", role)
+		for h := s.StartHour; h < s.EndHour; h++ {
+			if safety[s.Employee.ID][h] {
+				description += "\nSafety senior on duty"
+				break
+			}
+		}
+
+		ics.line("BEGIN:VEVENT")
+		ics.line(fmt.Sprintf("UID:%s-l%d-e%d-%d@shiftopt", day.Format("20060102"), roster.LocationID, s.Employee.ID, ordinal[s.Employee.ID]))
+		ics.line("SEQUENCE:" + fmt.Sprint(stamp.Unix()))
+		ics.line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+		ics.line("DTSTART:" + day.Add(time.Duration(s.StartHour)*time.Hour).Format("20060102T150405"))
+		ics.line("DTEND:" + day.Add(time.Duration(s.EndHour)*time.Hour).Format("20060102T150405"))
+		ics.line("SUMMARY:" + escapeICS(summary))
+		ics.line("DESCRIPTION:" + escapeICS(description))
+		if opts.Location != "" {
+			ics.line("LOCATION:" + escapeICS(opts.Location))
+		}
+		ics.line("END:VEVENT")
+	}
+
+	ics.line("END:VCALENDAR")
+	return ics.err
+}
+
+// ExportICS writes roster.ics (every shift) plus one <id>-<name>.ics per employee into dir
+func ExportICS(roster *models.Roster, opts ICSOptions, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	files := map[int]string{0: "roster.ics"}
+	order := []int{0}
+	for _, s := range Shifts(roster) {
+		if _, ok := files[s.Employee.ID]; !ok {
+			files[s.Employee.ID] = fmt.Sprintf("%d-%s.ics", s.Employee.ID, slug(s.Employee.Name))
+			order = append(order, s.Employee.ID)
+		}
+	}
+
+	var written []string
+	for _, id := range order {
+		path := filepath.Join(dir, files[id])
+		if err := writeICSFile(roster, id, opts, path); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+func writeICSFile(roster *models.Roster, employeeID int, opts ICSOptions, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteICS(roster, employeeID, opts, file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func calendarName(roster *models.Roster, employeeID int, location string) string {
+	name := "Roster"
+	if location != "" {
+		name += " " + location
+	}
+	if employeeID == 0 {
+		return name
+	}
+	for _, a := range roster.Assignments {
+		if a.Employee.ID == employeeID {
+			return name + " - " + a.Employee.Name
+		}
+	}
+	return name
+}
+
+// slug keeps letters and digits for a file name ("Alice (Vet)" -> "alice-vet")
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// escapeICS escapes TEXT values (RFC 5545 3.3.11)
+func escapeICS(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icsWriter ends lines with CRLF and folds them at 75 octets, keeping the first error
+type icsWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (c *icsWriter) line(s string) {
+	if c.err != nil {
+		return
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		n := len(string(r))
+		if width+n > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	b.WriteString("\r\n")
+	_, c.err = io.WriteString(c.w, b.String())
+}
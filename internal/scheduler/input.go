@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// snapshot is everything a scheduling run reads, loaded once from the Store
+type snapshot struct {
+	employees []models.Employee    // In Store order (by ID)
+	hours     []int                // Opening hours, ascending
+	demand    map[int]int          // Hour -> Needed
+	blocked   map[int]map[int]bool // EmployeeID -> Hour -> IsBlocked
+	onCall    map[int]map[int]bool // EmployeeID -> Hour -> IsOnCall
+	lockList  []models.Lock
+	locks     *lockSet
+}
+
+// loadSnapshot reads the inputs and refuses to continue if the manager overrides conflict
+func loadSnapshot(store database.Store) (*snapshot, error) {
+	in, err := readSnapshot(store)
+	if err != nil {
+		return nil, err
+	}
+	if conflicts := checkLocks(in); len(conflicts) > 0 {
+		return nil, &LockConflictError{Conflicts: conflicts}
+	}
+	return in, nil
+}
+
+// readSnapshot reads the inputs without validating them
+func readSnapshot(store database.Store) (*snapshot, error) {
+	in := &snapshot{
+		demand:  make(map[int]int),
+		blocked: make(map[int]map[int]bool),
+		onCall:  make(map[int]map[int]bool),
+	}
+
+	// 1. Employees
+	employees, err := store.Employees()
+	if err != nil {
+		return nil, err
+	}
+	in.employees = employees
+
+	// 2. Demands
+	demands, err := store.Demands()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range demands {
+		if _, seen := in.demand[d.HourOfDay]; !seen {
+			in.hours = append(in.hours, d.HourOfDay)
+		}
+		in.demand[d.HourOfDay] = d.Needed
+	}
+
+	// 3. Unavailability: block every hour in the range [start, end)
+	blocks, err := store.Unavailability()
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range blocks {
+		markHours(in.blocked, u.EmployeeID, u.StartHour, u.EndHour)
+	}
+
+	// 4. On-Call windows
+	windows, err := store.OnCall()
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range windows {
+		markHours(in.onCall, o.EmployeeID, o.StartHour, o.EndHour)
+	}
+
+	// 5. Manager overrides
+	in.lockList, err = store.Locks()
+	if err != nil {
+		return nil, err
+	}
+	in.locks = newLockSet(in.lockList)
+
+	return in, nil
+}
+
+// markHours flags [start, end) for one employee in an EmployeeID -> Hour index
+func markHours(index map[int]map[int]bool, empID, start, end int) {
+	if index[empID] == nil {
+		index[empID] = make(map[int]bool)
+	}
+	for h := start; h < end; h++ {
+		index[empID][h] = true
+	}
+}
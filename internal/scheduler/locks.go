@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// LockConflictError is returned by the schedulers when the manager overrides
+// cannot all be honoured. Nothing is scheduled until the conflicts are resolved.
+type LockConflictError struct {
+	Conflicts []models.LockConflict
+}
+
+func (e *LockConflictError) Error() string {
+	var reasons []string
+	for _, c := range e.Conflicts {
+		reasons = append(reasons, c.Reason)
+	}
+	return fmt.Sprintf("%d lock conflict(s): %s", len(e.Conflicts), strings.Join(reasons, "; "))
+}
+
+// CheckLocks validates the pinned/forbidden overrides against each other and the other rules:
+// unknown employees, pins during unavailability, pins on forbidden hours,
+// pins outside opening hours and pins that bust the daily hour limit.
+func CheckLocks(db *sql.DB) ([]models.LockConflict, error) {
+	return LockConflicts(database.NewSQLiteStore(db))
+}
+
+// LockConflicts is CheckLocks against any Store
+func LockConflicts(store database.Store) ([]models.LockConflict, error) {
+	in, err := readSnapshot(store)
+	if err != nil {
+		return nil, err
+	}
+	return checkLocks(in), nil
+}
+
+func checkLocks(in *snapshot) []models.LockConflict {
+	const MaxDaily = 8
+
+	var conflicts []models.LockConflict
+	report := func(l models.Lock, format string, args ...any) {
+		conflicts = append(conflicts, models.LockConflict{Lock: l, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	reported := make(map[int]bool) // One daily-limit report per employee
+
+	for _, l := range in.lockList {
+		who := l.EmployeeName
+		if who == "" {
+			report(l, "lock %d refers to unknown employee %d", l.ID, l.EmployeeID)
+			continue
+		}
+		if l.EndHour <= l.StartHour {
+			report(l, "lock %d for %s has an empty window %02d:00-%02d:00", l.ID, who, l.StartHour, l.EndHour)
+			continue
+		}
+		if l.Kind != models.LockPinned {
+			continue
+		}
+
+		for h := l.StartHour; h < l.EndHour; h++ {
+			if _, open := in.demand[h]; !open {
+				report(l, "%s is pinned at %02d:00 but the store is closed", who, h)
+				break
+			}
+			if in.blocked[l.EmployeeID][h] {
+				report(l, "%s is pinned at %02d:00 but is unavailable", who, h)
+				break
+			}
+			if in.locks.isForbidden(l.EmployeeID, h) {
+				report(l, "%s is both pinned and forbidden at %02d:00", who, h)
+				break
+			}
+		}
+
+		if total := in.locks.pinnedFrom(l.EmployeeID, 0); total > MaxDaily && !reported[l.EmployeeID] {
+			reported[l.EmployeeID] = true
+			report(l, "%s is pinned for %d hours (max %d)", who, total, MaxDaily)
+		}
+	}
+
+	return conflicts
+}
+
+// lockSet indexes the overrides by employee and hour
+type lockSet struct {
+	pinned    map[int]map[int]bool // EmployeeID -> Hour -> MustWork
+	forbidden map[int]map[int]bool // EmployeeID -> Hour -> MustNotWork
+}
+
+func newLockSet(locks []models.Lock) *lockSet {
+	set := &lockSet{
+		pinned:    make(map[int]map[int]bool),
+		forbidden: make(map[int]map[int]bool),
+	}
+	for _, l := range locks {
+		target := set.forbidden
+		if l.Kind == models.LockPinned {
+			target = set.pinned
+		}
+		if target[l.EmployeeID] == nil {
+			target[l.EmployeeID] = make(map[int]bool)
+		}
+		for h := l.StartHour; h < l.EndHour; h++ {
+			target[l.EmployeeID][h] = true
+		}
+	}
+	return set
+}
+
+// isPinned reports whether the employee must work this hour
+func (s *lockSet) isPinned(empID, hour int) bool {
+	return s.pinned[empID][hour]
+}
+
+// isForbidden reports whether the employee must not work this hour
+func (s *lockSet) isForbidden(empID, hour int) bool {
+	return s.forbidden[empID][hour]
+}
+
+// pinnedFrom counts the pinned hours at or after the given hour.
+// Schedulers reserve these when checking the daily hour limit.
+func (s *lockSet) pinnedFrom(empID, hour int) int {
+	count := 0
+	for h := range s.pinned[empID] {
+		if h >= hour {
+			count++
+		}
+	}
+	return count
+}
+
+// pinEnd returns the end (exclusive) of the pinned window containing hour
+func (s *lockSet) pinEnd(empID, hour int) int {
+	end := hour
+	for s.pinned[empID][end] {
+		end++
+	}
+	return end
+}
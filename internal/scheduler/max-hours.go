@@ -5,30 +5,30 @@ import (
 	"fmt"
 	"sort"
 
-	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/database"
 )
 
 // RunConstrained adds the "8-Hour Limit" rule
 func RunConstrained(db *sql.DB) {
+	Constrained(database.NewSQLiteStore(db))
+}
+
+// Constrained is RunConstrained against any Store
+func Constrained(store database.Store) {
 	fmt.Println("\n--- Running Constrained Scheduler (Max 8h/day) ---")
 
-	// 1. Fetch & Sort Employees (Same as Greedy)
-	rows, _ := db.Query("SELECT id, name, hourly_rate FROM employees")
-	var employees []models.Employee
-	for rows.Next() {
-		var e models.Employee
-		rows.Scan(&e.ID, &e.Name, &e.HourlyRate)
-		employees = append(employees, e)
+	// 1. Fetch & Sort Employees (Same as Greedy), with Demands and Manager overrides
+	in, err := loadSnapshot(store)
+	if err != nil {
+		fmt.Printf("CRITICAL: %v\n", err)
+		return
 	}
-	rows.Close()
+	employees, locks := in.employees, in.locks
 
 	sort.Slice(employees, func(i, j int) bool {
 		return employees[i].HourlyRate < employees[j].HourlyRate
 	})
 
-	// 2. Fetch Demands
-	dRows, _ := db.Query("SELECT hour_of_day, needed FROM demands ORDER BY hour_of_day")
-	
 	totalCost := 0.0
 	unassignedShifts := 0
 
@@ -38,12 +38,20 @@ func RunConstrained(db *sql.DB) {
 	hoursWorked := make(map[int]int)
 	const MaxDailyHours = 8
 
-	for dRows.Next() {
-		var hour, needed int
-		dRows.Scan(&hour, &needed)
+	for _, hour := range in.hours {
+		needed := in.demand[hour]
 
 		assignedCount := 0
 
+		// Pinned staff are always on (CheckLocks guarantees they fit in 8 hours)
+		for _, emp := range employees {
+			if locks.isPinned(emp.ID, hour) {
+				hoursWorked[emp.ID]++
+				totalCost += emp.HourlyRate
+				assignedCount++
+			}
+		}
+
 		// Iterate through employees (Cheapest -> Expensive)
 		for _, emp := range employees {
 			// Stop if we filled this hour
@@ -58,6 +66,15 @@ func RunConstrained(db *sql.DB) {
 				continue
 			}
 
+			// Overrides: skip the pinned (already counted), the forbidden,
+			// and anyone whose remaining hours are reserved for their pins.
+			if locks.isPinned(emp.ID, hour) || locks.isForbidden(emp.ID, hour) {
+				continue
+			}
+			if hoursWorked[emp.ID]+locks.pinnedFrom(emp.ID, hour) >= MaxDailyHours {
+				continue
+			}
+
 			// If valid, assign them
 			hoursWorked[emp.ID]++
 			totalCost += emp.HourlyRate
@@ -70,7 +87,6 @@ func RunConstrained(db *sql.DB) {
 			fmt.Printf("WARNING: Hour %d is understaffed! (Ran out of eligible workers)\n", hour)
 		}
 	}
-	dRows.Close()
 
 	fmt.Printf("Optimization Complete. Daily Cost: $%.2f\n", totalCost)
 	if unassignedShifts > 0 {
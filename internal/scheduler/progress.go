@@ -0,0 +1,25 @@
+package scheduler
+
+import "context"
+
+// ProgressFunc receives how much of a run is done, from 0 to 1
+type ProgressFunc func(done float64)
+
+type progressKey struct{}
+
+// WithProgress asks the schedulers to report progress on ctx as they work through the day
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// step is called once per hour of the loop: it reports progress and
+// returns ctx.Err() so a cancelled run stops at the next hour
+func step(ctx context.Context, done, total int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && total > 0 {
+		fn(float64(done) / float64(total))
+	}
+	return nil
+}
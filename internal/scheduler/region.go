@@ -0,0 +1,266 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// RunRegion schedules every store in a region jointly: each store is solved with its own
+// crew first (Smart Tetris), then the gaps left anywhere are filled with floaters from
+// nearby stores. Returns one roster per store, in location order.
+//
+// A floater is an employee with Floats set whose home store has a travel time to the
+// store with the gap. Travel rules:
+//   - Travel time is paid: each away block costs the floater's rate for the trip
+//   - A floater cannot work at two stores closer together than the trip (rounded up to the hour)
+//   - The 8-hour daily limit counts hours at every store
+//
+// Floater ranking for each gap: a Senior if the hour has none, then the longest
+// cover of the gap, then the cheapest rate + travel.
+func RunRegion(db *sql.DB, region string) ([]*models.Roster, error) {
+	return Region(context.Background(), database.NewSQLiteStore(db), region)
+}
+
+// Region is RunRegion against any (unscoped) Store, stopping early if ctx is cancelled
+func Region(ctx context.Context, store database.Store, region string) ([]*models.Roster, error) {
+	// 1. Stores in the region and the trips between them
+	all, err := store.Locations()
+	if err != nil {
+		return nil, err
+	}
+	var locations []models.Location
+	inRegion := make(map[int]bool)
+	for _, l := range all {
+		if l.Region == region {
+			locations = append(locations, l)
+			inRegion[l.ID] = true
+		}
+	}
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("no locations in region %q", region)
+	}
+
+	times, err := store.TravelTimes()
+	if err != nil {
+		return nil, err
+	}
+	travel := make(map[[2]int]int) // {From, To} -> Minutes, both directions
+	for _, t := range times {
+		travel[[2]int{t.FromID, t.ToID}] = t.Minutes
+		travel[[2]int{t.ToID, t.FromID}] = t.Minutes
+	}
+
+	// 2. Every store with its own crew
+	rosters := make([]*models.Roster, len(locations))
+	demands := make([]map[int]int, len(locations))
+	for i, l := range locations {
+		fmt.Printf("\n=== %s ===", l.Name)
+		local := store.AtLocation(l.ID)
+
+		// Each store is an equal share of the progress
+		storeCtx := ctx
+		if report, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+			done := i
+			storeCtx = WithProgress(ctx, func(p float64) {
+				report((float64(done) + p) / float64(len(locations)))
+			})
+		}
+		roster, err := SmartTetris(storeCtx, local)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.Name, err)
+		}
+		roster.LocationID = l.ID
+		rosters[i] = roster
+
+		curve, err := local.Demands()
+		if err != nil {
+			return nil, err
+		}
+		demands[i] = make(map[int]int)
+		for _, d := range curve {
+			demands[i][d.HourOfDay] = d.Needed
+		}
+	}
+
+	// 3. Who works where, across the region
+	in, err := readSnapshot(store)
+	if err != nil {
+		return nil, err
+	}
+	blocked := in.blocked
+	for empID, hours := range in.locks.forbidden {
+		for h := range hours {
+			markHours(blocked, empID, h, h+1)
+		}
+	}
+
+	workingAt := make(map[int]map[int]int) // EmployeeID -> Hour -> LocationID
+	hoursWorked := make(map[int]int)
+	book := func(empID, hour, locationID int) {
+		if workingAt[empID] == nil {
+			workingAt[empID] = make(map[int]int)
+		}
+		workingAt[empID][hour] = locationID
+		hoursWorked[empID]++
+	}
+	for _, r := range rosters {
+		for _, a := range r.Assignments {
+			book(a.Employee.ID, a.Hour, r.LocationID)
+		}
+	}
+
+	var floaters []models.Employee
+	for _, emp := range in.employees {
+		if emp.Floats && inRegion[emp.LocationID] {
+			floaters = append(floaters, emp)
+		}
+	}
+
+	// free: can this floater work at the store for this hour, trip included?
+	free := func(emp models.Employee, locationID, hour int) bool {
+		if blocked[emp.ID][hour] {
+			return false
+		}
+		if _, busy := workingAt[emp.ID][hour]; busy {
+			return false
+		}
+		for h, at := range workingAt[emp.ID] {
+			if at == locationID {
+				continue
+			}
+			gap := hour - h
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap <= travelHours(travel[[2]int{at, locationID}]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// 4. Fill the gaps with floaters
+	const MaxDaily = 8
+
+	for i, l := range locations {
+		roster := rosters[i]
+		staffed := make(map[int]int)
+		seniorAt := make(map[int]bool)
+		for _, a := range roster.Assignments {
+			staffed[a.Hour]++
+			if a.IsSenior {
+				seniorAt[a.Hour] = true
+			}
+		}
+
+		var hours []int
+		for h := range demands[i] {
+			hours = append(hours, h)
+		}
+		sort.Ints(hours)
+		short := func(h int) bool { return staffed[h] < demands[i][h] }
+
+		for idx := 0; idx < len(hours); {
+			hour := hours[idx]
+			if !short(hour) {
+				idx++
+				continue
+			}
+
+			type Candidate struct {
+				Emp    models.Employee
+				Covers int
+				Cost   float64
+			}
+			var candidates []Candidate
+
+			for _, emp := range floaters {
+				minutes, nearby := travel[[2]int{emp.LocationID, l.ID}]
+				if emp.LocationID == l.ID || !nearby {
+					continue
+				}
+
+				// How many consecutive short hours can this floater take from here?
+				covers := 0
+				for j := idx; j < len(hours); j++ {
+					h := hours[j]
+					if j > idx && h != hours[j-1]+1 {
+						break
+					}
+					if !short(h) || !free(emp, l.ID, h) || hoursWorked[emp.ID]+covers+1 > MaxDaily {
+						break
+					}
+					covers++
+				}
+				if covers == 0 {
+					continue
+				}
+
+				cost := emp.HourlyRate*float64(covers) + travelCost(emp, minutes)
+				candidates = append(candidates, Candidate{Emp: emp, Covers: covers, Cost: cost})
+			}
+
+			needSenior := !seniorAt[hour]
+			sort.Slice(candidates, func(a, b int) bool {
+				ca, cb := candidates[a], candidates[b]
+				if needSenior {
+					sa, sb := ca.Emp.SkillLevel >= 2, cb.Emp.SkillLevel >= 2
+					if sa != sb {
+						return sa
+					}
+				}
+				if ca.Covers != cb.Covers {
+					return ca.Covers > cb.Covers
+				}
+				return ca.Cost/float64(ca.Covers) < cb.Cost/float64(cb.Covers)
+			})
+
+			if len(candidates) == 0 {
+				// Nobody nearby can help: the hour stays short
+				idx++
+				continue
+			}
+
+			winner := candidates[0]
+			minutes := travel[[2]int{winner.Emp.LocationID, l.ID}]
+			fmt.Printf("[Float] %s covers %s %02d:00-%02d:00 (%d min travel)\n",
+				winner.Emp.Name, l.Name, hour, hour+winner.Covers, minutes)
+
+			for j := idx; j < idx+winner.Covers; j++ {
+				h := hours[j]
+				isSenior := winner.Emp.SkillLevel >= 2
+				roster.Assignments = append(roster.Assignments, models.Assignment{
+					Hour: h, Employee: winner.Emp, IsSenior: isSenior,
+				})
+				roster.TotalCost += winner.Emp.HourlyRate
+				roster.Unfilled--
+				staffed[h]++
+				if isSenior {
+					seniorAt[h] = true
+				}
+				book(winner.Emp.ID, h, l.ID)
+			}
+			roster.TotalCost += travelCost(winner.Emp, minutes)
+		}
+
+		sort.SliceStable(roster.Assignments, func(a, b int) bool {
+			return roster.Assignments[a].Hour < roster.Assignments[b].Hour
+		})
+	}
+
+	return rosters, nil
+}
+
+// travelHours is the gap a trip needs between two shifts, in whole hours
+func travelHours(minutes int) int {
+	return (minutes + 59) / 60
+}
+
+func travelCost(emp models.Employee, minutes int) float64 {
+	return emp.HourlyRate * float64(minutes) / 60
+}
@@ -0,0 +1,189 @@
+package scheduler
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// RepairRoster patches a published roster after a call-out instead of rebuilding it.
+// Only the hours lost to the new unavailability are re-filled, so everyone else keeps
+// their shift. Returns the repaired roster and the diff against the published one.
+//
+// Forbidden locks are respected. A pinned employee who calls out is still removed.
+//
+// Replacement ranking (Minimum Disruption):
+//  1. A Senior, if the gap leaves the hour without one (Safety first)
+//  2. Whoever covers the longest stretch of the gap (fewest changed shifts)
+//  3. Staff on call for that hour
+//  4. The cheapest hourly rate
+func RepairRoster(db *sql.DB, published *models.Roster, u models.Unavailability) (*models.Roster, models.RosterDiff, error) {
+	return Repair(database.NewSQLiteStore(db), published, u)
+}
+
+// Repair is RepairRoster against any Store
+func Repair(store database.Store, published *models.Roster, u models.Unavailability) (*models.Roster, models.RosterDiff, error) {
+	fmt.Printf("\n--- Repairing Roster (Call-Out: %s %02d:00-%02d:00) ---\n", u.EmployeeName, u.StartHour, u.EndHour)
+
+	// 1. Resolve who called out
+	absentID := u.EmployeeID
+	if absentID == 0 {
+		id, err := store.EmployeeIDByName(u.EmployeeName)
+		if err != nil {
+			return nil, models.RosterDiff{}, fmt.Errorf("unknown employee %q: %w", u.EmployeeName, err)
+		}
+		absentID = id
+	}
+
+	// 2. Fetch Employees, Unavailability and On-Call windows for the roster's store and day.
+	// Pins are not re-validated here: a pinned employee calling out is exactly the conflict being repaired.
+	if published.LocationID != 0 {
+		store = store.AtLocation(published.LocationID)
+	}
+	if !published.Date.IsZero() {
+		store = store.OnDay(published.Date)
+	}
+	in, err := readSnapshot(store)
+	if err != nil {
+		return nil, models.RosterDiff{}, err
+	}
+	employees, blocked, onCall := in.employees, in.blocked, in.onCall
+
+	// The new call-out may not be saved yet, and forbidden overrides are blocks too
+	markHours(blocked, absentID, u.StartHour, u.EndHour)
+	for empID, hours := range in.locks.forbidden {
+		for h := range hours {
+			markHours(blocked, empID, h, h+1)
+		}
+	}
+
+	// 3. Strip the lost hours, keep everything else as published
+	repaired := &models.Roster{Date: published.Date, LocationID: published.LocationID}
+	working := make(map[int]map[int]bool) // EmployeeID -> Hour -> IsWorking
+	hoursWorked := make(map[int]int)
+	var lost []models.Assignment
+
+	for _, a := range published.Assignments {
+		if a.Employee.ID == absentID && a.Hour >= u.StartHour && a.Hour < u.EndHour {
+			lost = append(lost, a)
+			continue
+		}
+		repaired.Assignments = append(repaired.Assignments, a)
+		repaired.TotalCost += a.Employee.HourlyRate
+		if working[a.Employee.ID] == nil {
+			working[a.Employee.ID] = make(map[int]bool)
+		}
+		working[a.Employee.ID][a.Hour] = true
+		hoursWorked[a.Employee.ID]++
+	}
+	repaired.Unfilled = published.Unfilled
+
+	seniorAt := func(hour int) bool {
+		for _, a := range repaired.Assignments {
+			if a.Hour == hour && a.Employee.SkillLevel >= 2 {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 4. Re-fill the gap, one replacement block at a time
+	const MaxDaily = 8
+
+	sort.Slice(lost, func(i, j int) bool { return lost[i].Hour < lost[j].Hour })
+	lostSenior := make(map[int]bool)
+	var gap []int
+	for _, a := range lost {
+		gap = append(gap, a.Hour)
+		lostSenior[a.Hour] = a.Employee.SkillLevel >= 2
+	}
+
+	for i := 0; i < len(gap); {
+		hour := gap[i]
+		needSenior := lostSenior[hour] && !seniorAt(hour)
+
+		type Candidate struct {
+			Emp    models.Employee
+			Covers int
+			OnCall bool
+		}
+		var candidates []Candidate
+
+		for _, emp := range employees {
+			if emp.ID == absentID {
+				continue
+			}
+
+			// How many consecutive gap hours can this person take from here?
+			covers := 0
+			for j := i; j < len(gap); j++ {
+				if j > i && gap[j] != gap[j-1]+1 {
+					break // The gap itself is not contiguous
+				}
+				h := gap[j]
+				if working[emp.ID][h] || blocked[emp.ID][h] {
+					break
+				}
+				if hoursWorked[emp.ID]+covers+1 > MaxDaily {
+					break
+				}
+				covers++
+			}
+			if covers == 0 {
+				continue
+			}
+
+			candidates = append(candidates, Candidate{Emp: emp, Covers: covers, OnCall: onCall[emp.ID][hour]})
+		}
+
+		sort.Slice(candidates, func(a, b int) bool {
+			ca, cb := candidates[a], candidates[b]
+			if needSenior {
+				sa, sb := ca.Emp.SkillLevel >= 2, cb.Emp.SkillLevel >= 2
+				if sa != sb {
+					return sa
+				}
+			}
+			if ca.Covers != cb.Covers {
+				return ca.Covers > cb.Covers
+			}
+			if ca.OnCall != cb.OnCall {
+				return ca.OnCall
+			}
+			return ca.Emp.HourlyRate < cb.Emp.HourlyRate
+		})
+
+		if len(candidates) == 0 {
+			// Nobody can step in: the hour stays short
+			repaired.Unfilled++
+			i++
+			continue
+		}
+
+		winner := candidates[0]
+		for j := i; j < i+winner.Covers; j++ {
+			h := gap[j]
+			repaired.Assignments = append(repaired.Assignments, models.Assignment{
+				Hour: h, Employee: winner.Emp, IsSenior: winner.Emp.SkillLevel >= 2,
+			})
+			repaired.TotalCost += winner.Emp.HourlyRate
+			if working[winner.Emp.ID] == nil {
+				working[winner.Emp.ID] = make(map[int]bool)
+			}
+			working[winner.Emp.ID][h] = true
+		}
+		hoursWorked[winner.Emp.ID] += winner.Covers
+		i += winner.Covers
+	}
+
+	sort.SliceStable(repaired.Assignments, func(i, j int) bool {
+		return repaired.Assignments[i].Hour < repaired.Assignments[j].Hour
+	})
+
+	// 5. Report the disruption
+	diff := DiffRosters(published, repaired)
+	return repaired, diff, nil
+}
@@ -1,31 +1,32 @@
 package scheduler
 
 import (
+	"context"
 	"database/sql"
 	"sort"
 
+	"github.com/iannsp/shiftopt/internal/database"
 	"github.com/iannsp/shiftopt/internal/models"
 )
 
 // RunSafeSchedule returns a Roster object instead of printing
 func RunSafeSchedule(db *sql.DB) (*models.Roster, error) {
-	// 1. Fetch & Sort Employees
-	rows, _ := db.Query("SELECT id, name, hourly_rate, skill_level FROM employees")
-	var employees []models.Employee
-	for rows.Next() {
-		var e models.Employee
-		rows.Scan(&e.ID, &e.Name, &e.HourlyRate, &e.SkillLevel)
-		employees = append(employees, e)
+	return SafeSchedule(context.Background(), database.NewSQLiteStore(db))
+}
+
+// SafeSchedule is RunSafeSchedule against any Store, stopping early if ctx is cancelled
+func SafeSchedule(ctx context.Context, store database.Store) (*models.Roster, error) {
+	// 1. Fetch & Sort Employees, with Demands and Manager overrides (Pinned / Forbidden)
+	in, err := loadSnapshot(store)
+	if err != nil {
+		return nil, err
 	}
-	rows.Close()
+	employees, locks := in.employees, in.locks
 
 	sort.Slice(employees, func(i, j int) bool {
 		return employees[i].HourlyRate < employees[j].HourlyRate
 	})
 
-	// 2. Fetch Demands
-	dRows, _ := db.Query("SELECT hour_of_day, needed FROM demands ORDER BY hour_of_day")
-	
 	roster := &models.Roster{
 		Assignments: []models.Assignment{},
 	}
@@ -33,16 +34,43 @@ func RunSafeSchedule(db *sql.DB) (*models.Roster, error) {
 	hoursWorked := make(map[int]int)
 	const MaxDailyHours = 8
 
-	for dRows.Next() {
-		var hour, needed int
-		dRows.Scan(&hour, &needed)
+	for i, hour := range in.hours {
+		if err := step(ctx, i, len(in.hours)); err != nil {
+			return nil, err
+		}
+
+		needed := in.demand[hour]
 
 		assignedThisHour := make(map[int]bool)
 		slotsFilled := 0
+		seniorPinned := false
+
+		// --- PASS 0: Pinned (Manager Overrides) ---
+		for _, emp := range employees {
+			if !locks.isPinned(emp.ID, hour) { continue }
+			isSenior := emp.SkillLevel >= 2
+			hoursWorked[emp.ID]++
+			roster.TotalCost += emp.HourlyRate
+			roster.Assignments = append(roster.Assignments, models.Assignment{
+				Hour: hour, Employee: emp, IsSenior: isSenior,
+			})
+			assignedThisHour[emp.ID] = true
+			slotsFilled++
+			if isSenior {
+				seniorPinned = true
+			}
+		}
+
+		// available: not forbidden, and the daily limit still has room beyond their pins
+		available := func(emp models.Employee) bool {
+			if assignedThisHour[emp.ID] || locks.isForbidden(emp.ID, hour) { return false }
+			return hoursWorked[emp.ID]+locks.pinnedFrom(emp.ID, hour) < MaxDailyHours
+		}
 
 		// --- PASS 1: Safety (Senior) ---
 		for _, emp := range employees {
-			if hoursWorked[emp.ID] >= MaxDailyHours { continue }
+			if seniorPinned { break }
+			if !available(emp) { continue }
 			if emp.SkillLevel >= 2 {
 				hoursWorked[emp.ID]++
 				roster.TotalCost += emp.HourlyRate
@@ -59,7 +87,7 @@ func RunSafeSchedule(db *sql.DB) (*models.Roster, error) {
 		if slotsFilled < needed {
 			for _, emp := range employees {
 				if slotsFilled >= needed { break }
-				if hoursWorked[emp.ID] >= MaxDailyHours || assignedThisHour[emp.ID] { continue }
+				if !available(emp) { continue }
 
 				hoursWorked[emp.ID]++
 				roster.TotalCost += emp.HourlyRate
@@ -74,7 +102,6 @@ func RunSafeSchedule(db *sql.DB) (*models.Roster, error) {
 			roster.Unfilled += (needed - slotsFilled)
 		}
 	}
-	dRows.Close()
 
 	return roster, nil
 }
@@ -1,58 +1,32 @@
 package scheduler
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sort"
 
+	"github.com/iannsp/shiftopt/internal/database"
 	"github.com/iannsp/shiftopt/internal/models"
 )
 
 // RunSmartTetris uses Penalty Scoring to optimize skill usage AND respects Availability
 func RunSmartTetris(db *sql.DB) (*models.Roster, error) {
-	fmt.Println("\n--- Generating Smart Tetris Schedule (Penalty Scoring) ---")
+	return SmartTetris(context.Background(), database.NewSQLiteStore(db))
+}
 
-	// 1. Fetch Employees
-	rows, _ := db.Query("SELECT id, name, hourly_rate, skill_level FROM employees")
-	var employees []models.Employee
-	for rows.Next() {
-		var e models.Employee
-		rows.Scan(&e.ID, &e.Name, &e.HourlyRate, &e.SkillLevel)
-		employees = append(employees, e)
-	}
-	rows.Close()
-
-	// 1.5 Fetch Unavailability (The Missing Link)
-	// Map: EmployeeID -> Map[Hour] -> IsBlocked
-	blocked := make(map[int]map[int]bool)
-	uRows, err := db.Query("SELECT employee_id, start_hour, end_hour FROM unavailability")
-	if err == nil {
-		for uRows.Next() {
-			var empID, start, end int
-			uRows.Scan(&empID, &start, &end)
-			
-			if blocked[empID] == nil {
-				blocked[empID] = make(map[int]bool)
-			}
-			// Block every hour in the range [start, end)
-			for h := start; h < end; h++ {
-				blocked[empID][h] = true
-			}
-		}
-		uRows.Close()
-	}
+// SmartTetris is RunSmartTetris against any Store, stopping early if ctx is cancelled
+func SmartTetris(ctx context.Context, store database.Store) (*models.Roster, error) {
+	fmt.Println("\n--- Generating Smart Tetris Schedule (Penalty Scoring) ---")
 
-	// 2. Fetch Demands
-	dRows, _ := db.Query("SELECT hour_of_day, needed FROM demands ORDER BY hour_of_day")
-	var sortedHours []int
-	demands := make(map[int]int)
-	for dRows.Next() {
-		var h, n int
-		dRows.Scan(&h, &n)
-		demands[h] = n
-		sortedHours = append(sortedHours, h)
+	// 1. Fetch Employees, Unavailability (Map: EmployeeID -> Map[Hour] -> IsBlocked),
+	// Demands and Manager overrides (Pinned / Forbidden)
+	in, err := loadSnapshot(store)
+	if err != nil {
+		return nil, err
 	}
-	dRows.Close()
+	employees, blocked, locks := in.employees, in.blocked, in.locks
+	demands, sortedHours := in.demand, in.hours
 
 	roster := &models.Roster{}
 	shiftEnd := make(map[int]int)
@@ -66,9 +40,20 @@ func RunSmartTetris(db *sql.DB) (*models.Roster, error) {
 	)
 
 	// 3. The Loop
-	for _, hour := range sortedHours {
+	for i, hour := range sortedHours {
+		if err := step(ctx, i, len(sortedHours)); err != nil {
+			return nil, err
+		}
+
 		needed := demands[hour]
 
+		// Pinned staff start (or extend) a block that covers their whole pinned window
+		for _, emp := range employees {
+			if locks.isPinned(emp.ID, hour) && shiftEnd[emp.ID] <= hour {
+				shiftEnd[emp.ID] = locks.pinEnd(emp.ID, hour)
+			}
+		}
+
 		// A. Analyze Current State
 		activeCount := 0
 		seniorPresent := false
@@ -109,16 +94,16 @@ func RunSmartTetris(db *sql.DB) (*models.Roster, error) {
 					// 1. Is already working?
 					if activeStaff[emp.ID] { continue }
 					
-					// 2. Will bust 8-hour limit?
-					if hoursWorkedTotal[emp.ID]+MinBlock > MaxDaily { continue }
+					// 2. Will bust 8-hour limit? (Hours pinned later in the day are reserved)
+					if hoursWorkedTotal[emp.ID]+MinBlock+locks.pinnedFrom(emp.ID, hour+MinBlock) > MaxDaily { continue }
 
 					// 3. **AVAILABILITY CHECK** (The Fix)
-					// Check if ANY hour in the proposed block (hour -> hour+4) is blocked
+					// Check if ANY hour in the proposed block (hour -> hour+4) is blocked (or forbidden by a manager)
 					isBlocked := false
 					for b := 0; b < MinBlock; b++ {
 						// Logic: If blocked[Alice][09:00] is true, she cannot take a shift starting at 09:00
 						// We check hour, hour+1, hour+2, hour+3
-						if blocked[emp.ID][hour+b] {
+						if blocked[emp.ID][hour+b] || locks.isForbidden(emp.ID, hour+b) {
 							isBlocked = true
 							break
 						}
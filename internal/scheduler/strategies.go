@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// Strategy builds a roster from whatever the Store holds
+type Strategy func(ctx context.Context, store database.Store) (*models.Roster, error)
+
+// strategies are the schedulers that return a Roster, by the name clients use to pick one
+var strategies = map[string]Strategy{
+	"safe":   SafeSchedule,
+	"tetris": TetrisSchedule,
+	"smart":  SmartTetris,
+}
+
+// DefaultStrategy is the scheduler used when none is chosen
+const DefaultStrategy = "smart"
+
+// LookupStrategy finds a scheduler by name
+func LookupStrategy(name string) (Strategy, bool) {
+	s, ok := strategies[name]
+	return s, ok
+}
+
+// StrategyNames lists the known schedulers, sorted
+func StrategyNames() []string {
+	var names []string
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,42 +1,37 @@
 package scheduler
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sort"
 
+	"github.com/iannsp/shiftopt/internal/database"
 	"github.com/iannsp/shiftopt/internal/models"
 )
 
 // RunTetrisSchedule implements Block Scheduling (Min 4 hours contiguous)
 func RunTetrisSchedule(db *sql.DB) (*models.Roster, error) {
+	return TetrisSchedule(context.Background(), database.NewSQLiteStore(db))
+}
+
+// TetrisSchedule is RunTetrisSchedule against any Store, stopping early if ctx is cancelled
+func TetrisSchedule(ctx context.Context, store database.Store) (*models.Roster, error) {
 	fmt.Println("\n--- Generating Tetris Schedule (Block Continuity) ---")
 
-	// 1. Setup Data
-	rows, _ := db.Query("SELECT id, name, hourly_rate, skill_level FROM employees")
-	var employees []models.Employee
-	for rows.Next() {
-		var e models.Employee
-		rows.Scan(&e.ID, &e.Name, &e.HourlyRate, &e.SkillLevel)
-		employees = append(employees, e)
+	// 1. Setup Data (Employees, Demands, Manager overrides)
+	in, err := loadSnapshot(store)
+	if err != nil {
+		return nil, err
 	}
-	rows.Close()
+	employees, locks := in.employees, in.locks
 
 	// Sort cheapest first
 	sort.Slice(employees, func(i, j int) bool {
 		return employees[i].HourlyRate < employees[j].HourlyRate
 	})
 
-	dRows, _ := db.Query("SELECT hour_of_day, needed FROM demands ORDER BY hour_of_day")
-	demands := make(map[int]int)
-	var sortedHours []int
-	for dRows.Next() {
-		var h, n int
-		dRows.Scan(&h, &n)
-		demands[h] = n
-		sortedHours = append(sortedHours, h)
-	}
-	dRows.Close()
+	demands, sortedHours := in.demand, in.hours
 
 	roster := &models.Roster{}
 	
@@ -51,9 +46,20 @@ func RunTetrisSchedule(db *sql.DB) (*models.Roster, error) {
 	const MaxDaily = 8
 
 	// 2. The Tetris Loop
-	for _, hour := range sortedHours {
+	for i, hour := range sortedHours {
+		if err := step(ctx, i, len(sortedHours)); err != nil {
+			return nil, err
+		}
+
 		needed := demands[hour]
 		
+		// Pinned staff start (or extend) a block that covers their whole pinned window
+		for _, emp := range employees {
+			if locks.isPinned(emp.ID, hour) && shiftEnd[emp.ID] <= hour {
+				shiftEnd[emp.ID] = locks.pinEnd(emp.ID, hour)
+			}
+		}
+
 		// A. Who is ALREADY here? (The Continuity Check)
 		activeCount := 0
 		activeStaff := make(map[int]bool)
@@ -86,9 +92,20 @@ func RunTetrisSchedule(db *sql.DB) (*models.Roster, error) {
 					
 					// 2. Can they take a 4-hour block without busting 8 hours?
 					// (Simple check: Just checking total cap for now)
-					if hoursWorkedTotal[emp.ID] + MinBlock > MaxDaily { continue }
+					// (Hours pinned later in the day are reserved)
+					if hoursWorkedTotal[emp.ID] + MinBlock + locks.pinnedFrom(emp.ID, hour+MinBlock) > MaxDaily { continue }
+
+					// 3. Is any hour of the block forbidden by a manager override?
+					forbidden := false
+					for b := 0; b < MinBlock; b++ {
+						if locks.isForbidden(emp.ID, hour+b) {
+							forbidden = true
+							break
+						}
+					}
+					if forbidden { continue }
 
-					// 3. Assign the Block
+					// 4. Assign the Block
 					// Their shift will end at hour + MinBlock
 					shiftEnd[emp.ID] = hour + MinBlock
 					
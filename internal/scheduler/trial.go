@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// Change is what a trial run adds to the Store's inputs without saving it
+type Change struct {
+	Unavailability []models.Unavailability
+	Locks          []models.Lock // Pins and forbids; EmployeeName must be set
+}
+
+// Empty reports whether the change adds nothing
+func (c Change) Empty() bool {
+	return len(c.Unavailability) == 0 && len(c.Locks) == 0
+}
+
+// Trial re-runs SmartTetris for one day as if change were saved, and compares the result
+// with before, the roster in force that day (nil = none yet). Nothing is written.
+func Trial(ctx context.Context, store database.Store, day time.Time, change Change, before *models.Roster) (*models.Roster, models.RosterDiff, error) {
+	after, err := SmartTetris(ctx, withChange{Store: store.OnDay(day), change: change, day: day})
+	if err != nil {
+		return nil, models.RosterDiff{}, err
+	}
+	after.Date = day
+	if before == nil {
+		before = &models.Roster{Date: day}
+	}
+	after.LocationID = before.LocationID
+	return after, DiffRosters(before, after), nil
+}
+
+// withChange is a Store that also returns the change's blocks and locks for its day
+type withChange struct {
+	database.Store
+	change Change
+	day    time.Time
+}
+
+func (w withChange) Unavailability() ([]models.Unavailability, error) {
+	blocks, err := w.Store.Unavailability()
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range w.change.Unavailability {
+		if u.Date.IsZero() || u.Date.Equal(w.day) {
+			blocks = append(blocks, u)
+		}
+	}
+	return blocks, nil
+}
+
+func (w withChange) Locks() ([]models.Lock, error) {
+	locks, err := w.Store.Locks()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range w.change.Locks {
+		if l.Date.IsZero() || l.Date.Equal(w.day) {
+			locks = append(locks, l)
+		}
+	}
+	return locks, nil
+}
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// Validate checks a roster that did not come from a scheduler (e.g. a hand-edited export)
+// against the hard rules the schedulers honour: opening hours, unavailability, manager locks,
+// the daily hour limit and a senior on site. It also refreshes TotalCost and Unfilled.
+func Validate(store database.Store, roster *models.Roster) ([]models.Violation, error) {
+	if !roster.Date.IsZero() {
+		store = store.OnDay(roster.Date)
+	}
+	in, err := readSnapshot(store)
+	if err != nil {
+		return nil, err
+	}
+	const MaxDaily = 8
+
+	var violations []models.Violation
+	report := func(hour, empID int, format string, args ...any) {
+		violations = append(violations, models.Violation{Hour: hour, EmployeeID: empID, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	// 1. Per assignment
+	staffed := make(map[int]int)
+	senior := make(map[int]bool)
+	worked := make(map[int]map[int]bool) // EmployeeID -> Hour
+	names := make(map[int]string)
+	roster.TotalCost = 0
+	for _, a := range roster.Assignments {
+		e := a.Employee
+		names[e.ID] = e.Name
+		if worked[e.ID] == nil {
+			worked[e.ID] = make(map[int]bool)
+		}
+		if worked[e.ID][a.Hour] {
+			report(a.Hour, e.ID, "%s is listed twice at %02d:00", e.Name, a.Hour)
+			continue
+		}
+		worked[e.ID][a.Hour] = true
+		staffed[a.Hour]++
+		senior[a.Hour] = senior[a.Hour] || e.SkillLevel >= 2
+		roster.TotalCost += e.HourlyRate
+
+		if _, open := in.demand[a.Hour]; !open {
+			report(a.Hour, e.ID, "%s works at %02d:00 but the store is closed", e.Name, a.Hour)
+		}
+		if in.blocked[e.ID][a.Hour] {
+			report(a.Hour, e.ID, "%s works at %02d:00 but is unavailable", e.Name, a.Hour)
+		}
+		if in.locks.isForbidden(e.ID, a.Hour) {
+			report(a.Hour, e.ID, "%s works at %02d:00 but is forbidden by a manager", e.Name, a.Hour)
+		}
+	}
+
+	// 2. Per employee: daily limit and pins
+	for _, id := range sortedKeys(worked) {
+		if n := len(worked[id]); n > MaxDaily {
+			report(-1, id, "%s works %d hours (max %d)", names[id], n, MaxDaily)
+		}
+	}
+	for _, l := range in.lockList {
+		if l.Kind != models.LockPinned {
+			continue
+		}
+		for h := l.StartHour; h < l.EndHour; h++ {
+			if !worked[l.EmployeeID][h] {
+				report(h, l.EmployeeID, "%s is pinned at %02d:00 but not on the roster", l.EmployeeName, h)
+				break
+			}
+		}
+	}
+
+	// 3. Per hour: a senior whenever anyone works, and the demand left uncovered
+	roster.Unfilled = 0
+	for _, h := range in.hours {
+		if staffed[h] > 0 && !senior[h] {
+			report(h, 0, "no senior on site at %02d:00", h)
+		}
+		if gap := in.demand[h] - staffed[h]; gap > 0 {
+			roster.Unfilled += gap
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Hour < violations[j].Hour })
+	return violations, nil
+}
+
+func sortedKeys(m map[int]map[int]bool) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
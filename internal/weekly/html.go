@@ -0,0 +1,17 @@
+package weekly
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+)
+
+//go:embed week.html
+var weekHTML string
+
+var page = template.Must(template.New("week").Parse(weekHTML))
+
+// RenderHTML writes the grid as a page meant for printing (A4 landscape)
+func RenderHTML(w io.Writer, week Week) error {
+	return page.Execute(w, week)
+}
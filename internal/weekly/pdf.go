@@ -0,0 +1,230 @@
+package weekly
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The PDF is drawn by hand on A4 landscape with the two standard Helvetica fonts,
+// which every PDF reader has built in: no font embedding, no third-party library.
+const (
+	pageWidth  = 842.0
+	pageHeight = 595.0
+	margin     = 36.0
+	nameWidth  = 170.0
+	totalWidth = 56.0
+	dayWidth   = (pageWidth - 2*margin - nameWidth - totalWidth) / 7
+	headHeight = 28.0
+	rowHeight  = 18.0
+	textSize   = 9.0
+	rowsTop    = pageHeight - margin - 40 // Below the title block
+)
+
+// rowsPerPage leaves room for the header and the totals row:
+// (rowsTop - margin - headHeight - rowHeight) / rowHeight, rounded down
+const rowsPerPage = 24
+
+// RenderPDF writes the grid as a PDF, repeating the header on every page
+func RenderPDF(w io.Writer, week Week) error {
+	// 1. Draw each page
+	var pages []string
+	rows := week.Rows
+	for first := true; first || len(rows) > 0; first = false {
+		n := min(len(rows), rowsPerPage)
+		last := n == len(rows)
+		pages = append(pages, drawPage(week, rows[:n], last, len(pages)+1))
+		rows = rows[n:]
+	}
+
+	// 2. Objects: catalog, page tree, fonts, then a page and its content per page
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range pages {
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 6+2*i))
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	// 3. File: header, objects, cross-reference table, trailer
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// drawPage returns the content stream of one page
+func drawPage(week Week, rows []Row, last bool, number int) string {
+	c := &canvas{}
+
+	// 1. Title block
+	title := week.Title
+	if week.Location != "" {
+		title += " - " + week.Location
+	}
+	c.text("F2", 14, margin, pageHeight-margin-14, title)
+	meta := "Week of " + week.Start.Format("Monday 2 January 2006")
+	if number > 1 {
+		meta += fmt.Sprintf(" (page %d)", number)
+	}
+	c.text("F1", textSize, margin, pageHeight-margin-28, meta)
+
+	// 2. Header row
+	top := rowsTop
+	c.fill(0.9, margin, top-headHeight, pageWidth-2*margin, headHeight)
+	c.text("F2", textSize, margin+5, top-17, "Employee")
+	for i, day := range week.Days {
+		x := margin + nameWidth + float64(i)*dayWidth
+		c.centered("F2", textSize, x, dayWidth, top-12, day.Format("Mon"))
+		c.centered("F1", textSize-1, x, dayWidth, top-23, day.Format("2 Jan"))
+	}
+	c.centered("F2", textSize, pageWidth-margin-totalWidth, totalWidth, top-17, "Hours")
+	y := top - headHeight
+
+	// 3. One line per employee
+	for _, r := range rows {
+		name := r.Name
+		if r.Senior {
+			name += " *"
+		}
+		c.cells(y, "F1", name, r.Cells[:], fmt.Sprint(r.TotalHours))
+		y -= rowHeight
+	}
+
+	// 4. Totals on the last page
+	if last {
+		c.fill(0.95, margin, y-rowHeight, pageWidth-2*margin, rowHeight)
+		days := make([]string, 7)
+		for i, h := range week.DayHours {
+			days[i] = fmt.Sprint(h)
+		}
+		c.cells(y, "F2", "Staff-hours", days, fmt.Sprint(week.TotalHours))
+		y -= rowHeight
+		c.text("F1", textSize-1, margin, y-14, fmt.Sprintf("* Senior    Labour cost $%.2f", week.TotalCost))
+	}
+
+	// 5. Grid lines over the table
+	c.line(margin, top, pageWidth-margin, top)
+	for ly := top - headHeight; ly >= y-0.1; ly -= rowHeight {
+		c.line(margin, ly, pageWidth-margin, ly)
+	}
+	for _, x := range columnEdges() {
+		c.line(x, top, x, y)
+	}
+	return c.String()
+}
+
+func columnEdges() []float64 {
+	edges := []float64{margin, margin + nameWidth}
+	for i := 1; i <= 7; i++ {
+		edges = append(edges, margin+nameWidth+float64(i)*dayWidth)
+	}
+	return append(edges, pageWidth-margin)
+}
+
+// canvas collects PDF drawing operators
+type canvas struct {
+	bytes.Buffer
+}
+
+// cells writes one table row whose top edge is at y
+func (c *canvas) cells(y float64, font, name string, days []string, total string) {
+	baseline := y - rowHeight + 5.5
+	c.text(font, textSize, margin+5, baseline, fit(name, textSize, nameWidth-10))
+	for i, cell := range days {
+		c.centered(font, textSize, margin+nameWidth+float64(i)*dayWidth, dayWidth, baseline, fit(cell, textSize, dayWidth-6))
+	}
+	c.centered("F2", textSize, pageWidth-margin-totalWidth, totalWidth, baseline, total)
+}
+
+func (c *canvas) text(font string, size, x, y float64, s string) {
+	fmt.Fprintf(c, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+func (c *canvas) centered(font string, size, x, width, y float64, s string) {
+	c.text(font, size, x+(width-textWidth(s, size))/2, y, s)
+}
+
+func (c *canvas) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(c, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+func (c *canvas) fill(gray, x, y, w, h float64) {
+	fmt.Fprintf(c, "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, y, w, h)
+}
+
+// helvetica holds the glyph widths of Helvetica for ' '..'~' (1/1000 em)
+var helvetica = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+func textWidth(s string, size float64) float64 {
+	total := 0
+	for _, r := range s {
+		if r >= ' ' && r <= '~' {
+			total += helvetica[r-' ']
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// fit shortens s with "..." until it fits the width
+func fit(s string, size, width float64) string {
+	if textWidth(s, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// pdfString encodes s as WinAnsi inside a literal string.
+// Latin-1 maps directly, the en dash is 0x96 and anything else becomes '?'.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '–':
+			b.WriteString(`\226`)
+		case r >= ' ' && r <= '~':
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&b, `\%03o`, r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
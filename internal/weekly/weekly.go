@@ -0,0 +1,119 @@
+// Package weekly lays a week of rosters out as the grid managers pin to the wall:
+// employees down, days across, "09–13" cells and hour totals per person and per day.
+// It renders the grid as print-ready HTML and as a PDF, both without external dependencies.
+package weekly
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+// Week is the whole grid
+type Week struct {
+	Title       string
+	Location    string
+	Start       time.Time    // Monday
+	Days        [7]time.Time // Monday..Sunday
+	Rows        []Row
+	DayHours    [7]int // Staff-hours per day
+	TotalHours  int
+	TotalCost   float64
+	Versions    []int // Roster versions the grid was built from
+	GeneratedAt time.Time
+}
+
+// Row is one employee's week
+type Row struct {
+	Name       string
+	Senior     bool
+	Cells      [7]string // "09–13", "09–13, 16–20" for split shifts, "" when off
+	Hours      [7]int
+	TotalHours int
+}
+
+// Monday returns the start of the week holding t (as a UTC calendar day)
+func Monday(t time.Time) time.Time {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// Build lays out the rosters of one week. Rosters outside the week are ignored;
+// several rosters on one day (one per store) are merged.
+func Build(start time.Time, rosters []*models.Roster) Week {
+	w := Week{Title: "Weekly roster", Start: Monday(start), GeneratedAt: time.Now()}
+	for i := range w.Days {
+		w.Days[i] = w.Start.AddDate(0, 0, i)
+	}
+
+	// 1. Shifts per employee per day
+	rows := make(map[int]*Row)
+	shifts := make(map[int]*[7][]string)
+	for _, roster := range rosters {
+		day := int(roster.Date.Sub(w.Start).Hours() / 24)
+		if roster.Date.Before(w.Start) || day >= 7 {
+			continue
+		}
+		w.TotalCost += roster.TotalCost
+		for _, s := range scheduler.Shifts(roster) {
+			r := rows[s.Employee.ID]
+			if r == nil {
+				r = &Row{Name: s.Employee.Name, Senior: s.Employee.SkillLevel >= 2}
+				rows[s.Employee.ID] = r
+				shifts[s.Employee.ID] = &[7][]string{}
+			}
+			shifts[s.Employee.ID][day] = append(shifts[s.Employee.ID][day], fmt.Sprintf("%02d–%02d", s.StartHour, s.EndHour))
+			r.Hours[day] += s.EndHour - s.StartHour
+		}
+	}
+
+	// 2. Cells and totals, staff in name order
+	for id, r := range rows {
+		for day, cell := range shifts[id] {
+			r.Cells[day] = strings.Join(cell, ", ")
+			r.TotalHours += r.Hours[day]
+			w.DayHours[day] += r.Hours[day]
+		}
+		w.TotalHours += r.TotalHours
+		w.Rows = append(w.Rows, *r)
+	}
+	sort.Slice(w.Rows, func(i, j int) bool { return w.Rows[i].Name < w.Rows[j].Name })
+	return w
+}
+
+// Load builds the week holding start from the saved roster versions the Store can see.
+// Each day (and store) uses its newest published version, or its newest draft if none is published.
+func Load(store database.Store, start time.Time) (Week, error) {
+	monday := Monday(start)
+	rosters, versions, err := database.EffectiveRosters(store, monday, monday.AddDate(0, 0, 7))
+	if err != nil {
+		return Week{}, err
+	}
+
+	w := Build(monday, rosters)
+	locations := make(map[int]bool)
+	for _, v := range versions {
+		w.Versions = append(w.Versions, v.ID)
+		locations[v.LocationID] = true
+	}
+
+	// Name the store when the grid covers exactly one
+	if len(locations) == 1 {
+		all, err := store.Locations()
+		if err != nil {
+			return Week{}, err
+		}
+		for _, l := range all {
+			if locations[l.ID] && len(all) > 1 {
+				w.Location = l.Name
+			}
+		}
+	}
+	return w, nil
+}
@@ -0,0 +1,327 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/api"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/jobs"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// chatStub is an OpenAI-compatible /chat/completions endpoint answering replies in turn,
+// then the last one again. It records the requests it was sent.
+func chatStub(t *testing.T, status int, replies ...string) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var requests []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body == nil {
+			t.Errorf("request body: %v", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body["authorization"] = r.Header.Get("Authorization")
+		requests = append(requests, body)
+		if status != http.StatusOK {
+			http.Error(w, "model not loaded", status)
+			return
+		}
+		reply := replies[min(len(requests), len(replies))-1]
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]string{"role": "assistant", "content": reply}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestOpenAIParser(t *testing.T) {
+	// 1. A fenced JSON answer, as small local models tend to give
+	srv, requests := chatStub(t, http.StatusOK, "```json\n{\"EmployeeID\": 5, \"EmployeeName\": \"Eve\", \"StartHour\": 13, \"EndHour\": 17, \"Reason\": \"School run\"}\n```")
+	parser, err := ai.NewParser(ai.Config{Provider: ai.ProviderOpenAI, BaseURL: srv.URL + "/v1/", APIKey: "sk-test", Model: "llama3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := parser.Parse(context.Background(), ai.Message{Text: "Eve has the school run this afternoon", Crew: ai.DemoCrew})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("%d constraints, want 1", len(found))
+	}
+	if u := found[0]; u.EmployeeID != 5 || u.EmployeeName != "Eve (Jun)" || u.StartHour != 13 || u.EndHour != 17 || u.Reason != "School run" {
+		t.Errorf("parsed %+v", u)
+	}
+
+	// 2. What the server was sent
+	if len(*requests) != 1 {
+		t.Fatalf("%d requests, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+	if req["model"] != "llama3" || req["authorization"] != "Bearer sk-test" {
+		t.Errorf("model %v, authorization %v", req["model"], req["authorization"])
+	}
+	if format, _ := req["response_format"].(map[string]any); format["type"] != "json_object" {
+		t.Errorf("response_format %v, want json_object", req["response_format"])
+	}
+	messages, _ := req["messages"].([]any)
+	if len(messages) != 1 {
+		t.Fatalf("messages %v", messages)
+	}
+	content, _ := messages[0].(map[string]any)["content"].(string)
+	if !strings.Contains(content, "Eve has the school run this afternoon") || !strings.Contains(content, "ID 8: Hank (Grinder) (also: Hank)") {
+		t.Errorf("prompt misses the input or the crew:\n%s", content)
+	}
+}
+
+func TestOpenAIParserErrors(t *testing.T) {
+	srv, _ := chatStub(t, http.StatusServiceUnavailable, "")
+	parser := &ai.OpenAIParser{BaseURL: srv.URL + "/v1"}
+	if _, err := parser.Parse(context.Background(), ai.Message{Text: "Alice is off", Crew: ai.DemoCrew}); err == nil || !strings.Contains(err.Error(), "model not loaded") {
+		t.Errorf("error %v, want the server's message", err)
+	}
+
+	junk, _ := chatStub(t, http.StatusOK, "Sure! Alice is off in the morning.")
+	parser = &ai.OpenAIParser{BaseURL: junk.URL + "/v1"}
+	if _, err := parser.Parse(context.Background(), ai.Message{Text: "Alice is off", Crew: ai.DemoCrew}); err == nil {
+		t.Error("a non-JSON answer parsed")
+	}
+
+	// The rules take over when the model fails
+	found, err := ai.WithFallback(parser, ai.RuleParser{}).Parse(context.Background(), ai.Message{Text: "Bob is sick in the morning", Crew: ai.DemoCrew})
+	if err != nil || len(found) != 1 {
+		t.Fatalf("fallback %+v, %v", found, err)
+	}
+	if u := found[0]; u.EmployeeName != "Bob (Vet)" || u.StartHour != 8 || u.EndHour != 12 {
+		t.Errorf("fallback %+v", u)
+	}
+}
+
+func TestNewParser(t *testing.T) {
+	if _, err := ai.NewParser(ai.Config{Provider: "claude-on-a-napkin"}); err == nil {
+		t.Error("unknown provider accepted")
+	}
+	if _, err := ai.NewParser(ai.Config{Provider: ai.ProviderGemini}); err == nil {
+		t.Error("gemini without a key accepted")
+	}
+	if p, err := ai.NewParser(ai.Config{}); err != nil {
+		t.Error(err)
+	} else if _, ok := p.(ai.RuleParser); !ok {
+		t.Errorf("default parser %T, want rules", p)
+	}
+
+	t.Setenv("SHIFTOPT_AI_PROVIDER", "")
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_BASE_URL", "http://localhost:11434/v1")
+	if c := ai.ConfigFromEnv(); c.Provider != ai.ProviderOpenAI || c.BaseURL != "http://localhost:11434/v1" {
+		t.Errorf("config %+v, want the local OpenAI-compatible server", c)
+	}
+}
+
+func TestCrewFrom(t *testing.T) {
+	crew := ai.CrewFrom([]models.Employee{
+		{ID: 10, Name: "Mary Ann Lee (Barista)"},
+		{ID: 11, Name: "Mary Jones"},
+		{ID: 12, Name: "Tom (Vet)"},
+	})
+	if got := strings.Join(crew[0].Aliases, "|"); got != "Mary Ann Lee" {
+		t.Errorf("aliases %q: the shared first name must not be one", got)
+	}
+	if got := strings.Join(crew[2].Aliases, "|"); got != "Tom" {
+		t.Errorf("aliases %q, want Tom", got)
+	}
+
+	for input, want := range map[string]int{
+		"mary ann lee is off this morning": 10,
+		"Mary Jones has the dentist":       11,
+		"Tom's kid is sick, all day":       12,
+	} {
+		found, err := ai.RuleParser{}.Parse(context.Background(), ai.Message{Text: input, Crew: crew})
+		if err != nil || len(found) != 1 || found[0].EmployeeID != want {
+			t.Errorf("%q: %+v (%v), want employee %d", input, found, err, want)
+		}
+	}
+
+	// A shared first name is not guessed: it is asked about
+	found, err := ai.RuleParser{}.Parse(context.Background(), ai.Message{Text: "Mary is off", Crew: crew})
+	if err != nil || len(found) != 1 || found[0].EmployeeID != 0 || len(found[0].Unclear) == 0 {
+		t.Fatalf("ambiguous first name: %+v (%v), want it unclear", found, err)
+	}
+	if a := found[0].Unclear[0]; a.Field != ai.FieldEmployee || len(a.Options) != 2 || a.Options[0].ID != 10 || a.Options[1].ID != 11 {
+		t.Errorf("unclear %+v, want employee 10 or 11", a)
+	}
+}
+
+func TestParseConstraintReadsTheStoresCrew(t *testing.T) {
+	t.Setenv("SHIFTOPT_AI_PROVIDER", ai.ProviderRules)
+	store := clarifyStore(t) // Tom is employee 3, Carol in the demo crew
+
+	u, err := ai.ParseConstraint(store, "Tom is off in the morning")
+	if err != nil || u.EmployeeName != "Tom (Vet)" || u.EmployeeID != 3 || u.StartHour != 8 || u.EndHour != 12 {
+		t.Errorf("ParseConstraint: %+v (%v), want Tom 08:00-12:00", u, err)
+	}
+	if _, err := ai.ParseConstraint(store, "Carol is off in the morning"); err == nil {
+		t.Error("ParseConstraint found Carol, who is not on this crew")
+	}
+}
+
+func TestRecordValidatesEmployee(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+	id, err := store.AddEmployee(models.Employee{Name: "Zoe Quinn", HourlyRate: 25, SkillLevel: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. The prompt lists the real staff, and the ID the model returns is checked
+	srv, requests := chatStub(t, http.StatusOK, fmt.Sprintf(`{"EmployeeID": %d, "StartHour": 8, "EndHour": 12, "Reason": "Exam"}`, id))
+	parser := &ai.OpenAIParser{BaseURL: srv.URL + "/v1"}
+	outcome, err := ai.Record(context.Background(), store, parser, "Zoe has an exam this morning", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := outcome.Constraints; outcome.Held() || len(found) != 1 || found[0].EmployeeID != id || found[0].EmployeeName != "Zoe Quinn" {
+		t.Errorf("recorded %+v", outcome)
+	}
+	content := (*requests)[0]["messages"].([]any)[0].(map[string]any)["content"].(string)
+	if !strings.Contains(content, fmt.Sprintf("ID %d: Zoe Quinn (also: Zoe)", id)) || strings.Contains(content, "Alice (Vet)") {
+		t.Errorf("prompt does not list the staff table:\n%s", content)
+	}
+
+	// 2. An invented ID saves nothing, not even the valid constraint next to it
+	bad, _ := chatStub(t, http.StatusOK, fmt.Sprintf(`{"constraints": [
+		{"EmployeeID": %d, "StartHour": 13, "EndHour": 17},
+		{"EmployeeID": 999, "EmployeeName": "Zoe Quinn", "StartHour": 8, "EndHour": 12}]}`, id))
+	if _, err := ai.Record(context.Background(), store, &ai.OpenAIParser{BaseURL: bad.URL + "/v1"}, "Zoe is off", time.Now()); !errors.Is(err, ai.ErrUnknownEmployee) {
+		t.Errorf("ID 999: %v, want ErrUnknownEmployee", err)
+	}
+	blocks, err := store.Unavailability()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || blocks[0].EmployeeID != id || blocks[0].StartHour != 8 || blocks[0].EndHour != 12 {
+		t.Errorf("saved %+v, want only Zoe 8-12", blocks)
+	}
+}
+
+func TestRuleParserMultiple(t *testing.T) {
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	day := func(d int) string { return wednesday.AddDate(0, 0, d).Format("Mon 02") }
+
+	cases := []struct {
+		text string
+		want []string // "Name day start-end"
+	}{
+		{"Alice can't do Monday morning or Friday, and Bob is off Wednesday",
+			[]string{"Alice (Vet) " + day(5) + " 8-12", "Alice (Vet) " + day(2) + " 8-20", "Bob (Vet) " + day(0) + " 8-20"}},
+		{"Alice and Bob are off Thursday afternoon",
+			[]string{"Alice (Vet) " + day(1) + " 13-17", "Bob (Vet) " + day(1) + " 13-17"}},
+		{"On Tuesday Carol is at the dentist",
+			[]string{"Carol (Vet) " + day(6) + " 8-20"}},
+		{"Eve: Monday morning and afternoon",
+			[]string{"Eve (Jun) " + day(5) + " 8-12", "Eve (Jun) " + day(5) + " 13-17"}},
+		{"Grace is busy", []string{"Grace (Grinder) every day 9-10"}},
+	}
+	for _, tc := range cases {
+		found, err := ai.RuleParser{}.Parse(context.Background(), ai.Message{Text: tc.text, Crew: ai.DemoCrew, Today: wednesday})
+		if err != nil {
+			t.Errorf("%q: %v", tc.text, err)
+			continue
+		}
+		var got []string
+		for _, c := range found {
+			when := "every day"
+			if !c.Date.IsZero() {
+				when = c.Date.Format("Mon 02")
+			}
+			got = append(got, fmt.Sprintf("%s %s %d-%d", c.EmployeeName, when, c.StartHour, c.EndHour))
+			if c.Confidence <= 0 || c.Confidence > 1 {
+				t.Errorf("%q: confidence %v", tc.text, c.Confidence)
+			}
+		}
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("%q:\n got %v\nwant %v", tc.text, got, tc.want)
+		}
+	}
+
+	// A stated window is surer than a guessed one
+	found, _ := ai.RuleParser{}.Parse(context.Background(), ai.Message{Text: "Hank is off Friday morning, Dave is busy", Crew: ai.DemoCrew, Today: wednesday})
+	if len(found) != 2 || found[0].Confidence <= found[1].Confidence {
+		t.Errorf("confidences %+v", found)
+	}
+}
+
+func TestUnavailabilityMessageAPI(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	database.SeedData(db)
+	store := database.NewSQLiteStore(db)
+	srv := httptest.NewServer(api.NewServer(store, jobs.NewPool(store, 1)))
+	defer srv.Close()
+
+	post := func(body string) *http.Response {
+		resp, err := http.Post(srv.URL+"/unavailability/messages", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// 1. Three blocks from one message, dated from "today"
+	resp := post(`{"text": "Alice can't do Monday morning or Friday, and Bob is off Wednesday", "today": "2026-03-04"}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var out []struct {
+		Employee   string  `json:"employee"`
+		Date       string  `json:"date"`
+		StartHour  int     `json:"start_hour"`
+		Confidence float64 `json:"confidence"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 3 || out[0].Date != "2026-03-09" || out[1].Date != "2026-03-06" || out[2].Employee != "Bob (Vet)" || out[0].Confidence == 0 {
+		t.Errorf("constraints %+v", out)
+	}
+
+	// 2. Nobody named: nothing saved
+	if resp := post(`{"text": "someone is off on Friday"}`); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("nobody named: status %d, want 422", resp.StatusCode)
+	}
+
+	// 3. Only Friday's blocks apply on Friday
+	friday, err := store.OnDay(time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)).Unavailability()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(friday) != 1 || friday[0].EmployeeName != "Alice (Vet)" || friday[0].EndHour != 20 {
+		t.Errorf("Friday blocks %+v", friday)
+	}
+	if all, _ := store.Unavailability(); len(all) != 3 {
+		t.Errorf("%d blocks saved, want 3", len(all))
+	}
+}
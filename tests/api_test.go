@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/api"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/jobs"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// call sends a JSON request and decodes the JSON response into out (if not nil)
+func call(t *testing.T, srv *httptest.Server, method, path, body string, wantStatus int, out any) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s: want %d, got %d: %s", method, path, wantStatus, resp.StatusCode, raw)
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			t.Fatalf("%s %s: bad JSON %q: %v", method, path, raw, err)
+		}
+	}
+}
+
+func TestAPIScheduleRun(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+
+	store := database.NewSQLiteStore(db)
+	pool := jobs.NewPool(store, 1)
+	ctx, stop := context.WithCancel(context.Background())
+	defer func() { stop(); pool.Wait() }()
+	if err := pool.Start(ctx); err != nil {
+		t.Fatalf("Failed to start workers: %v", err)
+	}
+
+	srv := httptest.NewServer(api.NewServer(store, pool))
+	defer srv.Close()
+
+	// 1. Crew, demand and availability
+	for _, body := range []string{
+		`{"name": "Alice (Vet)", "hourly_rate": 50, "skill_level": 2}`,
+		`{"name": "Bob (Vet)", "hourly_rate": 55, "skill_level": 2}`,
+		`{"name": "Dave (Jun)", "hourly_rate": 20, "skill_level": 1}`,
+		`{"name": "Eve (Jun)", "hourly_rate": 22, "skill_level": 1}`,
+	} {
+		call(t, srv, "POST", "/employees", body, http.StatusCreated, nil)
+	}
+	call(t, srv, "POST", "/employees", `{"name": "Nobody", "hourly_rate": -1, "skill_level": 1}`, http.StatusBadRequest, nil)
+
+	var curve []string
+	for h := 8; h <= 20; h++ {
+		curve = append(curve, fmt.Sprintf(`{"hour_of_day": %d, "needed": 2}`, h))
+	}
+	call(t, srv, "PUT", "/demand", "["+strings.Join(curve, ",")+"]", http.StatusOK, nil)
+	call(t, srv, "POST", "/unavailability", `{"employee": "Alice (Vet)", "start_hour": 8, "end_hour": 12, "reason": "Dentist"}`, http.StatusCreated, nil)
+	call(t, srv, "POST", "/unavailability", `{"employee": "Ghost", "start_hour": 8, "end_hour": 12}`, http.StatusNotFound, nil)
+
+	var employees []map[string]any
+	call(t, srv, "GET", "/employees", "", http.StatusOK, &employees)
+	if len(employees) != 4 {
+		t.Fatalf("GET /employees: want 4, got %d", len(employees))
+	}
+
+	// 2. Start a run and poll the job
+	call(t, srv, "POST", "/runs", `{"strategy": "magic"}`, http.StatusBadRequest, nil)
+
+	var job struct {
+		ID       int    `json:"id"`
+		Status   string `json:"status"`
+		Error    string `json:"error"`
+		RosterID int    `json:"roster_id"`
+	}
+	call(t, srv, "POST", "/runs", `{"strategy": "smart", "date": "2026-03-02"}`, http.StatusAccepted, &job)
+	for deadline := time.Now().Add(5 * time.Second); job.Status == "queued" || job.Status == "running"; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Job still %s after 5s", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		call(t, srv, "GET", fmt.Sprintf("/jobs/%d", job.ID), "", http.StatusOK, &job)
+	}
+	if job.Status != "done" || job.RosterID == 0 {
+		t.Fatalf("Job should be done with a roster, got %+v", job)
+	}
+
+	// 3. Fetch the roster as JSON and CSV
+	var roster struct {
+		Date        string `json:"date"`
+		Status      string `json:"status"`
+		Assignments []struct {
+			Hour     int    `json:"hour"`
+			Employee string `json:"employee"`
+		} `json:"assignments"`
+	}
+	call(t, srv, "GET", fmt.Sprintf("/rosters/%d", job.RosterID), "", http.StatusOK, &roster)
+	if roster.Date != "2026-03-02" || roster.Status != "draft" || len(roster.Assignments) == 0 {
+		t.Errorf("Roster: got %+v", roster)
+	}
+	for _, a := range roster.Assignments {
+		if a.Employee == "Alice (Vet)" && a.Hour < 12 {
+			t.Errorf("CONSTRAINT VIOLATION: Alice assigned at %02d:00", a.Hour)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + fmt.Sprintf("/rosters/%d?format=csv", job.RosterID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	csv, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/csv" || !strings.HasPrefix(string(csv), "Hour,Employee Name") {
+		t.Errorf("CSV roster: got %s %q", resp.Header.Get("Content-Type"), csv)
+	}
+
+	call(t, srv, "POST", fmt.Sprintf("/rosters/%d/publish", job.RosterID), "", http.StatusOK, nil)
+	call(t, srv, "POST", fmt.Sprintf("/jobs/%d/cancel", job.ID), "", http.StatusConflict, nil)
+	call(t, srv, "GET", "/rosters/999", "", http.StatusNotFound, nil)
+	call(t, srv, "GET", "/jobs/999", "", http.StatusNotFound, nil)
+}
+
+func TestAPIMoveEmployee(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+	if _, err := store.AddLocation(models.Location{Name: "Annex", Region: "North"}); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(api.NewServer(store, nil))
+	defer srv.Close()
+
+	var alice struct {
+		ID         int `json:"id"`
+		LocationID int `json:"location_id"`
+	}
+	call(t, srv, "POST", "/employees", `{"name": "Alice (Vet)", "hourly_rate": 50, "skill_level": 2}`, http.StatusCreated, &alice)
+	body := `{"name": "Alice (Vet)", "hourly_rate": 52, "skill_level": 2}`
+
+	// Without ?location= she stays at the main store; with it she moves
+	call(t, srv, "PUT", fmt.Sprintf("/employees/%d", alice.ID), body, http.StatusOK, &alice)
+	if alice.LocationID != models.DefaultLocation {
+		t.Errorf("PUT without location: moved to %d", alice.LocationID)
+	}
+	call(t, srv, "PUT", fmt.Sprintf("/employees/%d?location=Annex", alice.ID), body, http.StatusOK, &alice)
+	var annex []map[string]any
+	call(t, srv, "GET", "/employees?location=Annex", "", http.StatusOK, &annex)
+	if len(annex) != 1 || alice.LocationID == models.DefaultLocation {
+		t.Errorf("PUT ?location=Annex: %+v, Annex staff %v", alice, annex)
+	}
+	call(t, srv, "PUT", fmt.Sprintf("/employees/%d?location=Nowhere", alice.ID), body, http.StatusNotFound, nil)
+}
@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/api"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// clarifyStore is a fresh database with two Marys and a Tom
+func clarifyStore(t *testing.T) *database.SQLStore {
+	t.Helper()
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store := database.NewSQLiteStore(db)
+	for _, name := range []string{"Mary Ann Lee (Barista)", "Mary Jones", "Tom (Vet)"} {
+		if _, err := store.AddEmployee(models.Employee{Name: name, HourlyRate: 20, SkillLevel: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestClarificationLoop(t *testing.T) {
+	store := clarifyStore(t)
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// 1. Which Mary, and what time is "late"? Nothing is saved until both are answered
+	outcome, err := ai.Record(ctx, store, ai.RuleParser{}, "Mary will be late on Friday", wednesday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !outcome.Held() || outcome.Question != "Which Mary: Mary Ann Lee (Barista) or Mary Jones?" {
+		t.Fatalf("outcome %+v, want it held asking which Mary", outcome)
+	}
+	if blocks, _ := store.Unavailability(); len(blocks) != 0 {
+		t.Errorf("saved %+v before the answer", blocks)
+	}
+
+	// 2. A surname settles the name; the next question is about the time
+	outcome, err = ai.Answer(store, outcome.PendingID, "Jones", wednesday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !outcome.Held() || !strings.HasPrefix(outcome.Question, `What time is "late"?`) {
+		t.Fatalf("outcome %+v, want the time question", outcome)
+	}
+
+	// 3. A reply that answers nothing is asked again
+	id := outcome.PendingID
+	if outcome, err = ai.Answer(store, id, "whenever", wednesday); err != nil || !outcome.Held() || !strings.HasPrefix(outcome.Question, "Sorry") {
+		t.Fatalf("outcome %+v (%v), want the question again", outcome, err)
+	}
+
+	// 4. The last answer saves the completed block and forgets the message
+	if outcome, err = ai.Answer(store, id, "after 3pm", wednesday); err != nil || outcome.Held() {
+		t.Fatalf("outcome %+v (%v), want it saved", outcome, err)
+	}
+	blocks, err := store.Unavailability()
+	if err != nil {
+		t.Fatal(err)
+	}
+	friday := time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)
+	if len(blocks) != 1 || blocks[0].EmployeeName != "Mary Jones" || !blocks[0].Date.Equal(friday) || blocks[0].StartHour != 15 || blocks[0].EndHour != 20 {
+		t.Errorf("saved %+v, want Mary Jones Friday 15-20", blocks)
+	}
+	if pending, _ := store.PendingMessages(); len(pending) != 0 {
+		t.Errorf("still pending: %+v", pending)
+	}
+	if _, err := ai.Answer(store, id, "yes", wednesday); err == nil {
+		t.Error("answered a message that was already saved")
+	}
+}
+
+func TestClarificationAnswers(t *testing.T) {
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		text, reply string
+		want        []models.Unavailability
+	}{
+		{"Tom is busy", "2 to 5pm", []models.Unavailability{{StartHour: 14, EndHour: 17}}},
+		{"Tom is busy later", "until 11", []models.Unavailability{{StartHour: 8, EndHour: 11}}},
+		{"Tom is busy later", "this afternoon", []models.Unavailability{{StartHour: 13, EndHour: 17}}},
+		{"Tom is off soon, all day", "thursday and friday", []models.Unavailability{
+			{StartHour: 8, EndHour: 20, Date: wednesday.AddDate(0, 0, 1)},
+		}},
+		{"Tom is off soon, all day", "from thursday to friday", []models.Unavailability{
+			{StartHour: 8, EndHour: 20, Date: wednesday.AddDate(0, 0, 1)},
+			{StartHour: 8, EndHour: 20, Date: wednesday.AddDate(0, 0, 2)},
+		}},
+	}
+	for _, tc := range cases {
+		store := clarifyStore(t)
+		outcome, err := ai.Record(context.Background(), store, ai.RuleParser{}, tc.text, wednesday)
+		if err != nil || !outcome.Held() {
+			t.Fatalf("%q: %+v (%v), want it held", tc.text, outcome, err)
+		}
+		if outcome, err = ai.Answer(store, outcome.PendingID, tc.reply, wednesday); err != nil || outcome.Held() {
+			t.Errorf("%q, %q: %+v (%v), want it saved", tc.text, tc.reply, outcome, err)
+			continue
+		}
+		blocks, _ := store.Unavailability()
+		if len(blocks) != len(tc.want) {
+			t.Errorf("%q, %q: saved %+v, want %+v", tc.text, tc.reply, blocks, tc.want)
+			continue
+		}
+		for i, b := range blocks {
+			if b.EmployeeName != "Tom (Vet)" || b.StartHour != tc.want[i].StartHour || b.EndHour != tc.want[i].EndHour || !b.Date.Equal(tc.want[i].Date) {
+				t.Errorf("%q, %q: saved %+v, want %+v", tc.text, tc.reply, b, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestClarificationConfirmsUnsureModels(t *testing.T) {
+	store := clarifyStore(t)
+	tom, err := store.EmployeeIDByName("Tom (Vet)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, _ := chatStub(t, http.StatusOK, fmt.Sprintf(`{"constraints": [
+		{"EmployeeID": %d, "Date": "2026-03-06", "StartHour": 8, "EndHour": 12, "Confidence": 0.2}]}`, tom))
+	parser := &ai.OpenAIParser{BaseURL: srv.URL + "/v1"}
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	outcome, err := ai.Record(context.Background(), store, parser, "tom maybe fri?", wednesday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Is this right: Tom (Vet) unavailable Fri 6 Mar 08:00-12:00? (yes/no)"; outcome.Question != want {
+		t.Fatalf("question %q, want %q", outcome.Question, want)
+	}
+	// "No" drops the guess: the message is done and nothing is saved
+	if outcome, err = ai.Answer(store, outcome.PendingID, "No.", wednesday); err != nil || outcome.Held() || len(outcome.Constraints) != 0 {
+		t.Fatalf("outcome %+v (%v), want it dropped", outcome, err)
+	}
+	if blocks, _ := store.Unavailability(); len(blocks) != 0 {
+		t.Errorf("saved %+v", blocks)
+	}
+}
+
+func TestClarificationChecksSwaps(t *testing.T) {
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	swaps := func(store *database.SQLStore) string {
+		var got []string
+		list, _ := store.SwapRequests("")
+		for _, w := range list {
+			got = append(got, w.EmployeeName+" with "+w.WithName)
+		}
+		return strings.Join(got, "; ")
+	}
+
+	// 1. Which Mary asks: the answer names who is asking, the partner is kept
+	store := clarifyStore(t)
+	outcome, err := ai.Record(ctx, store, ai.RuleParser{}, "Mary wants to swap Friday with Tom", wednesday)
+	if err != nil || !outcome.Held() {
+		t.Fatalf("%+v (%v), want it held", outcome, err)
+	}
+	if _, err := ai.Answer(store, outcome.PendingID, "Jones", wednesday); err != nil {
+		t.Fatal(err)
+	}
+	if got := swaps(store); got != "Mary Jones with Tom (Vet)" {
+		t.Errorf("swaps %q", got)
+	}
+
+	// 2. Which Mary to swap with: the answer names the partner, not who is asking
+	store = clarifyStore(t)
+	tom, _ := store.EmployeeIDByName("Tom (Vet)")
+	outcome, err = ai.RecordFrom(ctx, store, ai.RuleParser{}, tom, "I want to swap Friday with Mary", wednesday)
+	if err != nil || !outcome.Held() {
+		t.Fatalf("%+v (%v), want it held", outcome, err)
+	}
+	if _, err := ai.Answer(store, outcome.PendingID, "Mary Ann", wednesday); err != nil {
+		t.Fatal(err)
+	}
+	if got := swaps(store); got != "Tom (Vet) with Mary Ann Lee (Barista)" {
+		t.Errorf("swaps %q", got)
+	}
+
+	// 3. A model's swap is checked once the asker is known: not with themselves
+	store = clarifyStore(t)
+	lee, _ := store.EmployeeIDByName("Mary Ann Lee (Barista)")
+	jones, _ := store.EmployeeIDByName("Mary Jones")
+	srv, _ := chatStub(t, http.StatusOK, fmt.Sprintf(`{"constraints": [{"EmployeeName": "Mary", "Intent": "swap", "SwapWithID": %d,
+		"Date": "2026-03-06", "StartHour": 8, "EndHour": 20, "Confidence": 0.9,
+		"Unclear": [{"Field": "employee", "Text": "Mary", "Options": [%d, %d]}]}]}`, jones, lee, jones))
+	outcome, err = ai.Record(ctx, store, &ai.OpenAIParser{BaseURL: srv.URL + "/v1"}, "Mary wants to swap Friday with Jones", wednesday)
+	if err != nil || !outcome.Held() {
+		t.Fatalf("%+v (%v), want it held", outcome, err)
+	}
+	if _, err := ai.Answer(store, outcome.PendingID, "Jones", wednesday); !errors.Is(err, ai.ErrUnknownEmployee) {
+		t.Errorf("swap with themselves: err %v, want ErrUnknownEmployee", err)
+	}
+	if got := swaps(store); got != "" {
+		t.Errorf("saved swaps %q", got)
+	}
+}
+
+func TestClarificationAPI(t *testing.T) {
+	store := clarifyStore(t)
+	srv := httptest.NewServer(api.NewServer(store, nil))
+	defer srv.Close()
+
+	// 1. The message is held: 202 and the question
+	var held struct {
+		ID          int
+		Question    string
+		Constraints []struct{ Unclear []string }
+	}
+	call(t, srv, "POST", "/unavailability/messages", `{"text": "Tom is busy later", "today": "2026-03-04"}`, http.StatusAccepted, &held)
+	if held.ID == 0 || !strings.Contains(held.Question, `"later"`) || len(held.Constraints) != 1 || strings.Join(held.Constraints[0].Unclear, ",") != "time" {
+		t.Fatalf("held %+v", held)
+	}
+	var pending []struct{ ID int }
+	call(t, srv, "GET", "/unavailability/pending", "", http.StatusOK, &pending)
+	if len(pending) != 1 || pending[0].ID != held.ID {
+		t.Errorf("pending %+v", pending)
+	}
+
+	// 2. The answer saves it
+	var saved []struct {
+		Employee  string `json:"employee"`
+		StartHour int    `json:"start_hour"`
+		EndHour   int    `json:"end_hour"`
+	}
+	call(t, srv, "POST", fmt.Sprintf("/unavailability/pending/%d/answer", held.ID), `{"text": "in the morning"}`, http.StatusCreated, &saved)
+	if len(saved) != 1 || saved[0].Employee != "Tom (Vet)" || saved[0].StartHour != 8 || saved[0].EndHour != 12 {
+		t.Errorf("saved %+v", saved)
+	}
+	call(t, srv, "POST", fmt.Sprintf("/unavailability/pending/%d/answer", held.ID), `{"text": "yes"}`, http.StatusNotFound, nil)
+}
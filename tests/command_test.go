@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/api"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+func TestParseCommand(t *testing.T) {
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	// Each command is written "kind member days hours part start-end text"
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"Who is working Saturday close?", "who - 03-07 0 close 0-0 "},
+		{"who's on tomorrow morning", "who - 03-05 0  8-12 "},
+		{"Is Alice working Friday? Who else?", "who Alice 03-06 0  0-0 "},
+		{"Give Grace four more hours on Friday", "give Grace 03-06 4  0-0 "},
+		{"add 2 hours for Dave tomorrow", "give Dave 03-05 2  0-0 "},
+		{"What if Bob is off Friday?", "what_if Bob 03-06 0  0-0 bob is off friday"},
+		{"suppose Eve can't do Saturday", "what_if Eve 03-07 0  0-0 eve can't do saturday"},
+	}
+	for _, tc := range cases {
+		c, err := ai.ParseCommand(tc.text, ai.DemoCrew, wednesday)
+		if err != nil {
+			t.Errorf("%q: %v", tc.text, err)
+			continue
+		}
+		member := "-"
+		if c.Member.ID != 0 {
+			member = strings.Fields(c.Member.Name)[0]
+		}
+		var days []string
+		for _, d := range c.Days {
+			days = append(days, d.Format("01-02"))
+		}
+		got := fmt.Sprintf("%s %s %s %d %s %d-%d %s", c.Kind, member, strings.Join(days, ","), c.Hours, c.Part, c.StartHour, c.EndHour, c.Text)
+		if got != tc.want {
+			t.Errorf("%q:\n got %q\nwant %q", tc.text, got, tc.want)
+		}
+	}
+
+	// "This week" is Monday to Sunday; give skips the days gone
+	if c, _ := ai.ParseCommand("give Grace four more hours this week", ai.DemoCrew, wednesday); len(c.Days) != 7 || !c.Days[2].Equal(wednesday) {
+		t.Errorf("this week: %v", c.Days)
+	}
+
+	for _, text := range []string{"hello", "give four more hours", "give Grace some hours", "what if"} {
+		if _, err := ai.ParseCommand(text, ai.DemoCrew, wednesday); !errors.Is(err, ai.ErrUnknownCommand) && !errors.Is(err, ai.ErrUnknownEmployee) {
+			t.Errorf("%q: err %v", text, err)
+		}
+	}
+}
+
+// commandStore is the standard crew with a flat demand of two and a published roster for friday
+func commandStore(t *testing.T, friday time.Time) *database.SQLStore {
+	t.Helper()
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	seedFixedDay(t, db, 2)
+	store := database.NewSQLiteStore(db)
+	roster, err := scheduler.SmartTetris(context.Background(), store.OnDay(friday))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roster.Date = friday
+	if _, err := store.SaveRoster(roster, models.RosterPublished, "tester"); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestPreviewAndApplyCommands(t *testing.T) {
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	friday := wednesday.AddDate(0, 0, 2)
+	store := commandStore(t, friday)
+	ctx := context.Background()
+	before, _, err := database.EffectiveRosters(store, friday, friday.AddDate(0, 0, 1))
+	if err != nil || len(before) != 1 {
+		t.Fatalf("rosters %v (%v)", before, err)
+	}
+
+	// 1. Who reads the roster in force; a day without one says so
+	plan, err := ai.Preview(ctx, store, "who is working Friday close?", wednesday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Working) != 1 || len(plan.Working[0].Shifts) == 0 || !plan.Change.Empty() {
+		t.Fatalf("who: %+v", plan)
+	}
+	for _, s := range plan.Working[0].Shifts {
+		if s.EndHour <= 17 {
+			t.Errorf("%s %d-%d does not work the close (17-21)", s.Employee.Name, s.StartHour, s.EndHour)
+		}
+	}
+	if plan, _ := ai.Preview(ctx, store, "who is working Saturday?", wednesday); plan.Working[0].Shifts != nil || len(plan.Notes) != 1 {
+		t.Errorf("who on Saturday: %+v", plan)
+	}
+
+	// 2. What if someone working Friday is off: a trial without them, and nothing saved
+	off := scheduler.Shifts(before[0])[0].Employee
+	plan, err = ai.Preview(ctx, store, fmt.Sprintf("what if %s is off Friday?", strings.Fields(off.Name)[0]), wednesday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Trials) != 1 || len(plan.Change.Unavailability) != 1 || len(plan.Trials[0].Diff.Removed) == 0 {
+		t.Fatalf("what if: %+v", plan)
+	}
+	for _, a := range plan.Trials[0].After.Assignments {
+		if a.Employee.ID == off.ID {
+			t.Errorf("%s still works at %02d:00", off.Name, a.Hour)
+		}
+	}
+	if blocks, _ := store.Unavailability(); len(blocks) != 0 {
+		t.Errorf("the preview saved %+v", blocks)
+	}
+
+	// 3. Applying saves the block and the trial roster as a draft
+	versions, err := ai.Apply(store, plan, "manager")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("apply: %v (%v)", versions, err)
+	}
+	if blocks, _ := store.OnDay(friday).Unavailability(); len(blocks) != 1 || blocks[0].EmployeeID != off.ID {
+		t.Errorf("blocks %+v", blocks)
+	}
+	if _, v, err := store.LoadRoster(versions[0]); err != nil || v.Status != models.RosterDraft || !v.Date.Equal(friday) {
+		t.Errorf("draft %+v (%v)", v, err)
+	}
+
+	// 4. Give hours: the shortest shift on Friday grows, pinned for Friday only
+	var short models.Shift
+	for _, s := range scheduler.Shifts(before[0]) {
+		if s.Employee.ID != off.ID && (short.Employee.ID == 0 || s.EndHour-s.StartHour < short.EndHour-short.StartHour) {
+			short = s
+		}
+	}
+	plan, err = ai.Preview(ctx, store, fmt.Sprintf("give %s two more hours on Friday", strings.Fields(short.Employee.Name)[0]), wednesday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Change.Locks) == 0 || len(plan.Trials) != 1 {
+		t.Fatalf("give: %+v", plan)
+	}
+	for _, l := range plan.Change.Locks {
+		if l.Kind != models.LockPinned || !l.Date.Equal(friday) || l.EmployeeID != short.Employee.ID {
+			t.Errorf("lock %+v", l)
+		}
+	}
+	worked := func(r *models.Roster) int {
+		n := 0
+		for _, a := range r.Assignments {
+			if a.Employee.ID == short.Employee.ID {
+				n++
+			}
+		}
+		return n
+	}
+	if got, was := worked(plan.Trials[0].After), worked(plan.Trials[0].Before); got <= was {
+		t.Errorf("%s works %d hours, was %d", short.Employee.Name, got, was)
+	}
+
+	// A change that cannot be saved whole saves nothing
+	broken := plan
+	broken.Change.Locks = append(append([]models.Lock(nil), plan.Change.Locks...), models.Lock{EmployeeID: short.Employee.ID, Kind: "bogus"})
+	saved, _ := store.ListRosterVersions()
+	if _, err := ai.Apply(store, broken, "manager"); err == nil {
+		t.Error("applied a lock of kind bogus")
+	}
+	if locks, _ := store.Locks(); len(locks) != 0 {
+		t.Errorf("locks %+v", locks)
+	}
+	if versions, _ := store.ListRosterVersions(); len(versions) != len(saved) {
+		t.Errorf("%d roster versions, want %d", len(versions), len(saved))
+	}
+
+	// 5. Only rostered days get hours
+	if plan, err := ai.Preview(ctx, store, "give Hank four more hours on Saturday", wednesday); err != nil || !plan.Change.Empty() || len(plan.Notes) != 1 {
+		t.Errorf("give on Saturday: %+v (%v)", plan, err)
+	}
+}
+
+func TestCommandsAPI(t *testing.T) {
+	friday := time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)
+	store := commandStore(t, friday)
+	srv := httptest.NewServer(api.NewServer(store, nil))
+	defer srv.Close()
+
+	var plan struct {
+		Kind   string
+		Trials []struct {
+			Date    string
+			Removed []struct{ Employee string }
+		}
+		Applied  bool
+		Versions []int
+	}
+	body := `{"text": "what if Alice is off Friday?", "today": "2026-03-04"}`
+	call(t, srv, "POST", "/commands", body, http.StatusOK, &plan)
+	if plan.Kind != "what_if" || len(plan.Trials) != 1 || plan.Trials[0].Date != "2026-03-06" || plan.Applied {
+		t.Fatalf("preview %+v", plan)
+	}
+	call(t, srv, "POST", "/commands", strings.Replace(body, "}", `, "apply": true, "author": "Ana"}`, 1), http.StatusCreated, &plan)
+	if !plan.Applied || len(plan.Versions) != 1 {
+		t.Fatalf("applied %+v", plan)
+	}
+	if _, v, err := store.LoadRoster(plan.Versions[0]); err != nil || v.Author != "Ana" {
+		t.Errorf("draft %+v (%v)", v, err)
+	}
+
+	call(t, srv, "POST", "/commands", `{"text": "who is working Friday?", "apply": true}`, http.StatusBadRequest, nil)
+	call(t, srv, "POST", "/commands", `{"text": "make coffee"}`, http.StatusUnprocessableEntity, nil)
+	call(t, srv, "POST", "/commands", `{"text": "what if Zed is off Friday?"}`, http.StatusUnprocessableEntity, nil)
+	call(t, srv, "POST", "/commands", `{}`, http.StatusBadRequest, nil)
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iannsp/shiftopt/internal/api"
+	"github.com/iannsp/shiftopt/internal/dashboard"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/jobs"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+func TestDashboard(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+
+	// 1. One senior and one junior cannot cover 3 people at lunch
+	store.AddEmployee(models.Employee{Name: "Alice (Vet)", HourlyRate: 50, SkillLevel: 2})
+	store.AddEmployee(models.Employee{Name: "Dave (Jun)", HourlyRate: 20, SkillLevel: 1})
+	var demand []models.Demand
+	for h := 8; h < 16; h++ {
+		needed := 1
+		if h == 12 {
+			needed = 3
+		}
+		demand = append(demand, models.Demand{HourOfDay: h, Needed: needed})
+	}
+	store.ReplaceDemands(demand)
+
+	roster, err := scheduler.SmartTetris(context.Background(), store)
+	if err != nil {
+		t.Fatalf("SmartTetris: %v", err)
+	}
+	id, err := store.SaveRoster(roster, models.RosterDraft, "tester")
+	if err != nil {
+		t.Fatalf("SaveRoster: %v", err)
+	}
+
+	// 2. The page reflects the roster
+	page, err := dashboard.Load(store, 0, 100)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if page.Version.ID != id || !page.OverBudget || page.Unfilled == 0 || len(page.Shortages) == 0 {
+		t.Errorf("Page: got version %d, over budget %v, unfilled %d, %d shortages",
+			page.Version.ID, page.OverBudget, page.Unfilled, len(page.Shortages))
+	}
+
+	var html strings.Builder
+	if err := dashboard.Render(&html, page); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	out := html.String()
+	for _, want := range []string{"Alice (Vet)", "meter over", "12:00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Dashboard is missing %q", want)
+		}
+	}
+	// Self-contained: nothing is fetched from elsewhere
+	for _, banned := range []string{"http://", "https://", "<script", "<link"} {
+		if strings.Contains(out, banned) {
+			t.Errorf("Dashboard references %q", banned)
+		}
+	}
+
+	// 3. The API serves the same page
+	srv := httptest.NewServer(api.NewServer(store, jobs.NewPool(store, 1)))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/dashboard?budget=100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("GET /dashboard: got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	call(t, srv, "GET", "/dashboard?budget=lots", "", http.StatusBadRequest, nil)
+	call(t, srv, "GET", "/rosters/999/dashboard", "", http.StatusNotFound, nil)
+}
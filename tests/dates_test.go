@@ -0,0 +1,198 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // The time zone tests must not depend on the machine's zoneinfo
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/api"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/jobs"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+func TestRuleParserDates(t *testing.T) {
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		text  string
+		want  string // Days, comma separated; "" = undated
+		hours string
+	}{
+		{"Alice is off today", "2026-03-04", "8-20"},
+		{"Alice is off tomorrow", "2026-03-05", "8-20"},
+		{"Alice is off the day after tomorrow", "2026-03-06", "8-20"},
+		{"Alice is off tomorrow morning", "2026-03-05", "8-12"},
+		{"Alice has the dentist this morning", "2026-03-04", "8-12"},
+		{"Alice is off Friday", "2026-03-06", "8-20"},
+		{"Alice is off this Friday", "2026-03-06", "8-20"},
+		{"Alice is off on Wednesday", "2026-03-04", "8-20"},
+		{"Alice is off next Friday", "2026-03-13", "8-20"},
+		{"Alice is off next Monday afternoon", "2026-03-09", "13-17"},
+		{"Alice is off the 24th", "2026-03-24", "8-20"},
+		{"Alice is off on the 3rd", "2026-04-03", "8-20"},
+		{"Alice is off Dec 20", "2026-12-20", "8-20"},
+		{"Alice is off the 20th of December", "2026-12-20", "8-20"},
+		{"Alice is off february 2nd", "2027-02-02", "8-20"},
+		{"Alice is off March 4", "2026-03-04", "8-20"},
+		{"Alice is off Dec 20 2027", "2027-12-20", "8-20"},
+		{"Alice is off 2026-05-01", "2026-05-01", "8-20"},
+		{"Alice is off from Dec 30 to Jan 2", "2026-12-30,2026-12-31,2027-01-01,2027-01-02", "8-20"},
+		{"Alice is off Monday to Wednesday", "2026-03-09,2026-03-10,2026-03-11", "8-20"},
+		{"Alice is off between the 10th and the 12th", "2026-03-10,2026-03-11,2026-03-12", "8-20"},
+		{"Alice is off Dec 20-22 in the morning", "2026-12-20,2026-12-21,2026-12-22", "8-12"},
+		{"Alice is off this weekend", "2026-03-07,2026-03-08", "8-20"},
+		{"Alice is off next week", "2026-03-09,2026-03-10,2026-03-11,2026-03-12,2026-03-13,2026-03-14,2026-03-15", "8-20"},
+		{"Alice is off sat", "2026-03-07", "8-20"},
+		{"Alice can't do sun morning", "2026-03-08", "8-12"},
+		{"Alice is off next sat", "2026-03-14", "8-20"},
+		{"Alice sat down, she is off Friday", "2026-03-06", "8-20"},                    // "sat" the verb
+		{"Alice can do 2 shifts", "", "9-10"},                                          // A bare number is not a day
+		{"Alice and Bob are fine and Friday is busy", "2026-03-06,2026-03-06", "8-20"}, // "and" only ranges after "between"
+	}
+	for _, tc := range cases {
+		found, err := ai.RuleParser{}.Parse(context.Background(), ai.Message{Text: tc.text, Crew: ai.DemoCrew, Today: wednesday})
+		if err != nil {
+			t.Errorf("%q: %v", tc.text, err)
+			continue
+		}
+		var got []string
+		for _, c := range found {
+			if !c.Date.IsZero() {
+				got = append(got, c.Date.Format(database.DateLayout))
+			}
+			if hours := fmt.Sprintf("%d-%d", c.StartHour, c.EndHour); hours != tc.hours {
+				t.Errorf("%q: hours %s, want %s", tc.text, hours, tc.hours)
+			}
+		}
+		if strings.Join(got, ",") != tc.want {
+			t.Errorf("%q:\n got %v\nwant %v", tc.text, strings.Join(got, ","), tc.want)
+		}
+	}
+
+	// A date that cannot be read is asked about, never saved as every day
+	for _, text := range []string{"Alice is off the 32nd in the morning", "Alice can't work Feb 30 afternoon", "Alice is off 2026-02-30 morning"} {
+		found, err := ai.RuleParser{}.Parse(context.Background(), ai.Message{Text: text, Crew: ai.DemoCrew, Today: wednesday})
+		if err != nil || len(found) != 1 {
+			t.Errorf("%q: %+v (%v)", text, found, err)
+			continue
+		}
+		if c := found[0]; !c.Date.IsZero() || c.Confidence >= ai.ConfirmBelow || len(c.Unclear) == 0 || c.Unclear[0].Field != ai.FieldDate {
+			t.Errorf("%q: want an undated window to ask about, got %+v", text, c)
+		}
+	}
+
+	// A range longer than two months is read as two separate days
+	found, _ := ai.RuleParser{}.Parse(context.Background(), ai.Message{Text: "Alice is off from Jan 5 to Dec 1", Crew: ai.DemoCrew, Today: wednesday})
+	if len(found) != 2 {
+		t.Errorf("long range: %d constraints, want 2", len(found))
+	}
+}
+
+func TestDatedUnavailability(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	database.SeedData(db)
+	store := database.NewSQLiteStore(db)
+	friday := time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)
+
+	// 1. A block dated Friday only applies on Friday
+	if err := store.AddUnavailability(models.Unavailability{EmployeeID: 1, StartHour: 0, EndHour: 24, Reason: "Wedding", Date: friday}); err != nil {
+		t.Fatal(err)
+	}
+	alice := models.Employee{ID: 1, Name: "Alice (Vet)", HourlyRate: 50, SkillLevel: 2}
+	unavailable := func(day time.Time) bool {
+		violations, err := scheduler.Validate(store, dayRoster(day, map[models.Employee][2]int{alice: {9, 12}}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range violations {
+			if v.EmployeeID == 1 && strings.Contains(v.Reason, "unavailable") {
+				return true
+			}
+		}
+		return false
+	}
+	if !unavailable(friday) || unavailable(friday.AddDate(0, 0, -1)) {
+		t.Errorf("Friday blocked: %v, Thursday blocked: %v; want true, false", unavailable(friday), unavailable(friday.AddDate(0, 0, -1)))
+	}
+
+	// 2. The scheduler leaves Alice off on Friday
+	roster, err := scheduler.SmartTetris(context.Background(), store.OnDay(friday))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range roster.Assignments {
+		if a.Employee.ID == 1 {
+			t.Fatalf("Alice scheduled at %02d:00 on her day off", a.Hour)
+		}
+	}
+}
+
+func TestStoreTimezones(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+
+	// 1. Two stores 25 hours apart are never on the same calendar day
+	east, err := store.AddLocation(models.Location{Name: "Kiritimati", Timezone: "Pacific/Kiritimati"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	west, err := store.AddLocation(models.Location{Name: "Pago Pago"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetLocationTimezone(west, "Pacific/Pago_Pago"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetLocationTimezone(west, "Mars/Olympus_Mons"); err == nil {
+		t.Error("unknown time zone accepted")
+	}
+	for _, l := range []struct {
+		id   int
+		name string
+	}{{east, "Kiritimati"}, {west, "Pago Pago"}} {
+		if _, err := store.AtLocation(l.id).AddEmployee(models.Employee{Name: "Ana " + l.name, HourlyRate: 20, SkillLevel: 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 2. "Today" in a message is the store's today
+	srv := httptest.NewServer(api.NewServer(store, jobs.NewPool(store, 1)))
+	defer srv.Close()
+	dayAt := func(location string) string {
+		resp, err := http.Post(srv.URL+"/unavailability/messages?location="+strings.ReplaceAll(location, " ", "+"),
+			"application/json", strings.NewReader(`{"text": "Ana is off today"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out []struct {
+			Date string `json:"date"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || resp.StatusCode != http.StatusCreated || len(out) != 1 {
+			t.Fatalf("%s: status %d, %v, %+v", location, resp.StatusCode, err, out)
+		}
+		return out[0].Date
+	}
+	eastDay, westDay := dayAt("Kiritimati"), dayAt("Pago Pago")
+	zone, _ := time.LoadLocation("Pacific/Kiritimati")
+	if eastDay == westDay || eastDay != time.Now().In(zone).Format(database.DateLayout) {
+		t.Errorf("today is %s in Kiritimati and %s in Pago Pago", eastDay, westDay)
+	}
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+// failingWriter rejects every write, to check errors reach the caller
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func export(t *testing.T, format string, opts scheduler.ExportOptions) []byte {
+	t.Helper()
+	exporter, err := scheduler.NewExporter(format, opts)
+	if err != nil {
+		t.Fatalf("NewExporter(%s): %v", format, err)
+	}
+	var b bytes.Buffer
+	if err := exporter.Export(icsRoster(8, 16), &b); err != nil {
+		t.Fatalf("Export(%s): %v", format, err)
+	}
+	return b.Bytes()
+}
+
+func TestExporters(t *testing.T) {
+	// 1. Default CSV keeps the original columns, one row per hour
+	records, err := csv.NewReader(bytes.NewReader(export(t, "csv", scheduler.ExportOptions{}))).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV: %v", err)
+	}
+	if got := strings.Join(records[0], ","); got != "Hour,Employee Name,Role,Hourly Rate,Is Safety Senior?" {
+		t.Errorf("CSV header: got %q", got)
+	}
+	if len(records) != 1+12 || strings.Join(records[1], ",") != "08:00,Alice (Vet),Senior,50.00,YES" {
+		t.Errorf("CSV rows: got %d, first %v", len(records), records[1])
+	}
+
+	// 2. Shift layout with chosen columns
+	shifts := scheduler.ExportOptions{Layout: scheduler.LayoutShift, Columns: []string{scheduler.ColumnCost, scheduler.ColumnSafety}}
+	records, _ = csv.NewReader(bytes.NewReader(export(t, "csv", shifts))).ReadAll()
+	if got := strings.Join(records[0], ","); got != "Start,End,Hours,Employee Name,Cost,Is Safety Senior?" {
+		t.Errorf("Shift header: got %q", got)
+	}
+	if len(records) != 1+3 || strings.Join(records[1], ",") != "08:00,16:00,8,Alice (Vet),400.00,YES" {
+		t.Errorf("Shift rows: got %v", records)
+	}
+
+	// 3. JSON keeps numbers as numbers
+	var doc struct {
+		Date string           `json:"date"`
+		Rows []map[string]any `json:"rows"`
+	}
+	if err := json.Unmarshal(export(t, "json", shifts), &doc); err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+	if doc.Date != "2026-03-02" || len(doc.Rows) != 3 || doc.Rows[0]["start_hour"] != 8.0 || doc.Rows[0]["cost"] != 400.0 || doc.Rows[0]["safety_senior"] != true {
+		t.Errorf("JSON: got %+v", doc)
+	}
+
+	// A cost that is not a number is null, and the file stays valid JSON
+	broken := icsRoster(8, 16)
+	broken.TotalCost = math.NaN()
+	broken.Assignments[0].Employee.HourlyRate = math.Inf(1)
+	exporter, _ := scheduler.NewExporter("json", scheduler.ExportOptions{Columns: []string{scheduler.ColumnRate}})
+	var b bytes.Buffer
+	if err := exporter.Export(broken, &b); err != nil || !json.Valid(b.Bytes()) || !strings.Contains(b.String(), `"total_cost": null`) {
+		t.Errorf("JSON with NaN: %s (%v)", b.String(), err)
+	}
+
+	// 4. XLSX is a zip with the worksheet inside
+	raw := export(t, "xlsx", shifts)
+	book, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("XLSX: %v", err)
+	}
+	var sheet string
+	for _, f := range book.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			r, _ := f.Open()
+			b, _ := io.ReadAll(r)
+			sheet = string(b)
+		}
+	}
+	for _, want := range []string{`<c r="D2" t="inlineStr"><is><t>Alice (Vet)</t></is></c>`, `<c r="E2"><v>400</v></c>`, `<row r="4">`} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("XLSX sheet is missing %q", want)
+		}
+	}
+
+	// 5. Bad options and failed writes are errors
+	for _, opts := range []scheduler.ExportOptions{{Layout: "weekly"}, {Columns: []string{"tips"}}, {Columns: []string{"role", "role"}}} {
+		if _, err := scheduler.NewExporter("csv", opts); err == nil {
+			t.Errorf("NewExporter accepted %+v", opts)
+		}
+	}
+	if _, err := scheduler.NewExporter("pdf", scheduler.ExportOptions{}); err == nil {
+		t.Error("NewExporter accepted an unknown format")
+	}
+	for _, format := range scheduler.ExportFormats() {
+		exporter, _ := scheduler.NewExporter(format, scheduler.ExportOptions{})
+		if err := exporter.Export(icsRoster(8, 16), failingWriter{}); err == nil {
+			t.Errorf("%s export swallowed a write error", format)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+func icsRoster(aliceStart, aliceEnd int) *models.Roster {
+	alice := models.Employee{ID: 1, Name: "Alice (Vet)", HourlyRate: 50, SkillLevel: 2}
+	dave := models.Employee{ID: 4, Name: "Dave, Jr.", HourlyRate: 20, SkillLevel: 1}
+	roster := &models.Roster{Date: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), LocationID: 1}
+	for h := aliceStart; h < aliceEnd; h++ {
+		roster.Assignments = append(roster.Assignments, models.Assignment{Hour: h, Employee: alice, IsSenior: true})
+	}
+	for _, h := range []int{8, 9, 14, 15} { // Split shift
+		roster.Assignments = append(roster.Assignments, models.Assignment{Hour: h, Employee: dave})
+	}
+	return roster
+}
+
+func TestICSExport(t *testing.T) {
+	uids := regexp.MustCompile(`UID:(\S+)`)
+	write := func(roster *models.Roster, employeeID int, stamp time.Time) string {
+		var b strings.Builder
+		if err := scheduler.WriteICS(roster, employeeID, scheduler.ICSOptions{Location: "Main", Stamp: stamp}, &b); err != nil {
+			t.Fatalf("WriteICS: %v", err)
+		}
+		return b.String()
+	}
+
+	// 1. Combined calendar: one event per shift, RFC 5545 line endings and escaping
+	first := write(icsRoster(8, 16), 0, time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC))
+	if n := strings.Count(first, "BEGIN:VEVENT"); n != 3 {
+		t.Errorf("Combined calendar: want 3 events, got %d", n)
+	}
+	if strings.Contains(strings.ReplaceAll(first, "\r\n", ""), "\n") {
+		t.Error("Lines must end with CRLF")
+	}
+	for _, want := range []string{
+		"DTSTART:20260302T080000\r\n",
+		"DTEND:20260302T160000\r\n",
+		`SUMMARY:Shift: Dave\, Jr. (Junior)`,
+		`DESCRIPTION:Role: Senior\nSafety senior on duty`,
+		"LOCATION:Main\r\n",
+	} {
+		if !strings.Contains(first, want) {
+			t.Errorf("Combined calendar is missing %q", want)
+		}
+	}
+
+	// 2. Per employee
+	dave := write(icsRoster(8, 16), 4, time.Time{})
+	if strings.Count(dave, "BEGIN:VEVENT") != 2 || strings.Contains(dave, "Alice") {
+		t.Errorf("Dave's calendar should hold his 2 shifts only:\n%s", dave)
+	}
+
+	// 3. Republishing with new hours keeps the UIDs and bumps the SEQUENCE
+	second := write(icsRoster(10, 18), 0, time.Date(2026, 3, 1, 17, 0, 0, 0, time.UTC))
+	republished := make(map[string]bool)
+	for _, m := range uids.FindAllStringSubmatch(second, -1) {
+		republished[m[1]] = true
+	}
+	for _, m := range uids.FindAllStringSubmatch(first, -1) {
+		if !republished[m[1]] {
+			t.Errorf("UID %s disappeared on republish", m[1])
+		}
+	}
+	if !strings.Contains(second, "DTSTART:20260302T100000") || !strings.Contains(second, "SEQUENCE:1772384400") {
+		t.Errorf("Republished calendar should carry the new times and sequence:\n%s", second)
+	}
+
+	// 4. Files: combined plus one per employee
+	dir := t.TempDir()
+	files, err := scheduler.ExportICS(icsRoster(8, 16), scheduler.ICSOptions{}, dir)
+	if err != nil {
+		t.Fatalf("ExportICS: %v", err)
+	}
+	if len(files) != 3 || !strings.HasSuffix(files[0], "roster.ics") || !strings.HasSuffix(files[1], "1-alice-vet.ics") {
+		t.Errorf("ExportICS: got %v", files)
+	}
+	if _, err := os.Stat(files[2]); err != nil {
+		t.Errorf("ExportICS: %v", err)
+	}
+}
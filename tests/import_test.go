@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/importer"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestImportCrewDemandAndUnavailability(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+
+	// 1. Employees from CSV, then an update by name from JSON
+	res, err := importer.Employees(store, writeFile(t, "crew.csv",
+		"name,hourly_rate,skill_level\nAlice,50,2\nDave,20,1\n"))
+	if err != nil || res.Added != 2 {
+		t.Fatalf("Employees CSV: got %+v (err=%v)", res, err)
+	}
+	// Dave had left: the list hires him back
+	dave, _ := store.EmployeeIDByName("Dave")
+	if err := store.DeactivateEmployee(dave); err != nil {
+		t.Fatal(err)
+	}
+	res, err = importer.Employees(store, writeFile(t, "crew.json",
+		`[{"name": "Dave", "hourly_rate": 24, "skill_level": 1}, {"name": "Eve", "hourly_rate": "22.5", "skill_level": "1"}]`))
+	if err != nil || res.Added != 1 || res.Updated != 1 {
+		t.Fatalf("Employees JSON: got %+v (err=%v)", res, err)
+	}
+	crew, _ := store.Employees()
+	if len(crew) != 3 || crew[1].Name != "Dave" || crew[1].HourlyRate != 24 {
+		t.Errorf("Crew after upsert: got %+v", crew)
+	}
+
+	// 2. Demand replaces the curve
+	if _, err := importer.Demands(store, writeFile(t, "demand.csv", "hour_of_day,needed\n9,1\n10,2\n")); err != nil {
+		t.Fatalf("Demands: %v", err)
+	}
+	if demands, _ := store.Demands(); len(demands) != 2 {
+		t.Errorf("Demands: want 2 hours, got %+v", demands)
+	}
+
+	// 3. Unavailability resolves names
+	if _, err := importer.Unavailability(store, writeFile(t, "off.csv",
+		"employee,start_hour,end_hour,reason\nAlice,8,12,Dentist\n")); err != nil {
+		t.Fatalf("Unavailability: %v", err)
+	}
+	if blocks, _ := store.Unavailability(); len(blocks) != 1 || blocks[0].EmployeeName != "Alice" {
+		t.Errorf("Unavailability: got %+v", blocks)
+	}
+}
+
+func TestSeedClearsTheOldCrew(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+
+	// The old crew's blocks, standby and rosters would land on whoever gets their IDs
+	database.SeedData(db)
+	alice, _ := store.EmployeeIDByName("Alice (Vet)")
+	store.AddUnavailability(models.Unavailability{EmployeeID: alice, StartHour: 8, EndHour: 12})
+	store.AddOnCall(models.OnCall{EmployeeID: alice, StartHour: 12, EndHour: 16})
+	roster := &models.Roster{Assignments: []models.Assignment{{Hour: 8, Employee: models.Employee{ID: alice}}}}
+	if _, err := store.SaveRoster(roster, models.RosterDraft, "tester"); err != nil {
+		t.Fatal(err)
+	}
+
+	database.SeedData(db)
+	blocks, _ := store.Unavailability()
+	onCall, _ := store.OnCall()
+	versions, _ := store.ListRosterVersions()
+	crew, _ := store.AllEmployees()
+	if len(blocks) != 0 || len(onCall) != 0 || len(versions) != 0 || len(crew) != 8 {
+		t.Errorf("after seeding: %d blocks, %d on call, %d rosters, %d employees", len(blocks), len(onCall), len(versions), len(crew))
+	}
+}
+
+func TestImportRejectsInvalidRows(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+
+	// Row 3 is fine, but rows 2 and 4 are not: nothing may be written
+	_, err = importer.Employees(store, writeFile(t, "crew.csv",
+		"name,hourly_rate,skill_level\nAlice,-5,2\nBob,55,2\nBob,abc,3\n"))
+	var invalid *importer.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+	want := map[int][]string{2: {"hourly_rate"}, 4: {"hourly_rate", "skill_level", "name"}}
+	got := make(map[int][]string)
+	for _, r := range invalid.Rows {
+		got[r.Row] = append(got[r.Row], r.Field)
+	}
+	for row, fields := range want {
+		if len(got[row]) != len(fields) {
+			t.Errorf("Row %d: want errors on %v, got %v", row, fields, got[row])
+		}
+	}
+	if all, _ := store.AllEmployees(); len(all) != 0 {
+		t.Errorf("Invalid file must not be partially imported, got %+v", all)
+	}
+
+	// Unknown employees are row errors too
+	_, err = importer.Unavailability(store, writeFile(t, "off.json",
+		`[{"employee": "Nobody", "start_hour": 14, "end_hour": 10}]`))
+	if !errors.As(err, &invalid) || len(invalid.Rows) != 2 {
+		t.Errorf("Expected unknown employee + bad range, got %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/api"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/inbound"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// inboundStore is clarifyStore with a phone number for Tom and an address for Mary Jones
+func inboundStore(t *testing.T) *database.SQLStore {
+	t.Helper()
+	store := clarifyStore(t)
+	for name, address := range map[string]string{"Tom (Vet)": "+15550100001", "Mary Jones": "mary@example.com"} {
+		id, err := store.EmployeeIDByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.AddContact(models.Contact{EmployeeID: id, Address: address}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestReceiveSMS(t *testing.T) {
+	store := inboundStore(t)
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	sms := func(from, text, sid string) (models.InboundMessage, ai.Outcome, error) {
+		return inbound.Receive(ctx, store, ai.RuleParser{}, inbound.Message{Channel: models.ChannelSMS, From: from, Text: text, ExternalID: sid}, wednesday)
+	}
+
+	// 1. The sender is "I": saved for Tom, and the reply says what was noted
+	record, outcome, err := sms("+1 (555) 010-0001", "I can't do Friday afternoon", "SM1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != models.InboundSaved || record.EmployeeID == 0 || len(outcome.Constraints) != 1 {
+		t.Fatalf("record %+v, outcome %+v", record, outcome)
+	}
+	if reply := inbound.Reply(record, outcome); reply != "Thanks, noted: Fri 6 Mar 13:00-17:00 off." {
+		t.Errorf("reply %q", reply)
+	}
+	if blocks, _ := store.Unavailability(); len(blocks) != 1 || blocks[0].EmployeeName != "Tom (Vet)" {
+		t.Errorf("saved %+v", blocks)
+	}
+
+	// 2. The gateway redelivers: read once
+	if _, _, err := sms("+15550100001", "I can't do Friday afternoon", "SM1"); !errors.Is(err, inbound.ErrDuplicate) {
+		t.Errorf("redelivery: err %v, want ErrDuplicate", err)
+	}
+
+	// A redelivery racing the first is turned away by the row the first one kept
+	if _, err := store.AddInbound(models.InboundMessage{Channel: models.ChannelSMS, Sender: "+15550100001", Text: "off Monday",
+		ExternalID: "SM9", Status: models.InboundNew}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sms("+15550100001", "off Monday", "SM9"); !errors.Is(err, inbound.ErrDuplicate) {
+		t.Errorf("racing redelivery: err %v, want ErrDuplicate", err)
+	}
+	if blocks, _ := store.Unavailability(); len(blocks) != 1 {
+		t.Errorf("saved %+v, want only Friday", blocks)
+	}
+
+	// 3. Unclear: held, and the reply is the question
+	record, outcome, err = sms("+15550100001", "I'll be late on Saturday", "SM2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != models.InboundHeld || record.PendingID != outcome.PendingID || inbound.Reply(record, outcome) != outcome.Question {
+		t.Errorf("record %+v, outcome %+v", record, outcome)
+	}
+
+	// 4. Unknown senders are kept as rejected, with no reply
+	record, outcome, err = sms("+15559999999", "off friday", "SM3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != models.InboundRejected || !strings.Contains(record.Error, "unknown sender") || inbound.Reply(record, outcome) != "" {
+		t.Errorf("record %+v", record)
+	}
+
+	inbox, err := store.InboundMessages()
+	if err != nil || len(inbox) != 4 {
+		t.Fatalf("inbound messages %+v (err=%v), want 4", inbox, err)
+	}
+	if inbox[0].EmployeeName != "Tom (Vet)" || inbox[0].Status != models.InboundSaved || inbox[3].EmployeeName != "" {
+		t.Errorf("inbound messages %+v", inbox)
+	}
+}
+
+func TestSMSClarificationRoundTrip(t *testing.T) {
+	store := inboundStore(t)
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	sms := func(text, sid string) (models.InboundMessage, ai.Outcome) {
+		t.Helper()
+		record, outcome, err := inbound.Receive(context.Background(), store, ai.RuleParser{}, inbound.Message{Channel: models.ChannelSMS, From: "+15550100001", Text: text, ExternalID: sid}, wednesday)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return record, outcome
+	}
+
+	// 1. Asked: what time is late?
+	record, outcome := sms("I'll be late on Saturday", "SM1")
+	if record.Status != models.InboundHeld || !strings.HasPrefix(inbound.Reply(record, outcome), `What time is "late"?`) {
+		t.Fatalf("record %+v, reply %q", record, inbound.Reply(record, outcome))
+	}
+
+	// 2. The reply answers it: saved, and the message no longer waits
+	record, outcome = sms("after 3pm", "SM2")
+	if reply := inbound.Reply(record, outcome); record.Status != models.InboundSaved || reply != "Thanks, noted: Sat 7 Mar 15:00-20:00 off." {
+		t.Fatalf("record %+v, reply %q", record, reply)
+	}
+	if blocks, _ := store.Unavailability(); len(blocks) != 1 || blocks[0].EmployeeName != "Tom (Vet)" || blocks[0].StartHour != 15 {
+		t.Errorf("saved %+v", blocks)
+	}
+	if pending, _ := store.PendingMessages(); len(pending) != 0 {
+		t.Errorf("still pending %+v", pending)
+	}
+
+	// 3. The next message is a new one again
+	if record, _ = sms("I can't do Friday morning", "SM3"); record.Status != models.InboundSaved {
+		t.Errorf("record %+v", record)
+	}
+}
+
+// Mail as delivered to new/, named so they sort in delivery order
+var testMail = map[string]string{
+	"1000.plain": "From: Mary Jones <Mary@Example.com>\r\nMessage-ID: <m1@example.com>\r\nSubject: Friday\r\n\r\n" +
+		"I can't work Friday morning.\r\n\r\n--\r\nMary\r\n",
+	"1001.multipart": "From: mary@example.com\r\nMessage-ID: <m2@example.com>\r\nMIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/alternative; boundary=b1\r\n\r\n" +
+		"--b1\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n" +
+		"I can't do Saturday afternoon =E2=80=93 sorry\r\n\r\n" +
+		"On Tue, 3 Mar 2026, Manager wrote:\r\n> Please send me your availability.\r\n" +
+		"--b1\r\nContent-Type: text/html\r\n\r\n<p>I can't do Saturday afternoon</p>\r\n--b1--\r\n",
+	"1002.stranger": "From: someone@elsewhere.com\r\nMessage-ID: <m3@example.com>\r\n\r\nI'm off Monday\r\n",
+	"1003.garbage":  "this is not an email",
+	"1004.again": "From: Mary Jones <mary@example.com>\r\nMessage-ID: <m1@example.com>\r\nSubject: Friday\r\n\r\n" +
+		"I can't work Friday morning.\r\n",
+}
+
+func TestMaildirReceive(t *testing.T) {
+	store := inboundStore(t)
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	for _, sub := range []string{"new", "cur", "tmp"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, mail := range testMail {
+		if err := os.WriteFile(filepath.Join(dir, "new", name), []byte(mail), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 1. Every mail but the redelivery, in order
+	results, err := inbound.Maildir(dir).Receive(context.Background(), store, ai.RuleParser{}, wednesday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, res := range results {
+		got = append(got, res.File+" "+res.Record.Status)
+	}
+	want := "1000.plain saved, 1001.multipart saved, 1002.stranger rejected, 1003.garbage rejected"
+	if strings.Join(got, ", ") != want {
+		t.Fatalf("results:\n got %s\nwant %s", strings.Join(got, ", "), want)
+	}
+
+	// 2. Only the sender's own words are read: no signature, no quoted reply
+	if text := results[1].Record.Text; text != "I can't do Saturday afternoon – sorry" {
+		t.Errorf("multipart text %q", text)
+	}
+	blocks, _ := store.Unavailability()
+	if len(blocks) != 2 || blocks[0].EmployeeName != "Mary Jones" || blocks[0].StartHour != 8 || blocks[1].StartHour != 13 {
+		t.Errorf("saved %+v", blocks)
+	}
+
+	// 3. Everything was moved to cur/, so the next run reads nothing
+	if left, _ := os.ReadDir(filepath.Join(dir, "new")); len(left) != 0 {
+		t.Errorf("left in new/: %v", left)
+	}
+	if read, _ := os.ReadDir(filepath.Join(dir, "cur")); len(read) != len(testMail) || !strings.HasSuffix(read[0].Name(), ":2,S") {
+		t.Errorf("cur/: %v", read)
+	}
+	if results, err := inbound.Maildir(dir).Receive(context.Background(), store, ai.RuleParser{}, wednesday); err != nil || len(results) != 0 {
+		t.Errorf("second run: %+v (err=%v)", results, err)
+	}
+	if inbox, _ := store.InboundMessages(); len(inbox) != 4 {
+		t.Errorf("inbound messages %+v, want 4", inbox)
+	}
+
+	// 4. Mail read late counts "tomorrow" from the day it was sent
+	late := "From: mary@example.com\r\nMessage-ID: <m5@example.com>\r\nDate: Mon, 2 Mar 2026 21:30:00 +0000\r\n\r\n" +
+		"I can't work tomorrow morning.\r\n"
+	if err := os.WriteFile(filepath.Join(dir, "new", "1005.late"), []byte(late), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := inbound.Maildir(dir).Receive(context.Background(), store, ai.RuleParser{}, wednesday); err != nil || len(results) != 1 {
+		t.Fatalf("late mail: %+v (err=%v)", results, err)
+	}
+	tuesday := wednesday.AddDate(0, 0, -1)
+	if blocks, _ := store.Unavailability(); len(blocks) != 3 || !blocks[2].Date.Equal(tuesday) {
+		t.Errorf("late mail saved %+v, want a block on %s", blocks, tuesday.Format("2006-01-02"))
+	}
+}
+
+// postSMS posts a webhook form and returns the status and body
+func postSMS(t *testing.T, srv *httptest.Server, form url.Values, signature string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", srv.URL+"/inbound/sms", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if signature != "" {
+		req.Header.Set("X-Twilio-Signature", signature)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestInboundSMSAPI(t *testing.T) {
+	store := inboundStore(t)
+	server := api.NewServer(store, nil)
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+	webhook := srv.URL + "/inbound/sms"
+	form := url.Values{"From": {"+15550100001"}, "Body": {"I can't work Friday morning"}, "MessageSid": {"SM1"}}
+
+	// 1. With no auth token nothing is taken, unless unsigned requests are allowed
+	if status, _ := postSMS(t, srv, form, ""); status != http.StatusForbidden {
+		t.Errorf("no token: %d, want 403", status)
+	}
+	local := httptest.NewServer(api.NewServer(inboundStore(t), nil).WithUnsignedSMS())
+	defer local.Close()
+	if status, body := postSMS(t, local, form, ""); status != http.StatusOK || !strings.Contains(body, "Thanks, noted: ") {
+		t.Errorf("unsigned allowed: %d %s", status, body)
+	}
+
+	// 2. A signed text is saved, and the reply is TwiML
+	server.WithTwilio("secret", webhook)
+	status, body := postSMS(t, srv, form, inbound.TwilioSignature("secret", webhook, form))
+	if status != http.StatusOK || !strings.Contains(body, "<Response><Message>Thanks, noted: ") {
+		t.Fatalf("%d %s", status, body)
+	}
+
+	// 3. A redelivery gets an empty response; a forged or unsigned one is refused
+	if status, body := postSMS(t, srv, form, inbound.TwilioSignature("secret", webhook, form)); status != http.StatusOK || strings.Contains(body, "<Message>") {
+		t.Errorf("redelivery: %d %s", status, body)
+	}
+	forged := url.Values{"From": {"+15550100001"}, "Body": {"I can't work ever"}, "MessageSid": {"SM2"}}
+	if status, _ := postSMS(t, srv, forged, inbound.TwilioSignature("guess", webhook, forged)); status != http.StatusForbidden {
+		t.Errorf("forged: %d, want 403", status)
+	}
+	if status, _ := postSMS(t, srv, forged, ""); status != http.StatusForbidden {
+		t.Errorf("unsigned: %d, want 403", status)
+	}
+
+	var inbox []struct {
+		Sender   string
+		Employee string
+		Status   string
+	}
+	call(t, srv, "GET", "/inbound", "", http.StatusOK, &inbox)
+	if len(inbox) != 1 || inbox[0].Employee != "Tom (Vet)" || inbox[0].Status != models.InboundSaved {
+		t.Errorf("inbound %+v", inbox)
+	}
+}
+
+func TestContactsAPI(t *testing.T) {
+	srv := httptest.NewServer(api.NewServer(clarifyStore(t), nil))
+	defer srv.Close()
+
+	var added struct {
+		ID      int
+		Address string
+	}
+	call(t, srv, "POST", "/contacts", `{"employee": "Tom (Vet)", "address": "+1 555 010 0001"}`, http.StatusCreated, &added)
+	call(t, srv, "POST", "/contacts", `{"employee": "Mary Jones", "address": "+15550100001"}`, http.StatusConflict, nil)
+	call(t, srv, "POST", "/contacts", `{"employee": "Mary Jones", "address": "555-0100"}`, http.StatusBadRequest, nil)
+	call(t, srv, "POST", "/contacts", `{"employee": "Nobody", "address": "nobody@example.com"}`, http.StatusNotFound, nil)
+
+	var contacts []struct {
+		Employee string
+		Address  string
+	}
+	call(t, srv, "GET", "/contacts", "", http.StatusOK, &contacts)
+	if len(contacts) != 1 || contacts[0].Employee != "Tom (Vet)" || contacts[0].Address != "+15550100001" {
+		t.Errorf("contacts %+v", contacts)
+	}
+	call(t, srv, "DELETE", "/contacts/"+strconv.Itoa(added.ID), "", http.StatusNoContent, nil)
+	call(t, srv, "DELETE", "/contacts/"+strconv.Itoa(added.ID), "", http.StatusNotFound, nil)
+}
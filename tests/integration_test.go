@@ -62,7 +62,7 @@ func TestAIConstraintIntegration(t *testing.T) {
 			// B. The AI Step using Mock
 			constraint := ai.MockParse(tc.inputText)
             // the AI Step using Gemini
-			//constraint := ai.ParseConstraint(tc.inputText)
+			//constraint, _ := ai.ParseConstraint(database.NewSQLiteStore(db), tc.inputText)
 			
 			// Verify AI Parsing
 			if constraint.EmployeeName != tc.expectedEmp {
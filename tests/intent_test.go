@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/api"
+	"github.com/iannsp/shiftopt/internal/models"
+)
+
+// TestRuleParserIntents is what staff messages ask for, read offline. Each constraint is
+// written "Name intent day start-end", a swap "Name swap:Partner day start-end".
+func TestRuleParserIntents(t *testing.T) {
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	dave, _ := ai.DemoCrew.ByID(4)
+
+	cases := []struct {
+		text string
+		want string
+	}{
+		// Unavailability stays the default
+		{"Alice is off tomorrow", "Alice unavailable 03-05 8-20"},
+		{"I can't do Friday", "Dave unavailable 03-06 8-20"},
+		{"Bob can only work mornings", "Bob unavailable * 12-20"},
+		{"Alice isn't available Friday", "Alice unavailable 03-06 8-20"},
+		{"Alice isn’t available Friday", "Alice unavailable 03-06 8-20"},
+		{"Alice and Bob aren't free tomorrow", "Alice unavailable 03-05 8-20; Bob unavailable 03-05 8-20"},
+		{"Bob doesn't want extra hours Saturday", "Bob unavailable 03-07 8-20"},
+		{"I don’t have spare time Friday", "Dave unavailable 03-06 8-20"},
+		{"I won't be available Friday morning", "Dave unavailable 03-06 8-12"},
+		{"I can’t cover Saturday", "Dave unavailable 03-07 8-20"},
+
+		// Offers of hours
+		{"I can pick up extra hours Saturday", "Dave available 03-07 8-20"},
+		{"Bob is free tomorrow afternoon", "Bob available 03-05 13-17"},
+		{"I can work Friday after 3pm", "Dave available 03-06 15-20"},
+		{"Alice can cover Saturday morning", "Alice available 03-07 8-12"},
+
+		// Swaps: the sender asks, whoever else is named is the partner
+		{"Eve and I want to swap Tuesday", "Dave swap:Eve 03-10 8-20"},
+		{"I want to swap Thursday morning with Bob", "Dave swap:Bob 03-05 8-12"},
+		{"Can Alice and Carol switch shifts on Friday?", "Alice swap:Carol 03-06 8-20"},
+
+		// Time off
+		{"Can I take Friday off?", "Dave time_off 03-06 8-20"},
+		{"I'd like next Monday off please", "Dave time_off 03-09 8-20"},
+		{"I'm on vacation from Dec 20 to Dec 22", "Dave time_off 12-20 8-20; Dave time_off 12-21 8-20; Dave time_off 12-22 8-20"},
+		{"Alice needs the 10th off", "Alice time_off 03-10 8-20"},
+	}
+	for _, tc := range cases {
+		found, err := ai.RuleParser{}.Parse(context.Background(), ai.Message{Text: tc.text, Crew: ai.DemoCrew, Sender: dave, Today: wednesday})
+		if err != nil {
+			t.Errorf("%q: %v", tc.text, err)
+			continue
+		}
+		var got []string
+		for _, c := range found {
+			day := "*"
+			if !c.Date.IsZero() {
+				day = c.Date.Format("01-02")
+			}
+			intent := c.Intent
+			if intent == ai.IntentSwap {
+				intent += ":" + strings.Fields(c.SwapWith.Name)[0]
+			}
+			got = append(got, fmt.Sprintf("%s %s %s %d-%d", strings.Fields(c.EmployeeName)[0], intent, day, c.StartHour, c.EndHour))
+		}
+		if strings.Join(got, "; ") != tc.want {
+			t.Errorf("%q:\n got %s\nwant %s", tc.text, strings.Join(got, "; "), tc.want)
+		}
+	}
+
+	// Without a sender "I" is nobody, and a swap needs two people
+	if _, err := (ai.RuleParser{}).Parse(context.Background(), ai.Message{Text: "I can't do Friday", Crew: ai.DemoCrew, Today: wednesday}); err == nil {
+		t.Error("read a message from nobody about nobody")
+	}
+	if _, err := (ai.RuleParser{}).Parse(context.Background(), ai.Message{Text: "Alice, Bob and Eve want to swap Monday", Crew: ai.DemoCrew, Today: wednesday}); err == nil {
+		t.Error("read a swap between three people")
+	}
+}
+
+func TestRecordRoutesIntents(t *testing.T) {
+	store := clarifyStore(t)
+	tom, err := store.EmployeeIDByName("Tom (Vet)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	saturday := wednesday.AddDate(0, 0, 3)
+	ctx := context.Background()
+
+	for _, text := range []string{
+		"I can pick up extra hours Saturday",
+		"Can I take Friday off?",
+		"Mary Jones and I want to swap Saturday afternoon",
+	} {
+		if outcome, err := ai.RecordFrom(ctx, store, ai.RuleParser{}, tom, text, wednesday); err != nil || outcome.Held() {
+			t.Fatalf("%q: %+v (%v), want it saved", text, outcome, err)
+		}
+	}
+
+	if onCall, _ := store.OnDay(saturday).OnCall(); len(onCall) != 1 || onCall[0].EmployeeID != tom || onCall[0].StartHour != 8 || onCall[0].EndHour != 20 {
+		t.Errorf("on call Saturday: %+v, want Tom 08-20", onCall)
+	}
+	if onCall, _ := store.OnDay(saturday.AddDate(0, 0, 1)).OnCall(); len(onCall) != 0 {
+		t.Errorf("on call Sunday: %+v, want none", onCall)
+	}
+	if blocks, _ := store.Unavailability(); len(blocks) != 0 {
+		t.Errorf("saved unavailability %+v before anything was approved", blocks)
+	}
+	timeOff, _ := store.TimeOffRequests(models.RequestPending)
+	if len(timeOff) != 1 || timeOff[0].EmployeeID != tom || timeOff[0].Message != "Can I take Friday off?" {
+		t.Fatalf("time off %+v", timeOff)
+	}
+	swaps, _ := store.SwapRequests(models.RequestPending)
+	if len(swaps) != 1 || swaps[0].EmployeeID != tom || swaps[0].WithName != "Mary Jones" || swaps[0].StartHour != 13 || !swaps[0].Date.Equal(saturday) {
+		t.Errorf("swaps %+v", swaps)
+	}
+
+	// Approved time off is unavailability
+	if err := store.DecideTimeOff(timeOff[0].ID, models.RequestApproved); err != nil {
+		t.Fatal(err)
+	}
+	if blocks, _ := store.Unavailability(); len(blocks) != 1 || blocks[0].EmployeeID != tom || blocks[0].Reason != "Personal" {
+		t.Errorf("after approval: %+v", blocks)
+	}
+
+	// An offer with no day is asked about, not saved for every day
+	outcome, err := ai.RecordFrom(ctx, store, ai.RuleParser{}, tom, "I can cover mornings", wednesday)
+	if err != nil || outcome.Question != "Which day is Tom (Vet) available?" {
+		t.Fatalf("outcome %+v (%v), want the day asked", outcome, err)
+	}
+	if outcome, err = ai.Answer(store, outcome.PendingID, "Monday", wednesday); err != nil || outcome.Held() {
+		t.Fatalf("outcome %+v (%v), want it saved", outcome, err)
+	}
+	if onCall, _ := store.OnDay(wednesday.AddDate(0, 0, 5)).OnCall(); len(onCall) != 1 || onCall[0].EndHour != 12 {
+		t.Errorf("on call Monday: %+v, want the morning", onCall)
+	}
+}
+
+func TestOpenAIParserIntents(t *testing.T) {
+	store := clarifyStore(t)
+	tom, _ := store.EmployeeIDByName("Tom (Vet)")
+	mary, _ := store.EmployeeIDByName("Mary Jones")
+	srv, requests := chatStub(t, http.StatusOK, fmt.Sprintf(`{"constraints": [
+		{"EmployeeID": %d, "Intent": "swap", "SwapWithID": %d, "Date": "2026-03-10", "StartHour": 8, "EndHour": 12, "Confidence": 0.9}]}`, tom, mary))
+	parser := &ai.OpenAIParser{BaseURL: srv.URL + "/v1"}
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	outcome, err := ai.RecordFrom(context.Background(), store, parser, tom, "mary and i swap tue am", wednesday)
+	if err != nil || outcome.Held() || outcome.Constraints[0].SwapWith.Name != "Mary Jones" {
+		t.Fatalf("outcome %+v (%v)", outcome, err)
+	}
+	if prompt := fmt.Sprint((*requests)[0]["messages"]); !strings.Contains(prompt, fmt.Sprintf("from ID %d: Tom (Vet)", tom)) {
+		t.Errorf("the prompt does not name the sender: %s", prompt)
+	}
+	if swaps, _ := store.SwapRequests(""); len(swaps) != 1 || swaps[0].WithID != mary {
+		t.Errorf("swaps %+v", swaps)
+	}
+
+	// A model that invents an intent, or swaps with nobody, saves nothing
+	for _, reply := range []string{
+		fmt.Sprintf(`{"constraints": [{"EmployeeID": %d, "Intent": "promotion", "StartHour": 8, "EndHour": 12, "Confidence": 0.9}]}`, tom),
+		fmt.Sprintf(`{"constraints": [{"EmployeeID": %d, "Intent": "swap", "SwapWithID": 99, "StartHour": 8, "EndHour": 12, "Confidence": 0.9}]}`, tom),
+	} {
+		srv, _ := chatStub(t, http.StatusOK, reply)
+		if _, err := ai.Record(context.Background(), store, &ai.OpenAIParser{BaseURL: srv.URL + "/v1"}, "tom wants things", wednesday); err == nil {
+			t.Errorf("%s: saved", reply)
+		}
+	}
+}
+
+func TestRequestsAPI(t *testing.T) {
+	store := clarifyStore(t)
+	srv := httptest.NewServer(api.NewServer(store, nil))
+	defer srv.Close()
+
+	// 1. A message from Tom: "I" is Tom
+	var saved []struct {
+		Employee string `json:"employee"`
+		Intent   string `json:"intent"`
+		SwapWith string `json:"swap_with"`
+	}
+	call(t, srv, "POST", "/unavailability/messages", `{"text": "Mary Jones and I want to trade Friday", "from": "Tom (Vet)", "today": "2026-03-04"}`, http.StatusCreated, &saved)
+	if len(saved) != 1 || saved[0].Employee != "Tom (Vet)" || saved[0].Intent != "swap" || saved[0].SwapWith != "Mary Jones" {
+		t.Fatalf("saved %+v", saved)
+	}
+	call(t, srv, "POST", "/unavailability/messages", `{"text": "Can I have Monday off?", "from": "Tom (Vet)", "today": "2026-03-04"}`, http.StatusCreated, &saved)
+	call(t, srv, "POST", "/unavailability/messages", `{"text": "I can't", "from": "Nobody"}`, http.StatusNotFound, nil)
+
+	// 2. The manager sees and decides them
+	var swaps []struct {
+		ID     int
+		With   string
+		Status string
+	}
+	call(t, srv, "GET", "/requests/swaps?status=pending", "", http.StatusOK, &swaps)
+	if len(swaps) != 1 || swaps[0].With != "Mary Jones" || swaps[0].Status != "pending" {
+		t.Fatalf("swaps %+v", swaps)
+	}
+	var timeOff []struct {
+		ID   int
+		Date string
+	}
+	call(t, srv, "GET", "/requests/time-off", "", http.StatusOK, &timeOff)
+	if len(timeOff) != 1 || timeOff[0].Date != "2026-03-09" {
+		t.Fatalf("time off %+v", timeOff)
+	}
+	call(t, srv, "POST", fmt.Sprintf("/requests/time-off/%d/approve", timeOff[0].ID), "", http.StatusOK, nil)
+	call(t, srv, "POST", fmt.Sprintf("/requests/time-off/%d/deny", timeOff[0].ID), "", http.StatusConflict, nil)
+	call(t, srv, "POST", fmt.Sprintf("/requests/swaps/%d/maybe", swaps[0].ID), "", http.StatusBadRequest, nil)
+	call(t, srv, "POST", "/requests/swaps/999/deny", "", http.StatusNotFound, nil)
+	call(t, srv, "POST", fmt.Sprintf("/requests/swaps/%d/deny", swaps[0].ID), "", http.StatusOK, nil)
+	call(t, srv, "GET", "/requests/swaps?status=pending", "", http.StatusOK, &swaps)
+	if len(swaps) != 0 {
+		t.Errorf("still pending: %+v", swaps)
+	}
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/jobs"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+func TestSchedulerStopsWhenCancelled(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	seedFixedDay(t, db, 2)
+
+	// Cancel as soon as the first hour reports progress
+	ctx, cancel := context.WithCancel(context.Background())
+	var reports []float64
+	ctx = scheduler.WithProgress(ctx, func(done float64) {
+		reports = append(reports, done)
+		cancel()
+	})
+
+	roster, err := scheduler.SmartTetris(ctx, database.NewSQLiteStore(db))
+	if !errors.Is(err, context.Canceled) || roster != nil {
+		t.Fatalf("Expected context.Canceled and no roster, got %v", err)
+	}
+	if len(reports) != 1 {
+		t.Errorf("Expected the run to stop after one hour, got %d progress reports", len(reports))
+	}
+}
+
+func TestJobQueueLifecycle(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	seedFixedDay(t, db, 2)
+	store := database.NewSQLiteStore(db)
+	pool := jobs.NewPool(store, 2)
+
+	// 1. Queued jobs are persisted, and can be withdrawn before a worker starts
+	if _, err := pool.Submit(models.Job{Strategy: "magic"}); err == nil {
+		t.Error("Unknown strategy should be refused")
+	}
+	withdrawn, err := pool.Submit(models.Job{Strategy: "tetris", Author: "tester"})
+	if err != nil || withdrawn.Status != models.JobQueued {
+		t.Fatalf("Submit: got %+v (err=%v)", withdrawn, err)
+	}
+	if err := pool.Cancel(withdrawn.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	// 2. A job left running by a dead process is picked up again, one a live worker is on is not
+	pool.Submit(models.Job{Strategy: "safe", Author: "tester"})
+	stale, _, _ := store.ClaimJob()
+	abandon(t, db, stale.ID)
+	pool.Submit(models.Job{Strategy: "smart", Author: "tester"})
+	live, _, _ := store.ClaimJob()
+	queued, _ := pool.Submit(models.Job{Strategy: "smart", Author: "tester"})
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer func() { stop(); pool.Wait() }()
+	if err := pool.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	for _, id := range []int{queued.ID, stale.ID} {
+		j := waitForJob(t, store, id)
+		if j.Status != models.JobDone || j.RosterID == 0 || j.Progress != 1 || j.StartedAt.IsZero() {
+			t.Errorf("Job %d: want done with a roster, got %+v", id, j)
+		}
+	}
+	if j, _ := store.Job(withdrawn.ID); j.Status != models.JobCancelled || j.RosterID != 0 {
+		t.Errorf("Withdrawn job should stay cancelled, got %+v", j)
+	}
+	if err := store.CancelJob(queued.ID); !errors.Is(err, database.ErrJobNotQueued) {
+		t.Errorf("Cancelling a finished job: want ErrJobNotQueued, got %v", err)
+	}
+	if j, _ := store.Job(live.ID); j.Status != models.JobRunning || !j.StartedAt.Equal(live.StartedAt) {
+		t.Errorf("Job %d of a live worker should stay running, got %+v", live.ID, j)
+	}
+	if list, _ := store.Jobs(); len(list) != 4 || list[0].ID != queued.ID {
+		t.Errorf("Jobs: want 4, newest first, got %+v", list)
+	}
+}
+
+func TestCancelReachesAnyWorker(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	seedFixedDay(t, db, 2)
+	store := database.NewSQLiteStore(db)
+
+	// 1. Claimed but not yet registered by a worker: the cancel is kept on the job
+	job, _ := jobs.NewPool(store, 1).Submit(models.Job{Strategy: "smart", Author: "tester"})
+	if _, ok, err := store.ClaimJob(); !ok || err != nil {
+		t.Fatalf("ClaimJob: ok=%v (err=%v)", ok, err)
+	}
+	if err := jobs.NewPool(store, 1).Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel a running job: %v", err)
+	}
+	if j, _ := store.Job(job.ID); j.Status != models.JobRunning || !j.CancelRequested {
+		t.Fatalf("Want running with a cancel requested, got %+v", j)
+	}
+	abandon(t, db, job.ID)
+
+	// 2. Its worker died: requeued at start, the worker that picks it up stops it before scheduling
+	pool := jobs.NewPool(store, 1)
+	ctx, stop := context.WithCancel(context.Background())
+	defer func() { stop(); pool.Wait() }()
+	if err := pool.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if j := waitForJob(t, store, job.ID); j.Status != models.JobCancelled || j.RosterID != 0 {
+		t.Errorf("Want cancelled without a roster, got %+v", j)
+	}
+}
+
+// abandon makes a running job look like its worker died: untouched for longer than jobs.StaleAfter
+func abandon(t *testing.T, db *sql.DB, id int) {
+	t.Helper()
+	before := time.Now().Add(-2 * jobs.StaleAfter).UTC().Format(time.RFC3339)
+	if _, err := db.Exec("UPDATE jobs SET heartbeat_at = ? WHERE id = ?", before, id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitForJob(t *testing.T, store database.Store, id int) models.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		j, err := store.Job(id)
+		if err != nil {
+			t.Fatalf("Job %d: %v", id, err)
+		}
+		if j.Status != models.JobQueued && j.Status != models.JobRunning {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job %d still %s after 5s", id, j.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/jobs"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+func TestRegionFloatersCoverNearbyGaps(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+
+	// 1. Two stores, 30 minutes apart. Uptown has one employee for a two-person day.
+	downtownID, err := store.AddLocation(models.Location{Name: "Downtown", Region: "North"})
+	if err != nil {
+		t.Fatalf("AddLocation: %v", err)
+	}
+	uptownID, _ := store.AddLocation(models.Location{Name: "Uptown", Region: "North"})
+	if err := store.SetTravelTime(models.TravelTime{FromID: uptownID, ToID: downtownID, Minutes: 30}); err != nil {
+		t.Fatalf("SetTravelTime: %v", err)
+	}
+
+	downtown, uptown := store.AtLocation(downtownID), store.AtLocation(uptownID)
+	for _, e := range []models.Employee{
+		{Name: "Alice (Vet)", HourlyRate: 50, SkillLevel: 2, Floats: true},
+		{Name: "Dave (Jun)", HourlyRate: 20, SkillLevel: 1},
+		{Name: "Grace (Grinder)", HourlyRate: 30, SkillLevel: 1},
+	} {
+		if _, err := downtown.AddEmployee(e); err != nil {
+			t.Fatalf("AddEmployee: %v", err)
+		}
+	}
+	uptown.AddEmployee(models.Employee{Name: "Bob (Vet)", HourlyRate: 55, SkillLevel: 2})
+
+	var morning, day []models.Demand
+	for h := 8; h < 16; h++ {
+		if h < 12 {
+			morning = append(morning, models.Demand{HourOfDay: h, Needed: 1})
+		}
+		day = append(day, models.Demand{HourOfDay: h, Needed: 2})
+	}
+	downtown.ReplaceDemands(morning)
+	uptown.ReplaceDemands(day)
+
+	// 2. Scoped stores only see their own crew and demand
+	if crew, _ := uptown.Employees(); len(crew) != 1 || crew[0].Name != "Bob (Vet)" {
+		t.Errorf("Uptown crew: want only Bob, got %+v", crew)
+	}
+	if curve, _ := downtown.Demands(); len(curve) != 4 {
+		t.Errorf("Downtown demand: want 4 hours, got %d", len(curve))
+	}
+
+	// 3. Joint run: Alice works Downtown 08-12, needs an hour on the road, then floats
+	rosters, err := scheduler.Region(context.Background(), store, "North")
+	if err != nil {
+		t.Fatalf("Region: %v", err)
+	}
+	if len(rosters) != 2 || rosters[1].LocationID != uptownID {
+		t.Fatalf("Expected a roster per store, got %d", len(rosters))
+	}
+
+	var floated []int
+	for _, a := range rosters[1].Assignments {
+		switch a.Employee.Name {
+		case "Alice (Vet)":
+			floated = append(floated, a.Hour)
+		case "Dave (Jun)", "Grace (Grinder)":
+			t.Errorf("%s does not float but was sent Uptown at %02d:00", a.Employee.Name, a.Hour)
+		}
+	}
+	if len(floated) != 3 || floated[0] != 13 {
+		t.Errorf("Alice should float Uptown 13:00-16:00 after travelling, got hours %v", floated)
+	}
+	if rosters[1].Unfilled != 5 {
+		t.Errorf("Uptown unfilled: want 5 (08-13 before Alice can arrive), got %d", rosters[1].Unfilled)
+	}
+	if want := 8*55.0 + 3*50.0 + 25.0; rosters[1].TotalCost != want {
+		t.Errorf("Uptown cost: want $%.2f (travel included), got $%.2f", want, rosters[1].TotalCost)
+	}
+
+	// 4. Versions are kept per store
+	for _, r := range rosters {
+		if _, err := store.SaveRoster(r, models.RosterDraft, "tester"); err != nil {
+			t.Fatalf("SaveRoster: %v", err)
+		}
+	}
+	if versions, _ := uptown.ListRosterVersions(); len(versions) != 1 || versions[0].LocationID != uptownID {
+		t.Errorf("Uptown versions: got %+v", versions)
+	}
+}
+
+func TestUnscopedRunSchedulesTheDefaultStore(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+
+	// Main needs one person 08-12, Uptown three 08-16: merged, Main's curve would be overwritten
+	uptownID, _ := store.AddLocation(models.Location{Name: "Uptown"})
+	mainStore, uptown := store.AtLocation(models.DefaultLocation), store.AtLocation(uptownID)
+	mainStore.AddEmployee(models.Employee{Name: "Alice (Vet)", HourlyRate: 50, SkillLevel: 2})
+	uptown.AddEmployee(models.Employee{Name: "Bob (Vet)", HourlyRate: 55, SkillLevel: 2})
+	var morning, day []models.Demand
+	for h := 8; h < 16; h++ {
+		if h < 12 {
+			morning = append(morning, models.Demand{HourOfDay: h, Needed: 1})
+		}
+		day = append(day, models.Demand{HourOfDay: h, Needed: 3})
+	}
+	mainStore.ReplaceDemands(morning)
+	uptown.ReplaceDemands(day)
+
+	// A job with no location, as POST /runs without ?location= queues it
+	pool := jobs.NewPool(store, 1)
+	job, err := pool.Submit(models.Job{Strategy: "smart", Author: "tester", Date: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	defer func() { stop(); pool.Wait() }()
+	if err := pool.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	j := waitForJob(t, store, job.ID)
+	if j.Status != models.JobDone {
+		t.Fatalf("job %+v", j)
+	}
+
+	roster, v, err := store.LoadRoster(j.RosterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.LocationID != models.DefaultLocation || roster.Unfilled != 0 || len(roster.Assignments) != 4 {
+		t.Errorf("roster %+v (version %+v), want Main's four hours", roster, v)
+	}
+	for _, a := range roster.Assignments {
+		if a.Employee.Name != "Alice (Vet)" || a.Hour >= 12 {
+			t.Errorf("%s at %02d:00 is not Main's crew or hours", a.Employee.Name, a.Hour)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+func TestLocksHonouredByEveryStrategy(t *testing.T) {
+	strategies := map[string]func(*sql.DB) (*models.Roster, error){
+		"Hourly": scheduler.RunSafeSchedule,
+		"Tetris": scheduler.RunTetrisSchedule,
+		"Smart":  scheduler.RunSmartTetris,
+	}
+
+	for name, run := range strategies {
+		t.Run(name, func(t *testing.T) {
+			db, err := database.InitDB(":memory:")
+			if err != nil {
+				t.Fatalf("Failed to init DB: %v", err)
+			}
+			defer db.Close()
+			seedFixedDay(t, db, 2)
+
+			// Hank is expensive (never picked on cost alone), Dave is the cheapest
+			hank := employeeByName(t, db, "Hank (Grinder)")
+			dave := employeeByName(t, db, "Dave (Jun)")
+			if _, err := database.AddLock(db, hank.ID, 8, 12, models.LockPinned); err != nil {
+				t.Fatalf("Failed to pin: %v", err)
+			}
+			if _, err := database.AddLock(db, dave.ID, 8, 21, models.LockForbidden); err != nil {
+				t.Fatalf("Failed to forbid: %v", err)
+			}
+
+			roster, err := run(db)
+			if err != nil {
+				t.Fatalf("Scheduler failed: %v", err)
+			}
+
+			hankHours := make(map[int]bool)
+			for _, a := range roster.Assignments {
+				if a.Employee.ID == dave.ID {
+					t.Errorf("LOCK VIOLATION: forbidden %s assigned at %02d:00", dave.Name, a.Hour)
+				}
+				if a.Employee.ID == hank.ID {
+					hankHours[a.Hour] = true
+				}
+			}
+			for h := 8; h < 12; h++ {
+				if !hankHours[h] {
+					t.Errorf("LOCK VIOLATION: pinned %s missing at %02d:00", hank.Name, h)
+				}
+			}
+		})
+	}
+}
+
+func TestLockConflictsReportedBeforeSolving(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	seedFixedDay(t, db, 2)
+
+	alice := employeeByName(t, db, "Alice (Vet)")
+	bob := employeeByName(t, db, "Bob (Vet)")
+
+	// 1. Pinned during a dentist appointment
+	database.AddUnavailability(db, alice.ID, 8, 12, "Dentist")
+	database.AddLock(db, alice.ID, 10, 14, models.LockPinned)
+	// 2. Pinned for longer than the daily limit
+	database.AddLock(db, bob.ID, 8, 18, models.LockPinned)
+	// 3. Pinned after closing time
+	database.AddLock(db, alice.ID, 21, 22, models.LockPinned)
+
+	conflicts, err := scheduler.CheckLocks(db)
+	if err != nil {
+		t.Fatalf("CheckLocks failed: %v", err)
+	}
+	if len(conflicts) != 3 {
+		t.Errorf("Want 3 conflicts, Got %d: %+v", len(conflicts), conflicts)
+	}
+
+	_, err = scheduler.RunSmartTetris(db)
+	var lockErr *scheduler.LockConflictError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("Want LockConflictError before solving, Got %v", err)
+	}
+	if len(lockErr.Conflicts) != len(conflicts) {
+		t.Errorf("Scheduler reported %d conflicts, CheckLocks %d", len(lockErr.Conflicts), len(conflicts))
+	}
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/iannsp/shiftopt/internal/database"
+)
+
+// legacySchema is what InitDB created before migrations existed
+const legacySchema = `
+	CREATE TABLE IF NOT EXISTS employees (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT,
+		hourly_rate REAL,
+		skill_level INTEGER
+	);
+	CREATE TABLE IF NOT EXISTS demands (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		hour_of_day INTEGER,
+		needed INTEGER
+	);
+	CREATE TABLE IF NOT EXISTS unavailability (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		employee_id INTEGER,
+		start_hour INTEGER,
+		end_hour INTEGER,
+		reason TEXT,
+		FOREIGN KEY(employee_id) REFERENCES employees(id)
+	);
+	`
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count); err != nil {
+		t.Fatalf("Failed to inspect schema: %v", err)
+	}
+	return count > 0
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shiftopt.db")
+
+	// 1. A shiftopt.db written by the old InitDB, with real data in it
+	legacy, err := database.OpenDB(path)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %v", err)
+	}
+	if _, err := legacy.Exec(legacySchema); err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+	legacy.Exec("INSERT INTO employees (name, hourly_rate, skill_level) VALUES ('Alice (Vet)', 50, 2)")
+	legacy.Exec("INSERT INTO unavailability (employee_id, start_hour, end_hour, reason) VALUES (1, 8, 12, 'Dentist')")
+	legacy.Close()
+
+	// 2. Opening it with the current binary migrates it in place
+	db, err := database.InitDB(path)
+	if err != nil {
+		t.Fatalf("Failed to migrate legacy DB: %v", err)
+	}
+	defer db.Close()
+
+	version, err := database.SchemaVersion(db)
+	if err != nil {
+		t.Fatalf("Failed to read schema version: %v", err)
+	}
+	if version != database.LatestVersion() {
+		t.Errorf("Want schema v%d, Got v%d", database.LatestVersion(), version)
+	}
+	for _, table := range []string{"on_call", "roster_versions", "roster_assignments", "assignment_locks"} {
+		if !tableExists(t, db, table) {
+			t.Errorf("Table %s missing after migration", table)
+		}
+	}
+
+	// 3. Data survived
+	id, err := database.GetEmployeeIDByName(db, "Alice (Vet)")
+	if err != nil || id != 1 {
+		t.Errorf("Employee lost during migration: id=%d err=%v", id, err)
+	}
+	var reason string
+	db.QueryRow("SELECT reason FROM unavailability WHERE employee_id = 1").Scan(&reason)
+	if reason != "Dentist" {
+		t.Errorf("Unavailability lost during migration, Got reason %q", reason)
+	}
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+
+	// 1. Step down one migration at a time, all the way to an empty schema
+	for v := database.LatestVersion() - 1; v >= 0; v-- {
+		if err := database.Migrate(db, v); err != nil {
+			t.Fatalf("Failed to migrate down to v%d: %v", v, err)
+		}
+		got, _ := database.SchemaVersion(db)
+		if got != v {
+			t.Fatalf("Want schema v%d, Got v%d", v, got)
+		}
+	}
+	for _, table := range []string{"employees", "demands", "unavailability", "roster_versions"} {
+		if tableExists(t, db, table) {
+			t.Errorf("Table %s still exists at v0", table)
+		}
+	}
+
+	// 2. And back up in one go
+	if err := database.Migrate(db, database.LatestVersion()); err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	seedFixedDay(t, db, 2)
+
+	// 3. Unknown versions are refused
+	if err := database.Migrate(db, database.LatestVersion()+1); err == nil {
+		t.Errorf("Want error migrating to an unknown version")
+	}
+}
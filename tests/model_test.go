@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+	"github.com/iannsp/shiftopt/internal/api"
+)
+
+func TestModelAnswersValidated(t *testing.T) {
+	msg := ai.Message{Text: "Eve has the school run this afternoon", Crew: ai.DemoCrew}
+
+	// 1. Each answer breaking the schema is asked again, then given up on
+	for _, reply := range []string{
+		`{"EmployeeID": 5, "StartHour": 25, "EndHour": 26}`,
+		`{"EmployeeID": 5, "StartHour": 13, "EndHour": 0}`,
+		`{"EmployeeID": 5, "StartHour": 17, "EndHour": 13}`,
+		`{"EmployeeID": 5, "StartHour": 13, "EndHour": 17, "Confidence": 1.5}`,
+		`{"EmployeeID": 5, "StartHour": 13, "EndHour": 17, "Intent": "promotion"}`,
+		`{"EmployeeID": 5, "StartHour": 13, "EndHour": 17, "Intent": "swap"}`,
+		`{"EmployeeID": 5, "StartHour": 13, "EndHour": 17, "Date": "tomorrow"}`,
+		`{"EmployeeID": 5, "StartHour": "1pm"}`,
+		`{"EmployeeID": 99, "StartHour": 13, "EndHour": 17}`,
+		`{"EmployeeName": "Nobody", "StartHour": 13, "EndHour": 17}`,
+		`{"EmployeeID": 5, "StartHour": 13, "EndHour": 17, "Intent": "swap", "SwapWithID": 99}`,
+		`{"EmployeeID": 5, "StartHour": 13, "EndHour": 17, "Intent": "swap", "SwapWithID": 5}`,
+		`Sure! Eve is busy from 1 to 5.`,
+	} {
+		srv, requests := chatStub(t, http.StatusOK, reply)
+		before := ai.ReadStats()
+		_, err := (&ai.OpenAIParser{BaseURL: srv.URL + "/v1"}).Parse(context.Background(), msg)
+		if !errors.Is(err, ai.ErrInvalidAnswer) {
+			t.Errorf("%s: err %v, want ErrInvalidAnswer", reply, err)
+		}
+		if len(*requests) != 3 || !strings.Contains(fmt.Sprint((*requests)[1]["messages"]), "Your previous answer was rejected") {
+			t.Errorf("%s: asked %d times, want 3 with the reason", reply, len(*requests))
+		}
+		if after := ai.ReadStats(); after.ModelCalls-before.ModelCalls != 3 || after.InvalidAnswers-before.InvalidAnswers != 3 {
+			t.Errorf("%s: stats %+v, then %+v", reply, before, after)
+		}
+	}
+
+	// 2. A valid answer on the second attempt is used
+	srv, requests := chatStub(t, http.StatusOK, `{"EmployeeID": 5, "StartHour": 13, "EndHour": 17.5}`, `{"EmployeeID": 5, "StartHour": 13, "EndHour": 17}`)
+	found, err := (&ai.OpenAIParser{BaseURL: srv.URL + "/v1"}).Parse(context.Background(), msg)
+	if err != nil || len(found) != 1 || found[0].EndHour != 17 || len(*requests) != 2 {
+		t.Errorf("found %+v (%v) after %d attempts", found, err, len(*requests))
+	}
+
+	// 3. Hours are not checked while the time is still to be asked
+	srv, _ = chatStub(t, http.StatusOK, `{"EmployeeID": 5, "StartHour": 0, "EndHour": 0, "Unclear": [{"Field": "time", "Text": "later"}]}`)
+	if found, err := (&ai.OpenAIParser{BaseURL: srv.URL + "/v1"}).Parse(context.Background(), msg); err != nil || len(found[0].Unclear) != 1 {
+		t.Errorf("found %+v (%v), want the time asked", found, err)
+	}
+}
+
+func TestParseCache(t *testing.T) {
+	store := clarifyStore(t)
+	tom, _ := store.EmployeeIDByName("Tom (Vet)")
+	srv, requests := chatStub(t, http.StatusOK, fmt.Sprintf(`{"EmployeeID": %d, "StartHour": 8, "EndHour": 12, "Confidence": 0.9}`, tom))
+	parser := ai.Cached(&ai.OpenAIParser{BaseURL: srv.URL + "/v1"}, "stub", store)
+	crew, err := ai.LoadCrew(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	msg := ai.Message{Text: "Tom is off this morning", Crew: crew, Today: wednesday}
+
+	// 1. The same message is only sent once
+	before := ai.ReadStats()
+	first, err := parser.Parse(context.Background(), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := parser.Parse(context.Background(), msg)
+	if err != nil || len(*requests) != 1 || fmt.Sprint(again) != fmt.Sprint(first) {
+		t.Fatalf("again %+v (%v) after %d requests, want %+v from the cache", again, err, len(*requests), first)
+	}
+	if hits := ai.ReadStats().CacheHits - before.CacheHits; hits != 1 {
+		t.Errorf("%d cache hits, want 1", hits)
+	}
+
+	// 2. Another day or another model is another prompt
+	msg.Today = wednesday.AddDate(0, 0, 1)
+	parser.Parse(context.Background(), msg)
+	ai.Cached(&ai.OpenAIParser{BaseURL: srv.URL + "/v1"}, "other", store).Parse(context.Background(), msg)
+	if len(*requests) != 3 {
+		t.Errorf("%d requests, want 3", len(*requests))
+	}
+
+	// 3. Failures are not cached
+	bad, badRequests := chatStub(t, http.StatusOK, "not JSON")
+	failing := ai.Cached(&ai.OpenAIParser{BaseURL: bad.URL + "/v1"}, "bad", store)
+	for range 2 {
+		if _, err := failing.Parse(context.Background(), msg); err == nil {
+			t.Error("a bad answer was accepted")
+		}
+	}
+	if len(*badRequests) != 6 {
+		t.Errorf("%d requests, want 2 messages of 3 attempts", len(*badRequests))
+	}
+}
+
+func TestFallbackStats(t *testing.T) {
+	srv, _ := chatStub(t, http.StatusServiceUnavailable, "")
+	parser := ai.WithFallback(&ai.OpenAIParser{BaseURL: srv.URL + "/v1"}, ai.RuleParser{})
+	before := ai.ReadStats()
+	if found, err := parser.Parse(context.Background(), ai.Message{Text: "Alice is off tomorrow", Crew: ai.DemoCrew}); err != nil || len(found) != 1 {
+		t.Fatalf("found %+v (%v), want the rules' answer", found, err)
+	}
+	after := ai.ReadStats()
+	if after.Parses-before.Parses != 1 || after.Fallbacks-before.Fallbacks != 1 || after.FallbackRate() <= 0 {
+		t.Errorf("stats %+v, then %+v", before, after)
+	}
+
+	api := httptest.NewServer(api.NewServer(clarifyStore(t), nil))
+	defer api.Close()
+	var stats struct {
+		Parses       int64   `json:"parses"`
+		Fallbacks    int64   `json:"fallbacks"`
+		FallbackRate float64 `json:"fallback_rate"`
+	}
+	call(t, api, "GET", "/ai/stats", "", http.StatusOK, &stats)
+	if stats.Parses < 1 || stats.Fallbacks < 1 || stats.FallbackRate != float64(stats.Fallbacks)/float64(stats.Parses) {
+		t.Errorf("GET /ai/stats: %+v", stats)
+	}
+}
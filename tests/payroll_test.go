@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/payroll"
+)
+
+func TestPayroll(t *testing.T) {
+	alice := models.Employee{ID: 1, Name: "Alice (Vet)", HourlyRate: 50, SkillLevel: 2}
+	bob := models.Employee{ID: 2, Name: "Bob (Vet)", HourlyRate: 40, SkillLevel: 2}
+	dave := models.Employee{ID: 4, Name: "Dave (Jun)", HourlyRate: 20, SkillLevel: 1}
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	// 1. Alice: 10h on Monday (2h daily overtime). Bob: 8h Monday to Saturday (8h weekly overtime).
+	// Dave: Saturday 20-24 (2h night premium) and Sunday 9-13 (Sunday premium).
+	var rosters []*models.Roster
+	for day := 0; day < 7; day++ {
+		shifts := map[models.Employee][2]int{}
+		if day == 0 {
+			shifts[alice] = [2]int{8, 18}
+		}
+		if day < 6 {
+			shifts[bob] = [2]int{8, 16}
+		}
+		if day == 5 {
+			shifts[dave] = [2]int{20, 24}
+		}
+		if day == 6 {
+			shifts[dave] = [2]int{9, 13}
+		}
+		rosters = append(rosters, dayRoster(monday.AddDate(0, 0, day), shifts))
+	}
+	rosters = append(rosters, dayRoster(monday.AddDate(0, 0, 7), map[models.Employee][2]int{alice: {8, 9}})) // Next period
+
+	report := payroll.Compute(rosters, monday, monday.AddDate(0, 0, 7), payroll.DefaultRules)
+	want := []payroll.Line{
+		{EmployeeID: 1, Name: "Alice (Vet)", Rate: 50, RegularHours: 8, OvertimeHours: 2, Gross: 8*50 + 2*75},
+		{EmployeeID: 2, Name: "Bob (Vet)", Rate: 40, RegularHours: 40, OvertimeHours: 8, Gross: 40*40 + 8*60},
+		{EmployeeID: 4, Name: "Dave (Jun)", Rate: 20, RegularHours: 2, PremiumHours: 6, Gross: 2*20 + 6*25},
+	}
+	if len(report.Lines) != len(want) {
+		t.Fatalf("Lines: got %+v", report.Lines)
+	}
+	for i, l := range report.Lines {
+		if l != want[i] {
+			t.Errorf("Line %d: want %+v, got %+v", i, want[i], l)
+		}
+	}
+	if report.Hours != 66 {
+		t.Errorf("Total hours: want 66, got %d", report.Hours)
+	}
+
+	// 2. CSV layouts
+	var b bytes.Buffer
+	if err := payroll.WriteCSV(&b, report, payroll.CSVOptions{}); err != nil {
+		t.Fatalf("WriteCSV generic: %v", err)
+	}
+	records, _ := csv.NewReader(&b).ReadAll()
+	if got := strings.Join(records[1], ","); got != "1,Alice (Vet),2026-03-02,2026-03-08,8,2,0,10,50.00,550.00" {
+		t.Errorf("Generic row: got %q", got)
+	}
+
+	b.Reset()
+	if err := payroll.WriteCSV(&b, report, payroll.CSVOptions{Layout: payroll.LayoutADP}); err == nil {
+		t.Error("The adp layout should insist on a company code")
+	}
+	b.Reset()
+	if err := payroll.WriteCSV(&b, report, payroll.CSVOptions{Layout: payroll.LayoutADP, CompanyCode: "ABC"}); err != nil {
+		t.Fatalf("WriteCSV adp: %v", err)
+	}
+	records, _ = csv.NewReader(&b).ReadAll()
+	if got := strings.Join(records[0], ","); got != "Co Code,Batch ID,File #,Reg Hours,O/T Hours,Hours 3 Code,Hours 3 Amount" {
+		t.Errorf("ADP header: got %q", got)
+	}
+	if got := strings.Join(records[3], ","); got != "ABC,20260302,000004,2,0,PRM,6" {
+		t.Errorf("ADP row: got %q", got)
+	}
+
+	// 3. From the database, each day counts once even with several versions
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+	store.SaveRoster(rosters[0], models.RosterPublished, "tester")
+	store.SaveRoster(rosters[0], models.RosterDraft, "tester")
+	loaded, err := payroll.Load(store, monday, monday.AddDate(0, 0, 14), payroll.DefaultRules)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Hours != 18 {
+		t.Errorf("Load: want 18 hours from one Monday roster, got %d", loaded.Hours)
+	}
+}
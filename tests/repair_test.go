@@ -0,0 +1,219 @@
+package tests
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+// seedFixedDay loads the standard crew with a flat, predictable demand curve
+// (SeedData adds random noise, which makes exact assertions impossible)
+func seedFixedDay(t *testing.T, db *sql.DB, needed int) {
+	t.Helper()
+	database.SeedData(db)
+	if _, err := db.Exec("DELETE FROM demands"); err != nil {
+		t.Fatalf("Failed to clear demand: %v", err)
+	}
+	for h := 8; h <= 20; h++ {
+		if _, err := db.Exec("INSERT INTO demands (hour_of_day, needed) VALUES (?, ?)", h, needed); err != nil {
+			t.Fatalf("Failed to seed demand: %v", err)
+		}
+	}
+}
+
+func employeeByName(t *testing.T, db *sql.DB, name string) models.Employee {
+	t.Helper()
+	var e models.Employee
+	err := db.QueryRow("SELECT id, name, hourly_rate, skill_level FROM employees WHERE name = ?", name).
+		Scan(&e.ID, &e.Name, &e.HourlyRate, &e.SkillLevel)
+	if err != nil {
+		t.Fatalf("Employee %q not seeded: %v", name, err)
+	}
+	return e
+}
+
+func TestRepairKeepsUnaffectedShifts(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	seedFixedDay(t, db, 3)
+
+	published, err := scheduler.RunSmartTetris(db)
+	if err != nil {
+		t.Fatalf("Scheduler crashed: %v", err)
+	}
+
+	// Call out whoever opens the store
+	absent := published.Assignments[0].Employee
+	callOut := models.Unavailability{EmployeeName: absent.Name, StartHour: 8, EndHour: 20, Reason: "Sick"}
+
+	repaired, diff, err := scheduler.RepairRoster(db, published, callOut)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+
+	// 1. The absent employee is gone
+	for _, a := range repaired.Assignments {
+		if a.Employee.ID == absent.ID {
+			t.Errorf("CONSTRAINT VIOLATION: %s still assigned at %02d:00", absent.Name, a.Hour)
+		}
+	}
+
+	// 2. Everyone else kept every published hour
+	kept := make(map[[2]int]bool)
+	for _, a := range repaired.Assignments {
+		kept[[2]int{a.Employee.ID, a.Hour}] = true
+	}
+	for _, a := range published.Assignments {
+		if a.Employee.ID != absent.ID && !kept[[2]int{a.Employee.ID, a.Hour}] {
+			t.Errorf("DISRUPTION: %s lost %02d:00 during repair", a.Employee.Name, a.Hour)
+		}
+	}
+
+	// 3. Coverage is preserved hour by hour
+	if len(repaired.Assignments)+repaired.Unfilled != len(published.Assignments)+published.Unfilled {
+		t.Errorf("Coverage drifted. Published %d+%d, Repaired %d+%d",
+			len(published.Assignments), published.Unfilled, len(repaired.Assignments), repaired.Unfilled)
+	}
+
+	// 4. The diff reports the absentee's removed shift(s)
+	removed := false
+	for _, s := range diff.Removed {
+		if s.Employee.ID == absent.ID {
+			removed = true
+		}
+	}
+	if !removed {
+		t.Errorf("Diff does not report %s's removed shift: %+v", absent.Name, diff)
+	}
+}
+
+func TestRepairPrefersOnCallStaff(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	seedFixedDay(t, db, 2)
+
+	alice := employeeByName(t, db, "Alice (Vet)")
+	dave := employeeByName(t, db, "Dave (Jun)")
+	hank := employeeByName(t, db, "Hank (Grinder)")
+
+	published := &models.Roster{}
+	for h := 8; h < 12; h++ {
+		for _, e := range []models.Employee{alice, dave} {
+			published.Assignments = append(published.Assignments, models.Assignment{Hour: h, Employee: e, IsSenior: e.SkillLevel >= 2})
+			published.TotalCost += e.HourlyRate
+		}
+	}
+
+	// Hank is the most expensive junior, but he is on standby
+	if err := database.AddOnCall(db, hank.ID, 8, 12); err != nil {
+		t.Fatalf("Failed to save on-call window: %v", err)
+	}
+
+	_, diff, err := scheduler.RepairRoster(db, published, models.Unavailability{EmployeeID: dave.ID, EmployeeName: dave.Name, StartHour: 8, EndHour: 12})
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+
+	if got := scheduler.ChangeCount(diff); got != 2 {
+		t.Errorf("Want 2 changed shifts (Dave out, Hank in), Got %d: %+v", got, diff)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Employee.ID != hank.ID {
+		t.Fatalf("Want Hank (on call) to cover the gap, Got %+v", diff.Added)
+	}
+	if diff.Added[0].StartHour != 8 || diff.Added[0].EndHour != 12 {
+		t.Errorf("Want cover 08:00-12:00, Got %02d:00-%02d:00", diff.Added[0].StartHour, diff.Added[0].EndHour)
+	}
+	if want := 4 * (hank.HourlyRate - dave.HourlyRate); diff.CostDelta != want {
+		t.Errorf("Want cost delta %.2f, Got %.2f", want, diff.CostDelta)
+	}
+}
+
+func TestRepairUsesTheRostersDay(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	seedFixedDay(t, db, 2)
+	store := database.NewSQLiteStore(db)
+
+	alice := employeeByName(t, db, "Alice (Vet)")
+	dave := employeeByName(t, db, "Dave (Jun)")
+	hank := employeeByName(t, db, "Hank (Grinder)")
+	friday := time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)
+
+	published := &models.Roster{Date: friday, LocationID: models.DefaultLocation}
+	for h := 8; h < 12; h++ {
+		for _, e := range []models.Employee{alice, dave} {
+			published.Assignments = append(published.Assignments, models.Assignment{Hour: h, Employee: e, IsSenior: e.SkillLevel >= 2})
+			published.TotalCost += e.HourlyRate
+		}
+	}
+
+	// Hank is on standby on Friday and away on Saturday: only Friday's windows count
+	if err := store.AddOnCall(models.OnCall{EmployeeID: hank.ID, StartHour: 8, EndHour: 12, Date: friday}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddUnavailability(models.Unavailability{EmployeeID: hank.ID, StartHour: 8, EndHour: 20, Date: friday.AddDate(0, 0, 1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	repaired, diff, err := scheduler.Repair(store, published, models.Unavailability{EmployeeID: dave.ID, EmployeeName: dave.Name, StartHour: 8, EndHour: 12})
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Employee.ID != hank.ID {
+		t.Errorf("Want Hank (on call Friday) to cover the gap, Got %+v", diff.Added)
+	}
+	if !repaired.Date.Equal(friday) || repaired.LocationID != models.DefaultLocation {
+		t.Errorf("Repaired roster lost its day or store: %v, location %d", repaired.Date, repaired.LocationID)
+	}
+}
+
+func TestRepairUsesTheRostersStore(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	seedFixedDay(t, db, 2)
+	store := database.NewSQLiteStore(db)
+
+	// The cheapest hand works at another store
+	uptown, err := store.AddLocation(models.Location{Name: "Uptown", Region: "North"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zed, err := store.AddEmployee(models.Employee{Name: "Zed (Jun)", HourlyRate: 1, SkillLevel: 1, LocationID: uptown})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := employeeByName(t, db, "Alice (Vet)")
+	dave := employeeByName(t, db, "Dave (Jun)")
+	published := &models.Roster{LocationID: models.DefaultLocation}
+	for h := 8; h < 12; h++ {
+		for _, e := range []models.Employee{alice, dave} {
+			published.Assignments = append(published.Assignments, models.Assignment{Hour: h, Employee: e, IsSenior: e.SkillLevel >= 2})
+			published.TotalCost += e.HourlyRate
+		}
+	}
+
+	_, diff, err := scheduler.Repair(store, published, models.Unavailability{EmployeeID: dave.ID, EmployeeName: dave.Name, StartHour: 8, EndHour: 12})
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Employee.ID == zed {
+		t.Errorf("Want one of the main store's crew to cover the gap, Got %+v", diff.Added)
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/api"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/importer"
+	"github.com/iannsp/shiftopt/internal/jobs"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+func TestRosterImportRoundTrip(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+
+	// 1. A published roster to edit
+	store.AddEmployee(models.Employee{Name: "Alice (Vet)", HourlyRate: 50, SkillLevel: 2})
+	store.AddEmployee(models.Employee{Name: "Bob (Vet)", HourlyRate: 55, SkillLevel: 2})
+	eve, _ := store.AddEmployee(models.Employee{Name: "Eve (Jun)", HourlyRate: 22, SkillLevel: 1})
+	var demand []models.Demand
+	for h := 8; h < 16; h++ {
+		demand = append(demand, models.Demand{HourOfDay: h, Needed: 1})
+	}
+	store.ReplaceDemands(demand)
+	store.AddUnavailability(models.Unavailability{EmployeeID: eve, StartHour: 8, EndHour: 12, Reason: "School run"})
+
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	original, err := scheduler.SmartTetris(context.Background(), store)
+	if err != nil {
+		t.Fatalf("SmartTetris: %v", err)
+	}
+	original.Date = day
+	baseID, _ := store.SaveRoster(original, models.RosterPublished, "tester")
+
+	// 2. Export, add Eve during her school run, import
+	var csv bytes.Buffer
+	scheduler.WriteCSV(original, &csv)
+	csv.WriteString("08:00,Eve (Jun),Junior,22.00,No\n09:00,Eve (Jun),Junior,22.00,No\n")
+	path := filepath.Join(t.TempDir(), "roster.csv")
+	os.WriteFile(path, csv.Bytes(), 0o644)
+
+	roster, err := importer.Roster(store, path)
+	if err != nil {
+		t.Fatalf("Roster: %v", err)
+	}
+	edit, err := importer.SaveEdit(store, roster, day, "manager")
+	if err != nil {
+		t.Fatalf("SaveEdit: %v", err)
+	}
+	if edit.Base.ID != baseID || scheduler.ChangeCount(edit.Diff) != 1 || edit.Diff.CostDelta != 44 {
+		t.Errorf("Edit against base: got base %d, %d changes, %+.2f", edit.Base.ID, scheduler.ChangeCount(edit.Diff), edit.Diff.CostDelta)
+	}
+	if len(edit.Introduced) != 2 || !strings.Contains(edit.Introduced[0].Reason, "Eve (Jun) works at 08:00 but is unavailable") {
+		t.Errorf("Introduced violations: got %+v", edit.Introduced)
+	}
+	if _, v, err := store.LoadRoster(edit.VersionID); err != nil || v.Status != models.RosterDraft || v.TotalCost != original.TotalCost+44 {
+		t.Errorf("Saved edit: got %+v (err=%v)", v, err)
+	}
+
+	// 3. The shift layout in JSON reads back to the same assignments
+	exporter, _ := scheduler.NewExporter("json", scheduler.ExportOptions{Layout: scheduler.LayoutShift})
+	var doc bytes.Buffer
+	exporter.Export(original, &doc)
+	back, err := importer.ReadRoster(store, &doc, "json")
+	if err != nil {
+		t.Fatalf("ReadRoster json: %v", err)
+	}
+	if scheduler.ChangeCount(scheduler.DiffRosters(original, back)) != 0 || back.TotalCost != original.TotalCost {
+		t.Errorf("JSON round trip changed the roster: %+v", scheduler.DiffRosters(original, back))
+	}
+
+	// 4. Bad rows are all reported and nothing is read
+	bad := "Hour,Employee Name\n08:00,Zed\n25:00,Alice (Vet)\n09:00,Alice (Vet)\n09:00,Alice (Vet)\n"
+	_, err = importer.ReadRoster(store, strings.NewReader(bad), "csv")
+	var invalid *importer.ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Rows) != 3 {
+		t.Fatalf("Bad roster: want 3 row errors, got %v", err)
+	}
+
+	// 5. Over HTTP
+	srv := httptest.NewServer(api.NewServer(store, jobs.NewPool(store, 1)))
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"/rosters/import?date=2026-03-02", "text/csv", bytes.NewReader(csv.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("POST /rosters/import: got %d", resp.StatusCode)
+	}
+	call(t, srv, "POST", "/rosters/import?format=csv", bad, http.StatusBadRequest, nil)
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/ai"
+)
+
+// TestRuleParserCorpus is the phrasings the offline parser must read without a model.
+// Each constraint is written "Name day start-end", the day "*" when it is every day.
+func TestRuleParserCorpus(t *testing.T) {
+	wednesday := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		text string
+		want string // Constraints separated by "; "
+	}{
+		// Names, aliases and several people
+		{"Alice is off tomorrow", "Alice 03-05 8-20"},
+		{"alice (vet) is off tomorrow", "Alice 03-05 8-20"},
+		{"ALICE IS OFF TOMORROW", "Alice 03-05 8-20"},
+		{"Alice's kid is sick today", "Alice 03-04 8-20"},
+		{"Alice and Bob are off Friday", "Alice 03-06 8-20; Bob 03-06 8-20"},
+		{"Alice, Bob and Carol are off Friday", "Alice 03-06 8-20; Bob 03-06 8-20; Carol 03-06 8-20"},
+		{"Alice is off Monday and Bob is off Tuesday", "Alice 03-09 8-20; Bob 03-10 8-20"},
+		{"Friday morning: Eve has the dentist", "Eve 03-06 8-12"},
+
+		// Day parts
+		{"Alice is off Friday morning", "Alice 03-06 8-12"},
+		{"Alice is off Friday afternoon", "Alice 03-06 13-17"},
+		{"Alice is off Friday evening", "Alice 03-06 17-20"},
+		{"Alice is off all day Friday", "Alice 03-06 8-20"},
+		{"Alice can't do mornings", "Alice * 8-12"},
+		{"Alice can't do Monday morning or Friday", "Alice 03-09 8-12; Alice 03-06 8-20"},
+		{"Alice is off Monday morning and afternoon", "Alice 03-09 8-12; Alice 03-09 13-17"},
+
+		// Clock times
+		{"Alice has the dentist at 3pm", "Alice * 15-16"},
+		{"Alice has the dentist at 3 pm on Friday", "Alice 03-06 15-16"},
+		{"Alice has the dentist Friday at 10:30", "Alice 03-06 10-11"},
+		{"Alice can't work after 3pm", "Alice * 15-20"},
+		{"Alice can't work after 3", "Alice * 15-20"},
+		{"Alice can't work after 18:00 on Friday", "Alice 03-06 18-20"},
+		{"Alice can't come in before 11am", "Alice * 8-11"},
+		{"Alice can't come in before 10 tomorrow", "Alice 03-05 8-10"},
+		{"Alice is busy until noon on Friday", "Alice 03-06 8-12"},
+		{"Alice is busy from 2pm to 5pm tomorrow", "Alice 03-05 14-17"},
+		{"Alice is busy 2-5pm tomorrow", "Alice 03-05 14-17"},
+		{"Alice is busy tomorrow 2pm-5pm", "Alice 03-05 14-17"},
+		{"Alice is busy tomorrow from 9 to 1", "Alice 03-05 9-13"},
+		{"Alice is busy tomorrow between 2 and 4", "Alice 03-05 14-16"},
+		{"Alice is busy tomorrow 11 to 2pm", "Alice 03-05 11-14"},
+		{"Alice is busy tomorrow 9:30 to 11:15", "Alice 03-05 9-12"},
+		{"Alice is busy tomorrow 10am until 1pm", "Alice 03-05 10-13"},
+		{"Alice is busy Monday 9-5", "Alice 03-09 9-17"},
+		{"Alice is busy Friday 8am to 12pm", "Alice 03-06 8-12"},
+		{"Alice is busy Friday 7am to 9am", "Alice 03-06 7-9"},
+		{"Alice is off Monday at 9 and Tuesday at 2", "Alice 03-09 9-10; Alice 03-10 14-15"},
+		{"Alice is busy 1pm-3pm Monday and Bob after 4pm Tuesday", "Alice 03-09 13-15; Bob 03-10 16-20"},
+
+		// Dates and ranges
+		{"Alice is off on the 10th after 2pm", "Alice 03-10 14-20"},
+		{"Alice is off Dec 20 2026 morning", "Alice 12-20 8-12"},
+		{"Alice is off from Monday to Wednesday in the afternoon", "Alice 03-09 13-17; Alice 03-10 13-17; Alice 03-11 13-17"},
+		{"Alice is off this weekend after 1pm", "Alice 03-07 13-20; Alice 03-08 13-20"},
+
+		// "Only": when they can work, turned around
+		{"Bob can only work mornings", "Bob * 12-20"},
+		{"Bob can work only mornings", "Bob * 12-20"},
+		{"Bob can only work afternoons", "Bob * 8-13; Bob * 17-20"},
+		{"Bob is only available after 3pm", "Bob * 8-15"},
+		{"Bob is available only until 2pm tomorrow", "Bob 03-05 14-20"},
+		{"Bob can only come in from 10 to 4 on Monday", "Bob 03-09 8-10; Bob 03-09 16-20"},
+		{"Bob can only work Friday", "Bob 03-04 8-20; Bob 03-05 8-20; Bob 03-07 8-20; Bob 03-08 8-20"},
+		{"Bob can only work Monday morning next week", "Bob 03-09 12-20; Bob 03-10 8-20; Bob 03-11 8-20; Bob 03-12 8-20; Bob 03-13 8-20; Bob 03-14 8-20; Bob 03-15 8-20"},
+		{"Bob can only work mornings and Carol is off Friday", "Bob * 12-20; Carol 03-06 8-20"},
+		{"Bob is off only on Friday", "Bob 03-06 8-20"}, // Only the day off: not turned around
+		{"Bob can't work, only Friday", "Bob 03-06 8-20"},
+		{"Bob can only work all day", ""}, // Free all day: nothing to save
+		{"Bob can only work", ""},
+		{"Bob can only work before 8am", "Bob * 8-20"}, // No hours before opening: off all day
+		{"Bob can only work before 7am on Friday", "Bob 03-06 8-20"},
+		{"Alice is off before 8am", ""}, // Before opening: nothing to block
+		{"Alice can't come in before 7am tomorrow", ""},
+
+		// Numbers that are not times
+		{"Alice can do 2 shifts on Friday", "Alice 03-06 8-20"},
+		{"Alice is off Dec 20-22", "Alice 12-20 8-20; Alice 12-21 8-20; Alice 12-22 8-20"},
+		{"Alice: off 2026-03-10", "Alice 03-10 8-20"},
+	}
+	for _, tc := range cases {
+		found, err := ai.RuleParser{}.Parse(context.Background(), ai.Message{Text: tc.text, Crew: ai.DemoCrew, Today: wednesday})
+		if tc.want == "" && errors.Is(err, ai.ErrNothingFound) {
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.text, err)
+			continue
+		}
+		var got []string
+		for _, c := range found {
+			day := "*"
+			if !c.Date.IsZero() {
+				day = c.Date.Format("01-02")
+			}
+			got = append(got, fmt.Sprintf("%s %s %d-%d", strings.Fields(c.EmployeeName)[0], day, c.StartHour, c.EndHour))
+		}
+		if strings.Join(got, "; ") != tc.want {
+			t.Errorf("%q:\n got %s\nwant %s", tc.text, strings.Join(got, "; "), tc.want)
+		}
+	}
+}
+
+func TestRuleParserClockAnswers(t *testing.T) {
+	for reply, want := range map[string]string{
+		"2 to 5pm":          "14-17",
+		"from 9 to 1":       "9-13",
+		"after 3pm":         "15-20",
+		"after 15:30":       "15-20",
+		"before 11am":       "8-11",
+		"before 8am":        "none",
+		"until noon":        "8-12",
+		"at 7pm":            "19-20",
+		"9pm to midnight":   "21-24",
+		"around 10 o'clock": "10-11",
+		"all day":           "8-20",
+		"whenever":          "none",
+		"13pm":              "none",
+		"5 to 3":            "none",
+	} {
+		got := "none"
+		if start, end, ok := ai.HoursIn(reply); ok {
+			got = fmt.Sprintf("%d-%d", start, end)
+		}
+		if got != want {
+			t.Errorf("%q: %s, want %s", reply, got, want)
+		}
+	}
+}
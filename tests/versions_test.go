@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/scheduler"
+)
+
+func TestRosterVersionsRoundTrip(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	seedFixedDay(t, db, 3)
+
+	draft, err := scheduler.RunSmartTetris(db)
+	if err != nil {
+		t.Fatalf("Scheduler crashed: %v", err)
+	}
+	draft.Date = time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	// 1. Save and reload the draft
+	v1, err := database.SaveRoster(db, draft, models.RosterDraft, "manager")
+	if err != nil {
+		t.Fatalf("Failed to save roster: %v", err)
+	}
+	loaded, meta, err := database.LoadRoster(db, v1)
+	if err != nil {
+		t.Fatalf("Failed to load roster: %v", err)
+	}
+	if meta.Status != models.RosterDraft || meta.Author != "manager" || !meta.Date.Equal(draft.Date) {
+		t.Errorf("Metadata mismatch: %+v", meta)
+	}
+	if len(loaded.Assignments) != len(draft.Assignments) || loaded.TotalCost != draft.TotalCost {
+		t.Errorf("Roster mismatch. Want %d assignments/$%.2f, Got %d/$%.2f",
+			len(draft.Assignments), draft.TotalCost, len(loaded.Assignments), loaded.TotalCost)
+	}
+	if diff := scheduler.DiffRosters(draft, loaded); scheduler.ChangeCount(diff) != 0 {
+		t.Errorf("Reloaded roster differs from saved one: %+v", diff)
+	}
+
+	// 2. A repaired roster saved as a second version diffs against the first
+	absent := draft.Assignments[0].Employee
+	repaired, _, err := scheduler.RepairRoster(db, loaded, models.Unavailability{EmployeeName: absent.Name, StartHour: 8, EndHour: 20})
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	v2, err := database.SaveRoster(db, repaired, models.RosterDraft, "manager")
+	if err != nil {
+		t.Fatalf("Failed to save repaired roster: %v", err)
+	}
+	second, _, err := database.LoadRoster(db, v2)
+	if err != nil {
+		t.Fatalf("Failed to load roster: %v", err)
+	}
+	diff := scheduler.DiffRosters(loaded, second)
+	if len(diff.Removed)+len(diff.Changed) == 0 {
+		t.Errorf("Want %s's shift reported as removed or changed, Got %+v", absent.Name, diff)
+	}
+	if diff.CostDelta != second.TotalCost-loaded.TotalCost {
+		t.Errorf("Cost delta mismatch: %.2f", diff.CostDelta)
+	}
+
+	// 3. Publishing
+	if err := database.PublishRoster(db, v2); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	versions, err := database.ListRosterVersions(db)
+	if err != nil {
+		t.Fatalf("Failed to list versions: %v", err)
+	}
+	if len(versions) != 2 || versions[0].ID != v2 || versions[0].Status != models.RosterPublished {
+		t.Errorf("Want v%d published first, Got %+v", v2, versions)
+	}
+	if err := database.PublishRoster(db, 999); err == nil {
+		t.Errorf("Want error publishing unknown version")
+	}
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iannsp/shiftopt/internal/api"
+	"github.com/iannsp/shiftopt/internal/database"
+	"github.com/iannsp/shiftopt/internal/models"
+	"github.com/iannsp/shiftopt/internal/weekly"
+)
+
+// dayRoster puts each employee on [start, end) of one day
+func dayRoster(day time.Time, shifts map[models.Employee][2]int) *models.Roster {
+	roster := &models.Roster{Date: day}
+	for e, s := range shifts {
+		for h := s[0]; h < s[1]; h++ {
+			roster.Assignments = append(roster.Assignments, models.Assignment{Hour: h, Employee: e})
+			roster.TotalCost += e.HourlyRate
+		}
+	}
+	return roster
+}
+
+func TestWeeklyGrid(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	defer db.Close()
+	store := database.NewSQLiteStore(db)
+
+	alice := models.Employee{ID: 1, Name: "Alice (Vet)", HourlyRate: 50, SkillLevel: 2}
+	dave := models.Employee{ID: 4, Name: "Dave (Jun)", HourlyRate: 20, SkillLevel: 1}
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	wednesday := monday.AddDate(0, 0, 2)
+
+	// 1. Monday: a published version, then a newer draft that must not win.
+	// Wednesday: only drafts, the newest wins. The Monday after is another week.
+	save := func(r *models.Roster, status string) {
+		if _, err := store.SaveRoster(r, status, "tester"); err != nil {
+			t.Fatalf("SaveRoster: %v", err)
+		}
+	}
+	save(dayRoster(monday, map[models.Employee][2]int{alice: {9, 13}, dave: {12, 20}}), models.RosterPublished)
+	save(dayRoster(monday, map[models.Employee][2]int{alice: {8, 20}}), models.RosterDraft)
+	save(dayRoster(wednesday, map[models.Employee][2]int{dave: {8, 10}}), models.RosterDraft)
+	save(dayRoster(wednesday, map[models.Employee][2]int{dave: {9, 13}}), models.RosterDraft)
+	save(dayRoster(monday.AddDate(0, 0, 7), map[models.Employee][2]int{alice: {8, 9}}), models.RosterPublished)
+
+	week, err := weekly.Load(store, wednesday.AddDate(0, 0, 1)) // Any day of the week
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !week.Start.Equal(monday) || fmt.Sprint(week.Versions) != "[1 4]" {
+		t.Fatalf("Load: week of %s from versions %v", week.Start, week.Versions)
+	}
+	if len(week.Rows) != 2 || week.Rows[0].Name != "Alice (Vet)" {
+		t.Fatalf("Rows: got %+v", week.Rows)
+	}
+	a, d := week.Rows[0], week.Rows[1]
+	if a.Cells[0] != "09–13" || a.Cells[2] != "" || a.TotalHours != 4 {
+		t.Errorf("Alice: got %+v", a)
+	}
+	if d.Cells[0] != "12–20" || d.Cells[2] != "09–13" || d.TotalHours != 12 {
+		t.Errorf("Dave: got %+v", d)
+	}
+	if week.DayHours != [7]int{12, 0, 4, 0, 0, 0, 0} || week.TotalHours != 16 {
+		t.Errorf("Totals: got %v / %d", week.DayHours, week.TotalHours)
+	}
+
+	// 2. HTML
+	var html bytes.Buffer
+	if err := weekly.RenderHTML(&html, week); err != nil {
+		t.Fatalf("RenderHTML: %v", err)
+	}
+	for _, want := range []string{"<td>12–20</td>", "Wed<small>4 Mar</small>", `<td class="total">16</td>`} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("HTML is missing %q", want)
+		}
+	}
+
+	// 3. PDF: the cross-reference table points at every object, and long crews get more pages
+	var pdf bytes.Buffer
+	if err := weekly.RenderPDF(&pdf, week); err != nil {
+		t.Fatalf("RenderPDF: %v", err)
+	}
+	checkPDF(t, pdf.Bytes(), 1)
+	if !bytes.Contains(pdf.Bytes(), []byte(`(12\22620)`)) {
+		t.Error("PDF should spell the en dash in WinAnsi")
+	}
+
+	crew := make(map[models.Employee][2]int)
+	for i := 0; i < 30; i++ {
+		crew[models.Employee{ID: 100 + i, Name: fmt.Sprintf("Temp %02d", i), HourlyRate: 18, SkillLevel: 1}] = [2]int{8, 16}
+	}
+	pdf.Reset()
+	weekly.RenderPDF(&pdf, weekly.Build(monday, []*models.Roster{dayRoster(monday, crew)}))
+	checkPDF(t, pdf.Bytes(), 2)
+
+	// 4. Over HTTP the default week is the one holding today at the store, not at the server
+	if err := store.SetLocationTimezone(models.DefaultLocation, "Pacific/Kiritimati"); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(api.NewServer(store, nil))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/week?format=pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	y, m, dd := time.Now().In(models.Location{Timezone: "Pacific/Kiritimati"}.Zone()).Date()
+	today := time.Date(y, m, dd, 0, 0, 0, 0, time.UTC)
+	start := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	if want := "inline; filename=week-" + start.Format(database.DateLayout) + ".pdf"; resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Disposition") != want {
+		t.Errorf("GET /week: %d %q, want %q", resp.StatusCode, resp.Header.Get("Content-Disposition"), want)
+	}
+	call(t, srv, "GET", "/week?format=doc", "", http.StatusBadRequest, nil)
+}
+
+// checkPDF follows startxref and checks each offset lands on its object
+func checkPDF(t *testing.T, pdf []byte, pages int) {
+	t.Helper()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("PDF header or trailer missing")
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("PDF has no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	lines := strings.Split(string(pdf[xref:]), "\n")
+	if lines[0] != "xref" {
+		t.Fatalf("startxref points at %q", lines[0])
+	}
+	var count int
+	fmt.Sscanf(lines[1], "0 %d", &count)
+	for i := 1; i < count; i++ {
+		off, _ := strconv.Atoi(lines[2+i][:10])
+		if want := fmt.Sprintf("%d 0 obj", i); !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i, pdf[off:off+10])
+		}
+	}
+	if want := fmt.Sprintf("/Count %d", pages); !bytes.Contains(pdf, []byte(want)) {
+		t.Errorf("PDF: want %d pages", pages)
+	}
+}